@@ -1,8 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -10,18 +18,161 @@ import (
 	"github.com/SoMuchForSubtlety/golark"
 )
 
-var endpoint = "https://f1tv.formula1.com/api/"
+// the F1TV API, api_base_url replaces it
+const defaultEndpoint = "https://f1tv.formula1.com/api/"
 
 const (
-	liveSlug = "grand-prix-weekend-live"
+	liveSlug     = "grand-prix-weekend-live"
+	mainFeedName = "WIF"
+	// used if a rate limited response doesn't say how long to wait
+	defaultRetryAfter = 10 * time.Second
+	// the default transport only keeps 2 idle connections per host,
+	// which means most of the concurrent requests for events and sessions open a new connection
+	defaultMaxIdleConnsPerHost = 32
+	defaultIdleConnTimeout     = 90 * time.Second
 )
 
+// configureAPI sets up the API client, the client for streams and the base URL for the config
+func (session *viewerSession) configureAPI() {
+	transport := newAPITransport(session.cfg)
+	transport.metrics = &session.metrics
+	session.apiClient = &http.Client{Transport: transport}
+	session.streamClient = &http.Client{Transport: newStreamTransport(configProxy(session.cfg))}
+	session.apiEndpoint = apiBaseURL(session.cfg)
+}
+
+// apiBaseURL returns the API base URL with a trailing slash, the collections are added to it
+func apiBaseURL(cfg config) string {
+	if cfg.APIBaseURL == "" {
+		return defaultEndpoint
+	}
+	return strings.TrimSuffix(cfg.APIBaseURL, "/") + "/"
+}
+
+// validateAPIBaseURL checks that api_base_url is an http or https URL with a host
+func validateAPIBaseURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if u, err := url.Parse(raw); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("api_base_url: '%s' must be an http or https URL", raw)
+	}
+	return nil
+}
+
+// newAPITransport returns a transport with a connection pool tuned for many concurrent requests
+func newAPITransport(cfg config) apiTransport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if t.MaxIdleConns < t.MaxIdleConnsPerHost {
+		t.MaxIdleConns = t.MaxIdleConnsPerHost
+	}
+	t.IdleConnTimeout = defaultIdleConnTimeout
+	if cfg.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = time.Duration(cfg.IdleConnTimeout) * time.Second
+	}
+	t.DisableKeepAlives = cfg.DisableKeepAlives
+	if proxy := configProxy(cfg); proxy != nil {
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	return apiTransport{base: t}
+}
+
+// rateLimitError is returned if the API rejects a request because too many requests were made
+type rateLimitError struct {
+	retryAfter time.Duration
+}
+
+func (e rateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by the API, retry after %s", e.retryAfter)
+}
+
+// geoBlockError is returned if the content isn't available in the region the request was made from
+type geoBlockError struct {
+	status string
+}
+
+func (e geoBlockError) Error() string {
+	return fmt.Sprintf("not available in your region (got status %s), set proxy in the config to use a proxy in a region where it is available (players fetch the stream directly, use serve_proxy to pass it through f1viewer)", e.status)
+}
+
+// the error code the API sends with forbidden responses for content that isn't available in the region of the request
+const geoBlockCode = "geo_blocked"
+
+// isGeoBlocked checks if a response rejected the request because of the region it was made from.
+// Other forbidden responses, eg. because the token expired, have a different or no error code.
+func isGeoBlocked(status int, body []byte) bool {
+	if status == http.StatusUnavailableForLegalReasons {
+		return true
+	}
+	if status != http.StatusForbidden {
+		return false
+	}
+	var apiErr struct {
+		Code string `json:"code"`
+	}
+	return json.Unmarshal(body, &apiErr) == nil && apiErr.Code == geoBlockCode
+}
+
+// apiTransport turns rate limited responses into rateLimitErrors and geo-blocked ones into geoBlockErrors
+type apiTransport struct {
+	base http.RoundTripper
+	// counts the requests if set
+	metrics *fetchMetrics
+}
+
+func (t apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	rateLimited := err == nil && resp.StatusCode == http.StatusTooManyRequests
+	if t.metrics != nil {
+		t.metrics.recordRequest(time.Since(start), err != nil || resp.StatusCode >= 400, rateLimited)
+	}
+	if err == nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnavailableForLegalReasons) {
+		body, readErr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
+		if isGeoBlocked(resp.StatusCode, body) {
+			return nil, geoBlockError{status: resp.Status}
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if !rateLimited {
+		return resp, err
+	}
+	resp.Body.Close()
+	return nil, rateLimitError{retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or a date
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && time.Until(date) > 0 {
+		return time.Until(date)
+	}
+	return defaultRetryAfter
+}
+
+func (session *viewerSession) newRequest(collection, id string) *golark.Request {
+	return golark.NewRequest(session.apiEndpoint, collection, id).WithClient(session.apiClient)
+}
+
 type episode struct {
 	Title        string   `json:"title"`
 	Subtitle     string   `json:"subtitle"`
+	Synopsis     string   `json:"synopsis"`
 	UID          string   `json:"uid"`
 	DataSourceID string   `json:"data_source_id"`
 	Items        []string `json:"items"`
+	DriverUrls   []string `json:"driver_urls"`
+	TeamUrls     []string `json:"team_urls"`
 }
 
 type vodTypes struct {
@@ -51,6 +202,30 @@ type eventStruct struct {
 	SessionoccurrenceUrls []string `json:"sessionoccurrence_urls"`
 	StartDate             string   `json:"start_date"`
 	EndDate               string   `json:"end_date"`
+	Circuit               struct {
+		Name string `json:"name"`
+	} `json:"circuit_url"`
+	Nation struct {
+		Name        string `json:"name"`
+		CountryCode string `json:"iso_country_code"`
+	} `json:"nation_url"`
+}
+
+// label returns the name of the event. Some events have no name, they are labeled with the official name,
+// or the circuit or country and the date. It returns "" if the event has none of them.
+func (e eventStruct) label() string {
+	switch {
+	case e.Name != "":
+		return e.Name
+	case e.OfficialName != "":
+		return e.OfficialName
+	case e.Circuit.Name != "":
+		return strings.Join(nonEmpty(e.Circuit.Name, e.StartDate), " ")
+	case e.Nation.Name != "":
+		return strings.Join(nonEmpty(e.Nation.Name, e.StartDate), " ")
+	default:
+		return ""
+	}
 }
 
 type sessionStruct struct {
@@ -63,25 +238,88 @@ type sessionStruct struct {
 	EndTime     time.Time `json:"end_time"`
 }
 
-type channel struct {
-	UID  string `json:"uid"`
-	Self string `json:"self"`
-	Name string `json:"name"`
-}
-
-func (c channel) PrettyName() string {
-	switch c.Name {
-	case "WIF":
-		return "Main Feed"
-	case "pit lane":
-		return "Pit Lane"
-	case "driver":
-		return "Driver Tracker"
-	case "data":
-		return "Data Channel"
+// replayAvailable checks if a session can be watched on demand. Sessions stay live for a while after they ended,
+// during that time both the live stream and the replay are available.
+func (s sessionStruct) replayAvailable(now time.Time) bool {
+	switch s.Status {
+	case "replay":
+		return true
+	case "live":
+		return !s.EndTime.IsZero() && now.After(s.EndTime)
 	default:
-		return c.Name
+		return false
+	}
+}
+
+type channel struct {
+	UID     string          `json:"uid"`
+	Self    string          `json:"self"`
+	Name    string          `json:"name"`
+	Drivers []channelDriver `json:"driveroccurrence_urls"`
+}
+
+// channelDriver is the driver shown in an onboard perspective
+type channelDriver struct {
+	RacingNumber int         `json:"driver_racingnumber"`
+	Driver       string      `json:"driver_url"`
+	Team         channelTeam `json:"team_url"`
+}
+
+// channelTeam is the team of an onboard perspective's driver
+type channelTeam struct {
+	Colour string `json:"colour"`
+}
+
+// UnmarshalJSON ignores the team if the API only returns its URL
+func (t *channelTeam) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '{' {
+		return nil
+	}
+	type plain channelTeam
+	return json.Unmarshal(data, (*plain)(t))
+}
+
+// driverURL returns the API URL of the perspective's driver, or "" if it isn't an onboard perspective
+func (c channel) driverURL() string {
+	if len(c.Drivers) == 0 {
+		return ""
 	}
+	return c.Drivers[0].Driver
+}
+
+// teamColour returns the hex color of the perspective driver's team, or "" if it's unknown
+func (c channel) teamColour() string {
+	if len(c.Drivers) == 0 {
+		return ""
+	}
+	return c.Drivers[0].Team.Colour
+}
+
+// racingNumber returns the racing number of the perspective's driver, or 0 if it isn't an onboard perspective
+func (c channel) racingNumber() int {
+	if len(c.Drivers) == 0 {
+		return 0
+	}
+	return c.Drivers[0].RacingNumber
+}
+
+// labels for the perspective names used by the API, they can be changed with perspective_labels
+var defaultPerspectiveLabels = map[string]string{
+	mainFeedName: "Main Feed",
+	"pit lane":   "Pit Lane",
+	"driver":     "Driver Tracker",
+	"data":       "Data Channel",
+}
+
+// PrettyName returns the label for the perspective, configured labels take precedence over the default ones
+func (c channel) PrettyName(labels map[string]string) string {
+	if label, ok := labels[c.Name]; ok {
+		return label
+	}
+	if label, ok := defaultPerspectiveLabels[c.Name]; ok {
+		return label
+	}
+	return c.Name
 }
 
 type collectionItem struct {
@@ -106,13 +344,13 @@ type collectionList struct {
 	Objects []collection `json:"objects"`
 }
 
-func getLiveWeekendEvent() (eventStruct, bool, error) {
+func (session *viewerSession) getLiveWeekendEvent() (eventStruct, bool, error) {
 	type container struct {
 		Objects []collection `json:"objects"`
 	}
 
 	var liveSet container
-	err := golark.NewRequest(endpoint, "sets", "").
+	err := session.newRequest("sets", "").
 		AddField(golark.NewField("items")).
 		WithFilter("slug", golark.NewFilter(golark.Equals, liveSlug)).
 		Execute(&liveSet)
@@ -122,12 +360,12 @@ func getLiveWeekendEvent() (eventStruct, bool, error) {
 	if len(liveSet.Objects) == 0 || len(liveSet.Objects[0].Items) == 0 {
 		return eventStruct{}, false, nil
 	}
-	event, err := getEvent(liveSet.Objects[0].Items[0].ContentURL)
+	event, err := session.getEvent(liveSet.Objects[0].Items[0].ContentURL)
 	return event, true, err
 }
 
-func getCollectionList() (collList collectionList, err error) {
-	err = golark.NewRequest(endpoint, "sets", "").
+func (session *viewerSession) getCollectionList() (collList collectionList, err error) {
+	err = session.newRequest("sets", "").
 		AddField(golark.NewField("title")).
 		AddField(golark.NewField("uid")).
 		WithFilter("set_type_slug", golark.NewFilter(golark.Equals, "video")).
@@ -135,25 +373,26 @@ func getCollectionList() (collList collectionList, err error) {
 	return
 }
 
-func getCollection(collID string) (coll collection, err error) {
-	err = golark.NewRequest(endpoint, "sets", collID).
+func (session *viewerSession) getCollection(collID string) (coll collection, err error) {
+	err = session.newRequest("sets", collID).
 		AddField(golark.NewField("items")).
 		Execute(&coll)
 	return
 }
 
-func getVodTypes() (types vodTypes, err error) {
-	err = golark.NewRequest(endpoint, "vod-type-tag", "").
+func (session *viewerSession) getVodTypes() (types vodTypes, err error) {
+	err = session.newRequest("vod-type-tag", "").
 		AddField(golark.NewField("name")).
 		AddField(golark.NewField("content_urls")).
 		Execute(&types)
 	return
 }
 
-func getSeasons() (s seasons, err error) {
+func (session *viewerSession) getSeasons() (s seasons, err error) {
 	year := golark.NewField("year").WithFilter(golark.NewFilter(golark.GreaterThan, "2017"))
-	err = golark.NewRequest(endpoint, "race-season", "").
+	err = session.newRequest("race-season", "").
 		AddField(year).
+		AddField(golark.NewField("uid")).
 		AddField(golark.NewField("name")).
 		AddField(golark.NewField("has_content")).
 		AddField(golark.NewField("eventoccurrence_urls")).
@@ -162,26 +401,37 @@ func getSeasons() (s seasons, err error) {
 	return
 }
 
-func getEvent(eventID string) (event eventStruct, err error) {
+func (session *viewerSession) getEvent(eventID string) (event eventStruct, err error) {
 	// TODO: use proper ID
-	err = golark.NewRequest(endpoint, "event-occurrence", pathToUID(eventID)).
+	err = session.newRequest("event-occurrence", pathToUID(eventID)).
+		AddField(golark.NewField("uid")).
 		AddField(golark.NewField("name")).
+		AddField(golark.NewField("official_name")).
+		AddField(golark.NewField("start_date")).
+		AddField(golark.NewField("end_date")).
+		AddField(golark.NewField("circuit_url").
+			WithSubField(golark.NewField("name"))).
+		AddField(golark.NewField("nation_url").
+			WithSubField(golark.NewField("name")).
+			WithSubField(golark.NewField("iso_country_code"))).
 		AddField(golark.NewField("sessionoccurrence_urls")).
 		Execute(&event)
 	return
 }
 
-func getSession(sessionID string) (session sessionStruct, err error) {
-	err = golark.NewRequest(endpoint, "session-occurrence", pathToUID(sessionID)).
+func (session *viewerSession) getSession(sessionID string) (s sessionStruct, err error) {
+	err = session.newRequest("session-occurrence", pathToUID(sessionID)).
 		AddField(golark.NewField("name")).
 		AddField(golark.NewField("status")).
 		AddField(golark.NewField("uid")).
 		AddField(golark.NewField("session_name")).
-		Execute(&session)
+		AddField(golark.NewField("start_time")).
+		AddField(golark.NewField("end_time")).
+		Execute(&s)
 	return
 }
 
-func getSessions(sessionIDs []string) ([]sessionStruct, error) {
+func (session *viewerSession) getSessions(sessionIDs []string) ([]sessionStruct, error) {
 	type container struct {
 		Objects []sessionStruct `json:"objects"`
 	}
@@ -192,10 +442,12 @@ func getSessions(sessionIDs []string) ([]sessionStruct, error) {
 		sessionIDs[i] = pathToUID(id)
 	}
 
-	err := golark.NewRequest(endpoint, "session-occurrence", "").
+	err := session.newRequest("session-occurrence", "").
 		AddField(golark.NewField("name")).
 		AddField(golark.NewField("status")).
 		AddField(golark.NewField("content_urls")).
+		AddField(golark.NewField("start_time")).
+		AddField(golark.NewField("end_time")).
 		AddField(golark.NewField("uid").
 			WithFilter(golark.NewFilter(golark.Equals, strings.Join(sessionIDs, ",")))).
 		Execute(&response)
@@ -203,41 +455,83 @@ func getSessions(sessionIDs []string) ([]sessionStruct, error) {
 	return response.Objects, err
 }
 
-func getSessionStreams(sessionID string) ([]channel, error) {
+func (session *viewerSession) getSessionStreams(sessionID string) ([]channel, error) {
 	type container struct {
 		Channels []channel `json:"channel_urls"`
 	}
 	var channels container
 
-	err := golark.NewRequest(endpoint, "session-occurrence", sessionID).
+	err := session.newRequest("session-occurrence", sessionID).
 		AddField(golark.NewField("channel_urls").
 			WithSubField(golark.NewField("self")).
 			WithSubField(golark.NewField("name")).
-			WithSubField(golark.NewField("uid"))).
+			WithSubField(golark.NewField("uid")).
+			WithSubField(golark.NewField("driveroccurrence_urls").
+				WithSubField(golark.NewField("driver_racingnumber")).
+				WithSubField(golark.NewField("driver_url")).
+				WithSubField(golark.NewField("team_url").
+					WithSubField(golark.NewField("colour"))))).
 		Execute(&channels)
 
 	return channels.Channels, err
 }
 
+// loadSessionStreams returns the streams of a session.
+// Streams of sessions that aren't live are cached, live sessions are always fetched again.
+// Empty results aren't cached, the API sometimes returns no streams around the start of a session.
+func (s *viewerSession) loadSessionStreams(sessionID string, live bool) ([]channel, error) {
+	if !live {
+		s.cacheLock.Lock()
+		streams, ok := s.streamCache[sessionID]
+		s.cacheLock.Unlock()
+		s.metrics.recordLookup("stream", ok)
+		if ok {
+			return streams, nil
+		}
+	}
+
+	streams, err := s.getSessionStreams(sessionID)
+	if err != nil || live || len(streams) == 0 {
+		return streams, err
+	}
+
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	if s.streamCache == nil {
+		s.streamCache = make(map[string][]channel)
+	}
+	s.streamCache[sessionID] = streams
+	return streams, nil
+}
+
+// number of episodes requested at once
+const episodeBatchSize = 5
+
+// episodeRequests returns the number of requests needed to load n episodes
+func episodeRequests(n int) int {
+	return (n + episodeBatchSize - 1) / episodeBatchSize
+}
+
 func (s *viewerSession) loadEpisodes(episodeIDs []string) ([]episode, error) {
 	type container struct {
-		Objects []episode `json:"objects"`
+		Objects []json.RawMessage `json:"objects"`
 	}
 
-	const batchSize = 5
+	const batchSize = episodeBatchSize
 	for i, id := range episodeIDs {
 		episodeIDs[i] = pathToUID(id)
 	}
 
-	episodes := make([]episode, len(episodeIDs))
+	// the episodes of each batch, malformed ones are missing
+	batches := make([][]episode, episodeRequests(len(episodeIDs)))
+	var rateErr error
 
 	var wg sync.WaitGroup
-	wg.Add(len(episodes) / batchSize)
-	if len(episodeIDs)%batchSize > 0 {
-		wg.Add(1)
-	}
+	var errLock sync.Mutex
+	wg.Add(len(batches))
 	for i := 0; i < len(episodeIDs); i += batchSize {
 		go func(rangeStart int) {
+			defer wg.Done()
 			rangeEnd := rangeStart + batchSize
 			if rangeEnd > len(episodeIDs) {
 				rangeEnd = len(episodeIDs)
@@ -246,26 +540,61 @@ func (s *viewerSession) loadEpisodes(episodeIDs []string) ([]episode, error) {
 			query := strings.Join(episodeIDs[rangeStart:rangeEnd], ",")
 			var response container
 			// TODO: properly handle error
-			err := golark.NewRequest(endpoint, "episodes", "").
+			err := s.newRequest("episodes", "").
 				AddField(golark.NewField("title")).
 				AddField(golark.NewField("subtitle")).
+				AddField(golark.NewField("synopsis")).
 				AddField(golark.NewField("uid").
 					WithFilter(golark.NewFilter(golark.Equals, query))).
 				AddField(golark.NewField("data_source_id")).
 				AddField(golark.NewField("items")).
+				AddField(golark.NewField("driver_urls")).
+				AddField(golark.NewField("team_urls")).
 				Execute(&response)
-			if err != nil {
+			if errors.As(err, &rateLimitError{}) {
+				errLock.Lock()
+				rateErr = err
+				errLock.Unlock()
+				return
+			} else if err != nil {
 				s.logError(err)
 				return
 			}
-			copy(episodes[rangeStart:], response.Objects)
-			wg.Done()
+			decoded, errs := decodeEpisodes(response.Objects)
+			for _, err := range errs {
+				s.logError(err)
+			}
+			batches[rangeStart/batchSize] = decoded
 		}(i)
 	}
 	wg.Wait()
+	// rate limited requests can be retried, so the whole load fails
+	if rateErr != nil {
+		return nil, rateErr
+	}
+	episodes := make([]episode, 0, len(episodeIDs))
+	for _, batch := range batches {
+		episodes = append(episodes, batch...)
+	}
 	return episodes, nil
 }
 
+// decodeEpisodes decodes every episode separately, so one malformed episode doesn't prevent the others from loading
+func decodeEpisodes(objects []json.RawMessage) ([]episode, []error) {
+	var errs []error
+	episodes := make([]episode, 0, len(objects))
+	for _, raw := range objects {
+		var ep episode
+		err := json.Unmarshal(raw, &ep)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("skipping malformed episode: %w", err))
+			continue
+		}
+		episodes = append(episodes, ep)
+	}
+	return episodes, errs
+}
+
 func sortEpisodes(episodes []episode) []episode {
 	sort.Slice(episodes, func(i, j int) bool {
 		if len(episodes[i].DataSourceID) >= 4 && len(episodes[j].DataSourceID) >= 4 {
@@ -285,6 +614,28 @@ func sortEpisodes(episodes []episode) []episode {
 	return episodes
 }
 
+func (session *viewerSession) getDriverName(driverID string) (string, error) {
+	var driver struct {
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+	err := session.newRequest("driver", pathToUID(driverID)).
+		AddField(golark.NewField("first_name")).
+		AddField(golark.NewField("last_name")).
+		Execute(&driver)
+	return strings.TrimSpace(driver.FirstName + " " + driver.LastName), err
+}
+
+func (session *viewerSession) getTeamName(teamID string) (string, error) {
+	var team struct {
+		Name string `json:"name"`
+	}
+	err := session.newRequest("team", pathToUID(teamID)).
+		AddField(golark.NewField("name")).
+		Execute(&team)
+	return team.Name, err
+}
+
 func pathToUID(p string) (uid string) {
 	return path.Base(p)
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeEpisodes(t *testing.T) {
+	t.Parallel()
+	objects := []json.RawMessage{
+		json.RawMessage(`{"title": "first", "uid": "ep_1", "data_source_id": "1901_AUS", "items": ["a"]}`),
+		json.RawMessage(`{"title": "malformed", "uid": 2, "data_source_id": ["1902_BHR"]}`),
+		json.RawMessage(`{"title": "third", "uid": "ep_3", "data_source_id": "19", "items": ["c"]}`),
+		json.RawMessage(`{"title": "fourth", "uid": "ep_4", "items": ["d"]}`),
+	}
+
+	episodes, errs := decodeEpisodes(objects)
+	assert.Len(t, errs, 1)
+	assert.Len(t, episodes, 3)
+	assert.Equal(t, "first", episodes[0].Title)
+	assert.Equal(t, "third", episodes[1].Title)
+	assert.Equal(t, "fourth", episodes[2].Title)
+
+	// short or missing data source IDs must not break sorting
+	sorted := sortEpisodes(episodes)
+	assert.Len(t, sorted, 3)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5"))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter(""))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("soon"))
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	assert.InDelta(t, float64(time.Minute), float64(parseRetryAfter(date)), float64(2*time.Second))
+}
+
+func TestEmptySessionStreams(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"channel_urls": []}`)
+	}))
+	defer server.Close()
+
+	_, s := newTestApp(t, 20, 5)
+	s.cfg.APIBaseURL = server.URL
+	s.configureAPI()
+	streams, err := s.loadSessionStreams("sess_123", false)
+	assert.NoError(t, err)
+	assert.Empty(t, streams)
+	assert.Empty(t, s.getPerspectiveNodes(Titles{}, streams))
+
+	// empty results must not be cached
+	_, ok := s.streamCache["sess_123"]
+	assert.False(t, ok)
+}
+
+func TestLoadEpisodesSkipsMalformed(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"objects": [{"uid": "ep_1", "title": "First"}, {"uid": 2, "title": []}, {"uid": "ep_3", "title": "Third"}]}`)
+	}))
+	defer server.Close()
+
+	_, s := newTestApp(t, 20, 5)
+	s.cfg.APIBaseURL = server.URL
+	s.configureAPI()
+	episodes, err := s.loadEpisodes([]string{"/api/episodes/ep_1/", "/api/episodes/ep_2/", "/api/episodes/ep_3/"})
+	assert.NoError(t, err)
+	// the malformed episode must not leave an empty one behind
+	if assert.Len(t, episodes, 2) {
+		assert.Equal(t, "First", episodes[0].Title)
+		assert.Equal(t, "Third", episodes[1].Title)
+	}
+}
+
+func TestPrettyName(t *testing.T) {
+	t.Parallel()
+	labels := map[string]string{"WIF": "Hauptkanal", "onboard": "Onboard"}
+	assert.Equal(t, "Main Feed", channel{Name: "WIF"}.PrettyName(nil))
+	assert.Equal(t, "Hauptkanal", channel{Name: "WIF"}.PrettyName(labels))
+	assert.Equal(t, "Onboard", channel{Name: "onboard"}.PrettyName(labels))
+	assert.Equal(t, "Data Channel", channel{Name: "data"}.PrettyName(labels))
+	assert.Equal(t, "Lewis Hamilton", channel{Name: "Lewis Hamilton"}.PrettyName(labels))
+}
+
+func TestNewAPITransport(t *testing.T) {
+	t.Parallel()
+	transport := newAPITransport(config{}).base.(*http.Transport)
+	assert.Equal(t, defaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, defaultIdleConnTimeout, transport.IdleConnTimeout)
+	assert.False(t, transport.DisableKeepAlives)
+
+	transport = newAPITransport(config{MaxIdleConnsPerHost: 200, IdleConnTimeout: 10, DisableKeepAlives: true}).base.(*http.Transport)
+	assert.Equal(t, 200, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 200, transport.MaxIdleConns)
+	assert.Equal(t, 10*time.Second, transport.IdleConnTimeout)
+	assert.True(t, transport.DisableKeepAlives)
+
+	// the default transport must not be modified
+	assert.NotEqual(t, 200, http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost)
+}
+
+func TestGeoBlock(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		if r.URL.Path == "/blocked" {
+			fmt.Fprint(w, `{"code": "geo_blocked", "detail": "This content is not available in your country"}`)
+		} else {
+			fmt.Fprint(w, `{"detail": "Authentication credentials were not provided for this region"}`)
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newAPITransport(config{})}
+
+	_, err := client.Get(server.URL + "/blocked")
+	assert.True(t, errors.As(err, &geoBlockError{}))
+	assert.Contains(t, err.Error(), "set proxy in the config")
+
+	// other forbidden responses are passed on with their body
+	resp, err := client.Get(server.URL + "/forbidden")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	err = checkResponse(resp)
+	assert.False(t, errors.As(err, &geoBlockError{}))
+	assert.Contains(t, err.Error(), "Authentication credentials")
+
+	assert.True(t, isGeoBlocked(http.StatusUnavailableForLegalReasons, nil))
+	assert.False(t, isGeoBlocked(http.StatusNotFound, []byte(`{"code": "geo_blocked"}`)))
+	// the message alone doesn't make it a geo-block
+	assert.False(t, isGeoBlocked(http.StatusForbidden, []byte("not available in your country")))
+
+	transport := newAPITransport(config{Proxy: "socks5://localhost:1080"}).base.(*http.Transport)
+	proxy, err := transport.Proxy(&http.Request{})
+	assert.NoError(t, err)
+	assert.Equal(t, "socks5://localhost:1080", proxy.String())
+}
+
+func TestAPIBaseURL(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, defaultEndpoint, apiBaseURL(config{}))
+	assert.Equal(t, "http://localhost:8080/api/", apiBaseURL(config{APIBaseURL: "http://localhost:8080/api"}))
+	assert.Equal(t, "http://localhost:8080/api/", apiBaseURL(config{APIBaseURL: "http://localhost:8080/api/"}))
+
+	assert.Empty(t, validateConfig([]byte(`{"api_base_url": "https://mirror.example.com/api/"}`)))
+	assert.NotEmpty(t, validateConfig([]byte(`{"api_base_url": "mirror.example.com"}`)))
+	assert.NotEmpty(t, validateConfig([]byte(`{"api_base_url": "ftp://mirror.example.com/api/"}`)))
+
+	assert.NoError(t, validateAPIBaseURL(""))
+	assert.Error(t, validateAPIBaseURL("https:///api/"))
+}
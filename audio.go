@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// listAudioDevices prints the audio devices MPV can use, the names can be used for audio_device
+func listAudioDevices(w io.Writer) error {
+	if _, err := exec.LookPath("mpv"); err != nil {
+		return errors.New("MPV is not installed, it's needed to list the audio devices")
+	}
+	out, err := exec.Command("mpv", "--audio-device=help").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not list audio devices: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\nuse the name in quotes for audio_device, eg. \"pulse/alsa_output.pci-0000_00_1f.3.hdmi-stereo\"\n"+
+		"VLC selects devices with options of its audio output module, run vlc -H to list them and set them in vlc_audio_options\n", out)
+	return err
+}
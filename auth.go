@@ -115,13 +115,37 @@ func post(content interface{}, url string, header http.Header) ([]byte, error) {
 	return ioutil.ReadAll(resp.Body)
 }
 
+// statusError is returned if a request didn't succeed
+type statusError struct {
+	code   int
+	status string
+	body   string
+}
+
+func (e statusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("got status %s", e.status)
+	}
+	return fmt.Sprintf("got status %s with body:\n%s", e.status, e.body)
+}
+
+// isAuthError checks if a request was rejected because the token is invalid or expired
+func isAuthError(err error) bool {
+	var statusErr statusError
+	return errors.As(err, &statusErr) && (statusErr.code == http.StatusUnauthorized || statusErr.code == http.StatusForbidden)
+}
+
 func checkResponse(resp *http.Response) error {
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		respString, err := ioutil.ReadAll(resp.Body)
-		if err != nil || string(respString) == "" {
-			return fmt.Errorf("got status %s", resp.Status)
+		if err == nil && isGeoBlocked(resp.StatusCode, respString) {
+			return geoBlockError{status: resp.Status}
+		}
+		statusErr := statusError{code: resp.StatusCode, status: resp.Status}
+		if err == nil {
+			statusErr.body = string(respString)
 		}
-		return fmt.Errorf("got status %s with body:\n%s", resp.Status, respString)
+		return statusErr
 	}
 	return nil
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// batch is an operation on several items, like a multi command or a multi-select action, that can be cancelled with X
+type batch struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock sync.Mutex
+	// keys of the processes the batch started
+	started []string
+}
+
+// startBatch registers a new batch, endBatch has to be called once it's done
+func (session *viewerSession) startBatch() *batch {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &batch{ctx: ctx, cancel: cancel}
+	session.batchLock.Lock()
+	session.batches = append(session.batches, b)
+	session.batchLock.Unlock()
+	return b
+}
+
+func (session *viewerSession) endBatch(b *batch) {
+	b.cancel()
+	session.batchLock.Lock()
+	defer session.batchLock.Unlock()
+	for i, running := range session.batches {
+		if running == b {
+			session.batches = append(session.batches[:i], session.batches[i+1:]...)
+			return
+		}
+	}
+}
+
+// cancelled logs how far the batch got if it was cancelled
+func (b *batch) cancelled(session *viewerSession, done, total int) bool {
+	if b.ctx.Err() == nil {
+		return false
+	}
+	session.logInfo(fmt.Sprintf("cancelled, %d of %d done, %d cancelled", done, total, total-done))
+	return true
+}
+
+func (b *batch) addProcess(key string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.started = append(b.started, key)
+}
+
+// cancelBatches asks if the running batches should be cancelled and if the processes they started should be stopped as well
+func (session *viewerSession) cancelBatches() {
+	session.batchLock.Lock()
+	batches := make([]*batch, len(session.batches))
+	copy(batches, session.batches)
+	session.batchLock.Unlock()
+	if len(batches) == 0 {
+		session.logInfo("nothing to cancel")
+		return
+	}
+
+	text := fmt.Sprintf("Cancel %d running operation(s)?", len(batches))
+	session.showModal(text, []string{"Cancel pending", "Also stop started", "Back"}, func(label string) {
+		session.app.SetFocus(session.tree)
+		if label == "Back" {
+			return
+		}
+		var stopped int
+		for _, b := range batches {
+			b.cancel()
+			if label != "Also stop started" {
+				continue
+			}
+			b.lock.Lock()
+			for _, key := range b.started {
+				if session.stopProcess(key) {
+					stopped++
+				}
+			}
+			b.lock.Unlock()
+		}
+		if stopped > 0 {
+			session.logInfo(fmt.Sprintf("stopped %d started process(es)", stopped))
+		}
+	})
+}
+
+// stopProcess kills the running process with the key, it returns false if there is none.
+// Raw downloads without a container format don't run a process and can't be stopped.
+func (session *viewerSession) stopProcess(key string) bool {
+	session.processLock.Lock()
+	defer session.processLock.Unlock()
+	for _, p := range session.processes {
+		if p.key == key && p.cmd != nil && p.cmd.Process != nil {
+			p.stopped = true
+			return p.cmd.Process.Kill() == nil
+		}
+	}
+	return false
+}
+
+// wasStopped checks if the process was stopped by f1viewer, in that case it didn't crash
+func (session *viewerSession) wasStopped(proc *runningProcess) bool {
+	session.processLock.Lock()
+	defer session.processLock.Unlock()
+	return proc.stopped
+}
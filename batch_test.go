@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+	session := &viewerSession{}
+	b1 := session.startBatch()
+	b2 := session.startBatch()
+	assert.Len(t, session.batches, 2)
+
+	b1.addProcess(downloadKey("1"))
+	assert.Equal(t, []string{"download|1"}, b1.started)
+	assert.False(t, b1.cancelled(session, 0, 2))
+
+	session.endBatch(b1)
+	assert.Equal(t, []*batch{b2}, session.batches)
+	assert.Error(t, b1.ctx.Err())
+	assert.NoError(t, b2.ctx.Err())
+	session.endBatch(b2)
+	assert.Empty(t, session.batches)
+}
+
+func TestStopProcessNotRunning(t *testing.T) {
+	t.Parallel()
+	session := &viewerSession{}
+	proc, ok := session.trackProcess(downloadKey("1"), "title", true)
+	assert.True(t, ok)
+	// the process wasn't started yet
+	assert.False(t, session.stopProcess(downloadKey("1")))
+	assert.False(t, session.stopProcess(downloadKey("2")))
+	session.untrackProcess(proc)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// saved URLs without an expiry parameter are used for this long after they were resolved
+const defaultURLLifetime = time.Hour
+
+// matches the expiry timestamp of signed stream URLs, eg. exp=1593950400
+var urlExpiryRegex = regexp.MustCompile(`(?i)\bexp(?:ires)?=(\d{10})\b`)
+
+// sessionBundle is a saved session with the URLs of its perspectives, so it can be played without navigating to it
+type sessionBundle struct {
+	Title        string               `json:"title"`
+	SessionID    string               `json:"session_id"`
+	Saved        time.Time            `json:"saved"`
+	Perspectives []bundledPerspective `json:"perspectives"`
+}
+
+type bundledPerspective struct {
+	Label   string    `json:"label"`
+	Titles  Titles    `json:"titles"`
+	Channel channel   `json:"channel"`
+	URL     string    `json:"url,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// savedURL is a stream URL from a bundle, it is used instead of requesting a new one until it expires
+type savedURL struct {
+	url     string
+	expires time.Time
+}
+
+func getBundlePath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	path = filepath.Join(path, "bundles")
+	return path, os.MkdirAll(path, 0755)
+}
+
+// urlExpiry returns when a signed URL expires, if the URL doesn't say it's assumed to last defaultURLLifetime
+func urlExpiry(u string, resolved time.Time) time.Time {
+	if m := urlExpiryRegex.FindStringSubmatch(u); m != nil {
+		if seconds, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			return time.Unix(seconds, 0)
+		}
+	}
+	return resolved.Add(defaultURLLifetime)
+}
+
+// rememberURL saves a bundled URL for playableURL
+func (session *viewerSession) rememberURL(id, u string, expires time.Time) {
+	if u == "" {
+		return
+	}
+	session.urlLock.Lock()
+	defer session.urlLock.Unlock()
+	if session.savedURLs == nil {
+		session.savedURLs = make(map[string]savedURL)
+	}
+	session.savedURLs[id] = savedURL{url: u, expires: expires}
+}
+
+// forgetURL removes the bundled URL of the content, eg. because it was rejected
+func (session *viewerSession) forgetURL(id string) {
+	session.urlLock.Lock()
+	defer session.urlLock.Unlock()
+	delete(session.savedURLs, id)
+}
+
+// savedURL returns the bundled URL of the content if it hasn't expired yet
+func (session *viewerSession) savedURL(id string, now time.Time) (string, bool) {
+	session.urlLock.Lock()
+	defer session.urlLock.Unlock()
+	saved, ok := session.savedURLs[id]
+	if !ok {
+		return "", false
+	}
+	if !now.Before(saved.expires) {
+		delete(session.savedURLs, id)
+		return "", false
+	}
+	return saved.url, true
+}
+
+// bundleSession saves the loaded perspectives of the selected session and their URLs as a bundle
+func (session *viewerSession) bundleSession(node *tview.TreeNode) {
+	sessionNode := node
+	if ref, ok := node.GetReference().(*NodeMetadata); ok && ref.nodeType == StreamNode {
+		sessionNode = session.findParent(node)
+	}
+	var ref *NodeMetadata
+	if sessionNode != nil {
+		ref, _ = sessionNode.GetReference().(*NodeMetadata)
+	}
+	if ref == nil || ref.nodeType != PlayableNode {
+		session.logInfo("select a session or one of its perspectives to save it as a bundle")
+		return
+	}
+	b := sessionBundle{Title: ref.titles.String(), SessionID: ref.id}
+	for _, child := range sessionNode.GetChildren() {
+		childRef, ok := child.GetReference().(*NodeMetadata)
+		if !ok || childRef.nodeType != StreamNode {
+			continue
+		}
+		c, ok := childRef.metadata.(channel)
+		if !ok {
+			continue
+		}
+		b.Perspectives = append(b.Perspectives, bundledPerspective{Label: nodeLabel(child), Titles: childRef.titles, Channel: c})
+	}
+	if len(b.Perspectives) == 0 {
+		session.logInfo("open ", nodeLabel(sessionNode), " first, only loaded perspectives are saved")
+		return
+	}
+	go func() {
+		for i := range b.Perspectives {
+			p := &b.Perspectives[i]
+			u, err := session.playableURL(p.Channel.Self)
+			if err != nil {
+				session.logWarn("could not get the URL of ", p.Label, ", it is requested when it's played: ", err)
+				continue
+			}
+			p.URL, p.Expires = u, urlExpiry(u, time.Now())
+		}
+		b.Saved = time.Now()
+		path, err := saveBundle(b)
+		if err != nil {
+			session.logError("could not save the bundle: ", err)
+			return
+		}
+		session.logInfo("saved ", len(b.Perspectives), " perspectives of ", b.Title, " to ", path)
+	}()
+}
+
+func saveBundle(b sessionBundle) (string, error) {
+	dir, err := getBundlePath()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(b, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, b.Title+".json")
+	return path, writeFileAtomic(path, data, 0644)
+}
+
+// loadBundles reads the bundles in the directory, sorted by title. Files that can't be read are returned as errors.
+func loadBundles(dir string) ([]sessionBundle, []error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, []error{err}
+	}
+	var bundles []sessionBundle
+	var errs []error
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var b sessionBundle
+		if err := json.Unmarshal(data, &b); err != nil {
+			errs = append(errs, fmt.Errorf("invalid bundle %s: %w", f.Name(), err))
+			continue
+		}
+		bundles = append(bundles, b)
+	}
+	sort.SliceStable(bundles, func(i, j int) bool {
+		return bundles[i].Title < bundles[j].Title
+	})
+	return bundles, errs
+}
+
+// getBundlesNode returns the Session Bundles category, it lists the saved bundles and is rebuilt every time it's expanded
+func (session *viewerSession) getBundlesNode() *tview.TreeNode {
+	node := tview.NewTreeNode("Session Bundles").
+		SetColor(session.theme.CategoryNodeColor).
+		SetReference(&NodeMetadata{nodeType: CategoryNode, titles: Titles{CategoryTitle: "Session Bundles"}})
+
+	load := session.withBlink(node, func() {
+		var children []*tview.TreeNode
+		dir, err := getBundlePath()
+		if err != nil {
+			session.logError("could not load bundles: ", err)
+			return
+		}
+		bundles, errs := loadBundles(dir)
+		for _, err := range errs {
+			session.logWarn(err)
+		}
+		for _, b := range bundles {
+			children = append(children, session.bundleNode(b))
+		}
+		if len(children) == 0 {
+			children = append(children, tview.NewTreeNode("no bundles yet, press B on a loaded session to save one").
+				SetColor(session.theme.NoContentColor).
+				SetReference(&NodeMetadata{nodeType: MiscNode}))
+		}
+		node.SetChildren(children)
+		session.styleNodes(node)
+	}, nil)
+	node.SetSelectedFunc(func() {
+		// collapsing doesn't need a reload
+		if node.IsExpanded() && len(node.GetChildren()) > 0 {
+			return
+		}
+		node.SetExpanded(true)
+		load()
+	})
+	return node
+}
+
+// bundleNode lists the perspectives of a bundle, their saved URLs are played until they expire
+func (session *viewerSession) bundleNode(b sessionBundle) *tview.TreeNode {
+	node := tview.NewTreeNode(b.Title).
+		SetColor(session.theme.FolderNodeColor).
+		SetExpanded(false).
+		SetReference(&NodeMetadata{nodeType: MiscNode, id: b.SessionID, titles: Titles{CategoryTitle: "Session Bundles"}})
+	for _, p := range b.Perspectives {
+		session.rememberURL(p.Channel.Self, p.URL, p.Expires)
+		node.AddChild(session.newPerspectiveNode(p.Titles, p.Channel, p.Label))
+	}
+	return node
+}
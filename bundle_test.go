@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLExpiry(t *testing.T) {
+	t.Parallel()
+	resolved := time.Date(2020, 7, 5, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Unix(1593954000, 0), urlExpiry("https://cdn.example.com/index.m3u8?hdnts=exp=1593954000~acl=/*~hmac=abc", resolved))
+	assert.Equal(t, resolved.Add(defaultURLLifetime), urlExpiry("https://cdn.example.com/index.m3u8", resolved))
+}
+
+func TestSavedURL(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, 7, 5, 12, 0, 0, 0, time.UTC)
+	s := &viewerSession{}
+	s.rememberURL("/api/channels/1/", "https://example.com/1.m3u8", now.Add(time.Minute))
+	s.rememberURL("/api/channels/2/", "", now.Add(time.Minute))
+
+	u, ok := s.savedURL("/api/channels/1/", now)
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/1.m3u8", u)
+	_, ok = s.savedURL("/api/channels/2/", now)
+	assert.False(t, ok)
+	_, ok = s.savedURL("/api/channels/1/", now.Add(time.Minute))
+	assert.False(t, ok)
+}
+
+func TestLoadBundles(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Monaco - Race.json"), []byte(`{
+		"title": "Monaco - Race",
+		"session_id": "ses_1",
+		"perspectives": [{"label": "Main Feed", "titles": {"SessionTitle": "Race", "PerspectiveTitle": "Main Feed"}, "channel": {"self": "/api/channels/1/", "name": "WIF"}, "url": "https://example.com/1.m3u8", "expires": "2100-01-01T00:00:00Z"}]
+	}`), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Austria - Race.json"), []byte(`{"title": "Austria - Race"}`), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{`), 0644))
+
+	bundles, errs := loadBundles(dir)
+	assert.Len(t, errs, 1)
+	assert.Len(t, bundles, 2)
+	assert.Equal(t, "Austria - Race", bundles[0].Title)
+
+	_, s := newTestApp(t, 20, 5)
+	node := s.bundleNode(bundles[1])
+	assert.Len(t, node.GetChildren(), 1)
+	assert.Equal(t, "Main Feed", node.GetChildren()[0].GetText())
+	u, ok := s.savedURL("/api/channels/1/", time.Now())
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/1.m3u8", u)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cacheVersion has to be increased when the format of a cache file changes,
+// together with a migration in cacheMigrations that converts the data of the previous version
+const cacheVersion = 1
+
+// cacheMigrations convert the data of a cache file to the next version, keyed by the version they convert from.
+// Files with a version that can't be migrated are discarded.
+var cacheMigrations = map[int]func(data json.RawMessage) (json.RawMessage, error){}
+
+// errInvalidCache is returned if a cache file is corrupted or was written by a different version
+var errInvalidCache = errors.New("invalid cache file")
+
+// errNewerCache is returned if a cache file was written by a newer version of f1viewer.
+// Unlike invalid files these must not be overwritten, the data would be lost when upgrading again.
+var errNewerCache = errors.New("cache file was written by a newer version of f1viewer")
+
+// cacheFile wraps the cached data with a version and a checksum to detect corrupted files
+type cacheFile struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// cacheFileLock returns the lock of the cache file at path, so concurrent reads and writes of a file don't interleave
+func (session *viewerSession) cacheFileLock(path string) *sync.Mutex {
+	session.cacheFilesLock.Lock()
+	defer session.cacheFilesLock.Unlock()
+	if session.cacheFiles == nil {
+		session.cacheFiles = make(map[string]*sync.Mutex)
+	}
+	lock, ok := session.cacheFiles[path]
+	if !ok {
+		lock = &sync.Mutex{}
+		session.cacheFiles[path] = lock
+	}
+	return lock
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeCache saves v to the cache file at path
+func (session *viewerSession) writeCache(path string, v interface{}) error {
+	lock := session.cacheFileLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+	return writeCacheFile(path, v)
+}
+
+// readCache loads the cache file at path into v.
+// If the file is corrupted or can't be migrated an error wrapping errInvalidCache is returned, a copy of it is kept as path.bak.
+// If it was written by a newer version an error wrapping errNewerCache is returned.
+func (session *viewerSession) readCache(path string, v interface{}) error {
+	lock := session.cacheFileLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+	return readCacheFile(path, v)
+}
+
+// updateCache loads the cache file at path into v, calls update and saves v, without other reads or writes of the file in between.
+// update gets the error of reading the file, a missing file is not an error. If update returns an error nothing is saved.
+func (session *viewerSession) updateCache(path string, v interface{}, update func(readErr error) error) error {
+	lock := session.cacheFileLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+	err := readCacheFile(path, v)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	if err := update(err); err != nil {
+		return err
+	}
+	return writeCacheFile(path, v)
+}
+
+func writeCacheFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	file, err := json.Marshal(cacheFile{Version: cacheVersion, Checksum: checksum(data), Data: data})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, file, 0600)
+}
+
+func readCacheFile(path string, v interface{}) error {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cache cacheFile
+	if err := json.Unmarshal(file, &cache); err != nil {
+		return invalidCache(path, file, err.Error())
+	}
+	if cache.Checksum != checksum(cache.Data) {
+		return invalidCache(path, file, "checksum mismatch")
+	}
+	if cache.Version > cacheVersion {
+		return fmt.Errorf("%w %s: version %d, expected %d", errNewerCache, path, cache.Version, cacheVersion)
+	}
+	data, err := migrateCache(cache.Version, cache.Data, cacheMigrations)
+	if err != nil {
+		return invalidCache(path, file, err.Error())
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return invalidCache(path, file, err.Error())
+	}
+	return nil
+}
+
+// migrateCache converts data of the given version to cacheVersion, one version at a time
+func migrateCache(version int, data json.RawMessage, migrations map[int]func(json.RawMessage) (json.RawMessage, error)) (json.RawMessage, error) {
+	for ; version < cacheVersion; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("can't migrate version %d", version)
+		}
+		var err error
+		data, err = migrate(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not migrate version %d: %w", version, err)
+		}
+	}
+	return data, nil
+}
+
+// invalidCache keeps a copy of an invalid cache file before it is overwritten and returns an error wrapping errInvalidCache
+func invalidCache(path string, file []byte, reason string) error {
+	backup := path + ".bak"
+	if err := writeFileAtomic(backup, file, 0600); err != nil {
+		return fmt.Errorf("%w %s: %s, could not keep a copy: %v", errInvalidCache, path, reason, err)
+	}
+	return fmt.Errorf("%w %s: %s, a copy was saved as %s", errInvalidCache, path, reason, backup)
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it to path,
+// so path never contains a partially written file.
+// New files get perm with the umask applied, existing files keep their mode but never get more permissions than perm.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	var existing os.FileMode
+	if info, err := os.Stat(path); err == nil {
+		existing = info.Mode().Perm() & perm
+	}
+	tmp, err := createTemp(path, perm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if existing != 0 {
+		if err := os.Chmod(tmp.Name(), existing); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// createTemp creates a new file next to path. Unlike ioutil.TempFile it's created with perm, so the umask applies.
+func createTemp(path string, perm os.FileMode) (*os.File, error) {
+	for i := 0; ; i++ {
+		name := path + ".tmp" + strconv.FormatInt(time.Now().UnixNano()+int64(i), 36)
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) && i < 10 {
+			continue
+		}
+		return f, err
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+	session := &viewerSession{}
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.json")
+
+	assert.NoError(t, session.writeCache(path, map[string]int{"a": 1}))
+	var data map[string]int
+	assert.NoError(t, session.readCache(path, &data))
+	assert.Equal(t, map[string]int{"a": 1}, data)
+
+	// no temporary files may be left behind
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	file, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(path, file[:len(file)/2], 0600))
+	assert.True(t, errors.Is(session.readCache(path, &data), errInvalidCache))
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"version": 0, "data": {}}`), 0600))
+	assert.True(t, errors.Is(session.readCache(path, &data), errInvalidCache))
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"version": 1, "checksum": "abc", "data": {"a": 2}}`), 0600))
+	assert.True(t, errors.Is(session.readCache(path, &data), errInvalidCache))
+
+	assert.True(t, os.IsNotExist(session.readCache(filepath.Join(dir, "missing.json"), &data)))
+}
+
+func TestCacheVersions(t *testing.T) {
+	t.Parallel()
+	session := &viewerSession{}
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.json")
+
+	data := []byte(`{"a": 2}`)
+	file := fmt.Sprintf(`{"version": %d, "checksum": "%s", "data": %s}`, cacheVersion+1, checksum(data), data)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(file), 0600))
+	var cached map[string]int
+	err = session.readCache(path, &cached)
+	assert.True(t, errors.Is(err, errNewerCache))
+	assert.False(t, errors.Is(err, errInvalidCache))
+
+	// invalid files are kept before they are overwritten
+	assert.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+	assert.True(t, errors.Is(session.readCache(path, &cached), errInvalidCache))
+	backup, err := ioutil.ReadFile(path + ".bak")
+	assert.NoError(t, err)
+	assert.Equal(t, "{", string(backup))
+
+	migrations := map[int]func(json.RawMessage) (json.RawMessage, error){
+		cacheVersion - 1: func(data json.RawMessage) (json.RawMessage, error) {
+			return json.RawMessage(`{"migrated": 1}`), nil
+		},
+	}
+	migrated, err := migrateCache(cacheVersion-1, json.RawMessage(`{}`), migrations)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"migrated": 1}`, string(migrated))
+	_, err = migrateCache(cacheVersion-2, json.RawMessage(`{}`), migrations)
+	assert.Error(t, err)
+	current, err := migrateCache(cacheVersion, json.RawMessage(`{"a": 1}`), nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a": 1}`, string(current))
+}
+
+func TestUpdateCache(t *testing.T) {
+	t.Parallel()
+	session := &viewerSession{}
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "counts.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counts := make(map[string]int)
+			assert.NoError(t, session.updateCache(path, &counts, func(readErr error) error {
+				counts["plays"]++
+				return readErr
+			}))
+		}()
+	}
+	wg.Wait()
+
+	var counts map[string]int
+	assert.NoError(t, session.readCache(path, &counts))
+	assert.Equal(t, 20, counts["plays"])
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// listContent prints the content of the given type to w without starting the UI.
+// Events, sessions and streams need the UID of their season, event or session.
+func listContent(w io.Writer, profile, contentType, id string, asJSON bool) error {
+	cfg, err := loadConfig(profile)
+	if err != nil {
+		return fmt.Errorf("Could not open config: %w", err)
+	}
+	session := &viewerSession{cfg: cfg}
+	session.configureAPI()
+
+	var data interface{}
+	var lines []string
+
+	switch contentType {
+	case "seasons":
+		s, err := session.getSeasons()
+		if err != nil {
+			return err
+		}
+		data = s.Seasons
+		for _, season := range s.Seasons {
+			lines = append(lines, season.UID+"\t"+season.Name)
+		}
+	case "events":
+		if id == "" {
+			return errors.New("listing events requires a season UID")
+		}
+		s, err := session.getSeasons()
+		if err != nil {
+			return err
+		}
+		var season *seasonStruct
+		for i := range s.Seasons {
+			if s.Seasons[i].UID == id {
+				season = &s.Seasons[i]
+			}
+		}
+		if season == nil {
+			return fmt.Errorf("found no season with UID '%s'", id)
+		}
+		var events []eventStruct
+		for _, eventID := range season.EventoccurrenceUrls {
+			event, err := session.getEvent(eventID)
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+			lines = append(lines, event.UID+"\t"+event.Name)
+		}
+		data = events
+	case "sessions":
+		if id == "" {
+			return errors.New("listing sessions requires an event UID")
+		}
+		event, err := session.getEvent(id)
+		if err != nil {
+			return err
+		}
+		sessionsData, err := session.getSessions(event.SessionoccurrenceUrls)
+		if err != nil {
+			return err
+		}
+		data = sessionsData
+		for _, s := range sessionsData {
+			lines = append(lines, s.UID+"\t"+s.Name+"\t"+s.Status)
+		}
+	case "streams":
+		if id == "" {
+			return errors.New("listing streams requires a session UID")
+		}
+		streams, err := session.getSessionStreams(id)
+		if err != nil {
+			return err
+		}
+		data = streams
+		for _, s := range streams {
+			lines = append(lines, s.Self+"\t"+s.PrettyName(cfg.PerspectiveLabels))
+		}
+	default:
+		return fmt.Errorf("unknown content type '%s', use seasons, events, sessions or streams", contentType)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(data)
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
@@ -1,14 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
 )
 
+// players that exit with an error within this time after they were started failed to start, eg. because of invalid arguments
+const defaultPlayerStartupTimeout = 10 * time.Second
+
+// number of output lines kept to show why a player failed to start
+const playerOutputLines = 20
+
 type commandAndArgs []string
 
 type command struct {
@@ -31,6 +46,14 @@ type commandContext struct {
 	EpID          string
 	CustomOptions command
 	Titles        Titles
+	// the configured quality, looked up for the perspective in Titles if it's empty
+	Quality string
+	// where MPV starts playing, used when it is relaunched after a crash
+	Start time.Duration
+	// how often the player was relaunched after crashes
+	Relaunches int
+	// the node the command was started from, it's marked if the player fails to start
+	Node *tview.TreeNode
 }
 
 // Titles contains title metadata
@@ -41,14 +64,79 @@ type Titles struct {
 	CategoryTitle    string
 	EpisodeTitle     string
 	SeasonTitle      string
+	// the content is a live session
+	Live bool
+	// the content is a driver's onboard camera
+	Onboard bool
+}
+
+// runningProcess is a command started by f1viewer that hasn't exited yet
+type runningProcess struct {
+	key      string
+	title    string
+	download bool
+	cmd      *exec.Cmd
+	// the process was stopped by f1viewer
+	stopped bool
+}
+
+// playerKey is the key a command is tracked under, the same command can't run twice for the same content
+func playerKey(cc commandContext) string {
+	return cc.EpID + "|" + cc.CustomOptions.Title
+}
+
+// playerContentID returns the ID of the content a player process key belongs to
+func playerContentID(key string) string {
+	return strings.SplitN(key, "|", 2)[0]
+}
+
+// downloadKey is the key a download is tracked under
+func downloadKey(epID string) string {
+	return "download|" + epID
 }
 
 func (session *viewerSession) runCustomCommand(cc commandContext) error {
-	url, err := getPlayableURL(cc.EpID, session.authtoken)
+	proc, ok := session.trackProcess(playerKey(cc), cc.Titles.String(), false)
+	if !ok {
+		session.logInfo(cc.CustomOptions.Title, " is already running for ", cc.Titles.String())
+		if session.cfg.DuplicatePlayback == duplicateFocus {
+			session.focusPlayer(playerKey(cc))
+		}
+		return nil
+	}
+	url, err := session.playableURL(cc.EpID)
 	if err != nil {
+		session.untrackProcess(proc)
+		if errors.As(err, &geoBlockError{}) && cc.Node != nil {
+			session.markGeoBlocked(cc.Node)
+			session.draw()
+		}
 		return err
 	}
-	// replace variables
+	if cc.Quality == "" {
+		cc.Quality = session.quality(cc.Titles)
+	}
+	session.clearPlayerFailure(cc.Node)
+	if session.dryRun {
+		session.untrackProcess(proc)
+		session.logInfo("dry run, not executing: ", strings.Join(fillCommand(cc, url), " "))
+		return nil
+	}
+	err = session.runPlayer(proc, cc, url, false)
+	if err != nil {
+		return err
+	}
+	if cc.Relaunches > 0 {
+		return nil
+	}
+	if err := session.countPlay(cc.EpID, cc.Titles); err != nil {
+		session.logWarn(err)
+	}
+	return nil
+}
+
+// fillCommand replaces the variables in the command
+func fillCommand(cc commandContext, url string) []string {
 	tmpCommand := make([]string, len(cc.CustomOptions.Command))
 	copy(tmpCommand, cc.CustomOptions.Command)
 	for i := range tmpCommand {
@@ -60,11 +148,404 @@ func (session *viewerSession) runCustomCommand(cc commandContext) error {
 		tmpCommand[i] = strings.ReplaceAll(tmpCommand[i], "$episode", cc.Titles.EpisodeTitle)
 		tmpCommand[i] = strings.ReplaceAll(tmpCommand[i], "$season", cc.Titles.SeasonTitle)
 		tmpCommand[i] = strings.ReplaceAll(tmpCommand[i], "$title", cc.Titles.String())
+		tmpCommand[i] = strings.ReplaceAll(tmpCommand[i], "$hls_bitrate", hlsBitrate(cc.Quality))
+	}
+	return tmpCommand
+}
+
+// quality returns the configured quality for the content.
+// perspective_quality takes precedence over quality, perspective names over the onboard key.
+func (session *viewerSession) quality(t Titles) string {
+	if q, ok := session.cfg.PerspectiveQuality[t.PerspectiveTitle]; ok && t.PerspectiveTitle != "" {
+		return q
+	}
+	if q, ok := session.cfg.PerspectiveQuality["onboard"]; ok && t.Onboard {
+		return q
+	}
+	return session.cfg.Quality
+}
+
+// validQuality checks if the quality is max, min or a bitrate in kbit/s
+func validQuality(quality string) bool {
+	switch quality {
+	case "", "max", "min":
+		return true
+	}
+	kbits, err := strconv.Atoi(quality)
+	return err == nil && kbits > 0
+}
+
+// hlsBitrate returns the quality in the format of MPV's --hls-bitrate option
+func hlsBitrate(quality string) string {
+	switch quality {
+	case "min":
+		return "min"
+	case "", "max":
+		return "max"
 	}
-	return session.runCmd(exec.Command(tmpCommand[0], tmpCommand[1:]...))
+	kbits, err := strconv.Atoi(quality)
+	if err != nil || kbits <= 0 {
+		return "max"
+	}
+	return strconv.Itoa(kbits * 1000)
+}
+
+// vlcQualityArgs returns the VLC options that select the quality
+func vlcQualityArgs(quality string) []string {
+	switch quality {
+	case "":
+		return nil
+	case "min":
+		return []string{"--adaptive-logic=lowest"}
+	case "max":
+		return []string{"--adaptive-logic=highest"}
+	}
+	kbits, err := strconv.Atoi(quality)
+	if err != nil || kbits <= 0 {
+		return nil
+	}
+	return []string{"--adaptive-maxbw=" + strconv.Itoa(kbits)}
 }
 
-func (session *viewerSession) runCmd(cmd *exec.Cmd) error {
+// runPlayer starts the command for the URL. If it fails because the player reports that the URL was rejected,
+// a new URL is requested and the command is started once more.
+func (session *viewerSession) runPlayer(proc *runningProcess, cc commandContext, url string, retried bool) error {
+	args := fillCommand(cc, url)
+	var tracker *positionTracker
+	var ipc string
+	if isMPV(args[0]) {
+		tracker = &positionTracker{pos: cc.Start}
+		if cc.Start > 0 {
+			args = append(args, fmt.Sprintf("--start=%d", int(cc.Start.Seconds())))
+		}
+		if !cc.Titles.Live {
+			ipc = newIPCPath()
+			args = append(args, "--input-ipc-server="+ipc)
+			tracker.onUpdate = func(pos time.Duration, paused bool) {
+				if session.cfg.WatchParty.Host && session.cfg.WatchParty.Location != "" {
+					session.publishPosition(cc, pos, paused)
+				}
+				session.updateNowPlaying(proc, cc, pos, paused)
+			}
+		}
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	detector := &expiredURLDetector{w: session.textWindow}
+	cmd.Stdout = detector
+	cmd.Stderr = detector
+	if session.cfg.DetachPlayers {
+		// only the process group is detached, the output and the IPC server are still monitored while f1viewer runs
+		detach(cmd)
+	}
+	started := time.Now()
+	err := session.startCmd(cmd)
+	if err != nil {
+		session.untrackProcess(proc)
+		return err
+	}
+	session.attachCmd(proc, cmd)
+	session.startNowPlaying(proc, cc, cc.Start)
+	exited := make(chan struct{})
+	if ipc != "" {
+		go tracker.poll(ipc, exited)
+	}
+	go func() {
+		err := cmd.Wait()
+		close(exited)
+		session.clearNowPlaying(proc)
+		if ipc != "" {
+			// MPV doesn't remove the socket if it crashed
+			os.Remove(ipc)
+		}
+		if err == nil {
+			session.processExited(proc, nil)
+			session.runPostPlayCommand(cc, url)
+			return
+		}
+		if !detector.expired() {
+			session.processExited(proc, err)
+			if time.Since(started) < session.playerStartupTimeout() {
+				session.playerFailed(cc, err, detector.output())
+			}
+			session.runPostPlayCommand(cc, url)
+			if tracker != nil && time.Since(started) >= session.playerStartupTimeout() && !session.wasStopped(proc) {
+				session.playerCrashed(cc, err, tracker.position())
+			}
+			return
+		}
+		if retried {
+			session.processExited(proc, errors.New("the stream URL was rejected"))
+			session.logError("could not play ", cc.Titles.String(), ", the stream URL was rejected")
+			session.runPostPlayCommand(cc, url)
+			return
+		}
+		session.logWarn("the stream URL was rejected, retrying with a new URL")
+		// the rejected URL may have come from a session bundle, it must not be used again
+		session.forgetURL(cc.EpID)
+		url, err := session.freshURL(cc.EpID)
+		if err == nil {
+			err = session.runPlayer(proc, cc, url, true)
+		} else {
+			session.untrackProcess(proc)
+		}
+		if err != nil {
+			session.logError("could not play ", cc.Titles.String(), ": ", err)
+		}
+	}()
+	return nil
+}
+
+// runPostPlayCommand runs post_play_command after a player exited, without waiting for it to finish
+func (session *viewerSession) runPostPlayCommand(cc commandContext, url string) {
+	if len(session.cfg.PostPlayCommand) == 0 {
+		return
+	}
+	cc.CustomOptions.Command = session.cfg.PostPlayCommand
+	args := fillCommand(cc, url)
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		session.logWarn("could not run post_play_command: ", err)
+		return
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			session.logWarn("post_play_command failed for ", cc.Titles.String(), ": ", err)
+		}
+	}()
+}
+
+// messages players print if a stream URL has expired or is invalid
+var expiredURLMessages = []string{
+	"403 forbidden",
+	"http error 403",
+	"server returned 403",
+	"410 gone",
+	"http error 410",
+	"server returned 410",
+}
+
+// expiredURLDetector passes output through and checks it for messages about rejected URLs.
+// The last lines are kept to show why a player failed to start.
+type expiredURLDetector struct {
+	w     io.Writer
+	line  []byte
+	found bool
+	tail  []string
+	sync.Mutex
+}
+
+func (d *expiredURLDetector) Write(p []byte) (int, error) {
+	d.Lock()
+	d.line = append(d.line, p...)
+	for {
+		i := bytes.IndexByte(d.line, '\n')
+		if i < 0 {
+			break
+		}
+		d.check(d.line[:i])
+		d.line = d.line[i+1:]
+	}
+	// don't buffer endless lines, like progress output
+	if len(d.line) > 4096 {
+		d.check(d.line)
+		d.line = nil
+	}
+	d.Unlock()
+	return d.w.Write(p)
+}
+
+func (d *expiredURLDetector) check(line []byte) {
+	if len(bytes.TrimSpace(line)) > 0 {
+		d.tail = append(d.tail, string(bytes.TrimRight(line, "\r")))
+		if len(d.tail) > playerOutputLines {
+			d.tail = d.tail[1:]
+		}
+	}
+	lower := strings.ToLower(string(line))
+	for _, msg := range expiredURLMessages {
+		if strings.Contains(lower, msg) {
+			d.found = true
+		}
+	}
+}
+
+func (d *expiredURLDetector) expired() bool {
+	d.Lock()
+	defer d.Unlock()
+	d.flush()
+	return d.found
+}
+
+// output returns the last lines the player printed
+func (d *expiredURLDetector) output() string {
+	d.Lock()
+	defer d.Unlock()
+	d.flush()
+	return strings.Join(d.tail, "\n")
+}
+
+// flush checks the last line even if it isn't terminated
+func (d *expiredURLDetector) flush() {
+	if len(d.line) > 0 {
+		d.check(d.line)
+		d.line = nil
+	}
+}
+
+// playerFailure is the output of a player that failed to start, shown with o
+type playerFailure struct {
+	output string
+	color  tcell.Color
+}
+
+func (session *viewerSession) playerStartupTimeout() time.Duration {
+	if session.cfg.PlayerStartupTimeout > 0 {
+		return time.Duration(session.cfg.PlayerStartupTimeout) * time.Second
+	}
+	return defaultPlayerStartupTimeout
+}
+
+// playerFailed reports a player that exited right after it was started and marks the node it was started from
+func (session *viewerSession) playerFailed(cc commandContext, err error, output string) {
+	if cc.Node == nil {
+		session.logError(cc.CustomOptions.Title, " failed to start for ", cc.Titles.String(), ": ", err)
+		return
+	}
+	session.logError(cc.CustomOptions.Title, " failed to start for ", cc.Titles.String(), ": ", err, ", press o on the red node to show its output")
+	session.markFailed(cc.Node, output)
+}
+
+// markFailed colors the node red, o shows the output that explains why
+func (session *viewerSession) markFailed(node *tview.TreeNode, output string) {
+	session.failureLock.Lock()
+	if session.playerFailures == nil {
+		session.playerFailures = make(map[*tview.TreeNode]playerFailure)
+	}
+	color := node.GetColor()
+	if previous, ok := session.playerFailures[node]; ok {
+		// keep the color from before the node was marked
+		color = previous.color
+	}
+	session.playerFailures[node] = playerFailure{output: output, color: color}
+	session.failureLock.Unlock()
+	node.SetColor(session.theme.ErrorColor)
+	session.draw()
+}
+
+// clearPlayerFailure restores the color of a node that was marked because a player failed to start
+func (session *viewerSession) clearPlayerFailure(node *tview.TreeNode) {
+	if node == nil {
+		return
+	}
+	session.failureLock.Lock()
+	defer session.failureLock.Unlock()
+	if failure, ok := session.playerFailures[node]; ok {
+		node.SetColor(failure.color)
+		delete(session.playerFailures, node)
+	}
+}
+
+// showPlayerOutput shows the output of the player that failed to start from the node
+func (session *viewerSession) showPlayerOutput(node *tview.TreeNode) {
+	session.failureLock.Lock()
+	failure, ok := session.playerFailures[node]
+	session.failureLock.Unlock()
+	if !ok {
+		session.logInfo("nothing failed on this node")
+		return
+	}
+	output := failure.output
+	if output == "" {
+		output = "the player didn't print anything"
+	}
+	session.showModal(output, []string{"Close"}, func(string) {
+		session.app.SetFocus(session.tree)
+	})
+}
+
+// runTracked starts a command for a tracked process and stops tracking it once the command exits
+func (session *viewerSession) runTracked(proc *runningProcess, cmd *exec.Cmd) error {
+	err := session.startCmd(cmd)
+	if err != nil {
+		session.untrackProcess(proc)
+		return err
+	}
+	session.attachCmd(proc, cmd)
+	go func() {
+		session.processExited(proc, cmd.Wait())
+	}()
+	return nil
+}
+
+// trackProcess registers a process under the given key before it is started.
+// Unless duplicate playback is allowed, false is returned if a process with the same key is already running.
+// Duplicate downloads are never allowed.
+func (session *viewerSession) trackProcess(key, title string, download bool) (*runningProcess, bool) {
+	session.processLock.Lock()
+	defer session.processLock.Unlock()
+	if download || session.cfg.DuplicatePlayback != duplicateAllow {
+		for _, p := range session.processes {
+			if p.key == key {
+				return nil, false
+			}
+		}
+	}
+	proc := &runningProcess{key: key, title: title, download: download}
+	session.processes = append(session.processes, proc)
+	if !download {
+		session.refreshIcons(playerContentID(key))
+	}
+	return proc, true
+}
+
+// attachCmd sets the command of a process once it has been started
+func (session *viewerSession) attachCmd(proc *runningProcess, cmd *exec.Cmd) {
+	session.processLock.Lock()
+	defer session.processLock.Unlock()
+	proc.cmd = cmd
+}
+
+// runningDownloads returns the titles of all downloads that are in progress
+func (session *viewerSession) runningDownloads() []string {
+	session.processLock.Lock()
+	defer session.processLock.Unlock()
+	var titles []string
+	for _, p := range session.processes {
+		if p.download {
+			titles = append(titles, p.title)
+		}
+	}
+	return titles
+}
+
+// processRunning checks if a process with the key is running
+func (session *viewerSession) processRunning(key string) bool {
+	session.processLock.Lock()
+	defer session.processLock.Unlock()
+	for _, p := range session.processes {
+		if p.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (session *viewerSession) untrackProcess(proc *runningProcess) {
+	session.processLock.Lock()
+	defer session.processLock.Unlock()
+	for i, p := range session.processes {
+		if p == proc {
+			session.processes = append(session.processes[:i], session.processes[i+1:]...)
+			if !p.download {
+				session.refreshIcons(playerContentID(p.key))
+			}
+			return
+		}
+	}
+}
+
+// startCmd prints the command to the output window and starts it with its output redirected there
+func (session *viewerSession) startCmd(cmd *exec.Cmd) error {
 	wdir, err := os.Getwd()
 	if err != nil {
 		session.logError("unable to get working directory: ", err)
@@ -78,17 +559,17 @@ func (session *viewerSession) runCmd(cmd *exec.Cmd) error {
 			wdir = filepath.Base(wdir)
 		}
 	}
-	accentColorString := colortoHexString(activeTheme.TerminalAccentColor)
+	accentColorString := colortoHexString(session.theme.TerminalAccentColor)
 	fmt.Fprintf(session.textWindow, "[%s::b][[-]%s[%s]]$[-::-] %s\n", accentColorString, wdir, accentColorString, strings.Join(cmd.Args, " "))
 
-	cmd.Stdout = session.textWindow
-	cmd.Stderr = session.textWindow
-
-	err = cmd.Start()
-	if err != nil {
-		return err
+	if cmd.Stdout == nil {
+		cmd.Stdout = session.textWindow
 	}
-	return cmd.Process.Release()
+	if cmd.Stderr == nil {
+		cmd.Stderr = session.textWindow
+	}
+
+	return cmd.Start()
 }
 
 func (t Titles) String() string {
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiredURLDetector(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	d := &expiredURLDetector{w: &out}
+
+	fmt.Fprintln(d, "Playing: https://example.com/index.m3u8")
+	assert.False(t, d.expired())
+
+	// messages can be split across writes
+	fmt.Fprint(d, "[ffmpeg] https: HTTP error ")
+	fmt.Fprintln(d, "403 Forbidden")
+	assert.True(t, d.expired())
+	assert.Equal(t, "Playing: https://example.com/index.m3u8\n[ffmpeg] https: HTTP error 403 Forbidden\n", out.String())
+}
+
+func TestExpiredURLDetectorOutput(t *testing.T) {
+	t.Parallel()
+	d := &expiredURLDetector{w: ioutil.Discard}
+	for i := 0; i < playerOutputLines+5; i++ {
+		fmt.Fprintf(d, "line %d\r\n\n", i)
+	}
+	fmt.Fprint(d, "Error: unknown option")
+	lines := strings.Split(d.output(), "\n")
+	assert.Len(t, lines, playerOutputLines)
+	assert.Equal(t, "line 6", lines[0])
+	assert.Equal(t, "Error: unknown option", lines[len(lines)-1])
+}
+
+func TestPlayerFailure(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+	node := tview.NewTreeNode("MPV").SetColor(tcell.ColorBlue)
+	cc := commandContext{Titles: Titles{EventTitle: "Monaco"}, CustomOptions: command{Title: "MPV"}, Node: node}
+
+	s.playerFailed(cc, errors.New("exit status 1"), "first")
+	s.playerFailed(cc, errors.New("exit status 1"), "second")
+	assert.Equal(t, s.theme.ErrorColor, node.GetColor())
+	assert.Equal(t, "second", s.playerFailures[node].output)
+
+	s.clearPlayerFailure(node)
+	assert.Equal(t, tcell.ColorBlue, node.GetColor())
+	assert.Empty(t, s.playerFailures)
+}
+
+func TestFillCommand(t *testing.T) {
+	t.Parallel()
+	cc := commandContext{
+		Titles:        Titles{EventTitle: "Monaco", SessionTitle: "Race"},
+		CustomOptions: command{Command: []string{"mpv", "$url", "--title=$session"}},
+	}
+	assert.Equal(t, []string{"mpv", "https://example.com", "--title=Race"}, fillCommand(cc, "https://example.com"))
+	// the configured command must not be modified
+	assert.Equal(t, "$url", cc.CustomOptions.Command[1])
+}
+
+func TestQuality(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	s.cfg.Quality = "max"
+	s.cfg.PerspectiveQuality = map[string]string{"onboard": "1500", "Lewis Hamilton": "min"}
+
+	assert.Equal(t, "max", s.quality(Titles{PerspectiveTitle: "Main Feed"}))
+	assert.Equal(t, "1500", s.quality(Titles{PerspectiveTitle: "Max Verstappen", Onboard: true}))
+	assert.Equal(t, "min", s.quality(Titles{PerspectiveTitle: "Lewis Hamilton", Onboard: true}))
+
+	assert.Equal(t, "max", hlsBitrate(""))
+	assert.Equal(t, "min", hlsBitrate("min"))
+	assert.Equal(t, "1500000", hlsBitrate("1500"))
+	assert.Equal(t, []string{"--adaptive-maxbw=1500"}, vlcQualityArgs("1500"))
+	assert.Empty(t, vlcQualityArgs(""))
+
+	assert.True(t, validQuality("3000"))
+	assert.False(t, validQuality("1080p"))
+
+	cc := commandContext{CustomOptions: command{Command: []string{"mpv", "--hls-bitrate=$hls_bitrate"}}, Quality: "1500"}
+	assert.Equal(t, []string{"mpv", "--hls-bitrate=1500000"}, fillCommand(cc, ""))
+}
+
+func TestPostPlayCommand(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "played")
+
+	session := &viewerSession{cfg: config{PostPlayCommand: []string{"sh", "-c", "echo \"$title $url\" > " + file}}}
+	cc := commandContext{Titles: Titles{EventTitle: "Monaco Grand Prix", SessionTitle: "Race"}}
+	session.runPostPlayCommand(cc, "https://example.com/race.m3u8")
+
+	assert.Eventually(t, func() bool {
+		data, err := ioutil.ReadFile(file)
+		return err == nil && strings.TrimSpace(string(data)) == "Monaco Grand Prix - Race https://example.com/race.m3u8"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRetryRejectedSavedURL(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "urls")
+
+	_, s := newTestApp(t, 20, 5)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+	s.cfg.URLResolverCommand = []string{"sh", "-c", "echo https://example.com/fresh.m3u8", "$id"}
+	s.rememberURL("/api/channels/1/", "https://example.com/saved.m3u8", time.Now().Add(time.Hour))
+
+	// the player rejects every URL like an expired one
+	player := command{Title: "player", Command: []string{"sh", "-c", "echo $0 >> " + file + "; echo 'HTTP error 403 Forbidden'; exit 1", "$url"}}
+	cc := commandContext{Titles: Titles{EventTitle: "Monaco"}, EpID: "/api/channels/1/", CustomOptions: player}
+	assert.NoError(t, s.runCustomCommand(cc))
+
+	assert.Eventually(t, func() bool {
+		data, err := ioutil.ReadFile(file)
+		return err == nil && strings.TrimSpace(string(data)) == "https://example.com/saved.m3u8\nhttps://example.com/fresh.m3u8"
+	}, 5*time.Second, 10*time.Millisecond)
+	_, ok := s.savedURL("/api/channels/1/", time.Now())
+	assert.False(t, ok)
+}
+
+func TestResolveURL(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"resolve", "--id=123", "tok"}, resolverArgs([]string{"resolve", "--id=$id", "$token"}, "123", "tok"))
+	assert.Equal(t, []string{"resolve", "123"}, resolverArgs([]string{"resolve"}, "123", "tok"))
+
+	url, err := resolveURL([]string{"echo", "https://example.com/$id.m3u8"}, "123", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/123.m3u8", url)
+
+	_, err = resolveURL([]string{"echo", "not a url"}, "123", "")
+	assert.Error(t, err)
+	_, err = resolveURL([]string{"false"}, "123", "")
+	assert.Error(t, err)
+	_, err = parseResolvedURL("\n")
+	assert.Error(t, err)
+
+	url, err = parseResolvedURL("http://example.com/a.m3u8\nsome log output\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/a.m3u8", url)
+}
+
+func TestDuplicatePlayback(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	key := playerKey(commandContext{EpID: "1", CustomOptions: command{Title: "MPV"}})
+	_, ok := s.trackProcess(key, "Race", false)
+	assert.True(t, ok)
+	_, ok = s.trackProcess(key, "Race", false)
+	assert.False(t, ok)
+
+	s.cfg.DuplicatePlayback = duplicateFocus
+	_, ok = s.trackProcess(key, "Race", false)
+	assert.False(t, ok)
+
+	s.cfg.DuplicatePlayback = duplicateAllow
+	_, ok = s.trackProcess(key, "Race", false)
+	assert.True(t, ok)
+
+	assert.Equal(t, []string{"xdotool", "search", "--onlyvisible", "--pid", "42", "windowactivate"}, focusCommand("linux", 42))
+	assert.Nil(t, focusCommand("plan9", 42))
+	assert.Empty(t, validateConfig([]byte(`{"duplicate_playback": "focus"}`)))
+	assert.Len(t, validateConfig([]byte(`{"duplicate_playback": true}`)), 1)
+	assert.Len(t, validateConfig([]byte(`{"duplicate_playback": "ask"}`)), 1)
+}
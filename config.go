@@ -1,30 +1,116 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type config struct {
-	LiveRetryTimeout      int            `json:"live_retry_timeout"`
-	Lang                  string         `json:"preferred_language"`
-	CheckUpdate           bool           `json:"check_updates"`
-	SaveLogs              bool           `json:"save_logs"`
-	LogLocation           string         `json:"log_location"`
-	CustomPlaybackOptions []command      `json:"custom_playback_options"`
-	MultiCommand          []multiCommand `json:"multi_commands"`
-	HorizontalLayout      bool           `json:"horizontal_layout"`
-	Theme                 theme          `json:"theme"`
-	TreeRatio             int            `json:"tree_ratio"`
-	OutputRatio           int            `json:"output_ratio"`
+	LiveRetryTimeout      int                        `json:"live_retry_timeout"`
+	IdleTimeout           int                        `json:"idle_timeout"`
+	Lang                  string                     `json:"preferred_language"`
+	CheckUpdate           bool                       `json:"check_updates"`
+	SaveLogs              bool                       `json:"save_logs"`
+	LogLevel              string                     `json:"log_level"`
+	Debug                 bool                       `json:"debug"`
+	DebugPane             bool                       `json:"debug_pane"`
+	DebugActions          bool                       `json:"debug_actions"`
+	LogLocation           string                     `json:"log_location"`
+	CustomPlaybackOptions []command                  `json:"custom_playback_options"`
+	DefaultPlayer         string                     `json:"default_player,omitempty"`
+	MultiCommand          []multiCommand             `json:"multi_commands"`
+	HorizontalLayout      bool                       `json:"horizontal_layout"`
+	ColorMode             string                     `json:"color_mode"`
+	Theme                 theme                      `json:"theme"`
+	WrapOutput            bool                       `json:"wrap_output"`
+	RedrawInterval        int                        `json:"redraw_interval"`
+	TreeRatio             int                        `json:"tree_ratio"`
+	TreeIndent            *int                       `json:"tree_indent,omitempty"`
+	TreePrefixes          map[string]string          `json:"tree_prefixes,omitempty"`
+	NodeIcons             map[string]string          `json:"node_icons,omitempty"`
+	KeyBindings           map[string]string          `json:"key_bindings,omitempty"`
+	Pager                 []string                   `json:"pager,omitempty"`
+	OutputRatio           int                        `json:"output_ratio"`
+	DuplicatePlayback     string                     `json:"duplicate_playback,omitempty"`
+	DetachPlayers         bool                       `json:"detach_players"`
+	Notifications         map[string]bool            `json:"notifications,omitempty"`
+	PlayerStartupTimeout  int                        `json:"player_startup_timeout"`
+	PostPlayCommand       []string                   `json:"post_play_command,omitempty"`
+	URLResolverCommand    []string                   `json:"url_resolver_command,omitempty"`
+	PlayerRelaunch        int                        `json:"player_relaunch"`
+	AudioDevice           string                     `json:"audio_device"`
+	VLCAudioOptions       []string                   `json:"vlc_audio_options,omitempty"`
+	WatchParty            watchParty                 `json:"watch_party"`
+	MainFeedAction        string                     `json:"main_feed_action"`
+	LiveReplay            string                     `json:"live_replay"`
+	LiveJumpPlay          bool                       `json:"live_jump_play"`
+	NowPlayingFile        string                     `json:"now_playing_file"`
+	EnterAction           string                     `json:"enter_action"`
+	MPVProfiles           map[string]string          `json:"mpv_profiles,omitempty"`
+	Quality               string                     `json:"quality"`
+	PerspectiveQuality    map[string]string          `json:"perspective_quality,omitempty"`
+	PerspectiveLabels     map[string]string          `json:"perspective_labels,omitempty"`
+	PerspectiveOrder      []string                   `json:"perspective_order,omitempty"`
+	CategoryOrder         []string                   `json:"category_order,omitempty"`
+	HiddenCategories      []string                   `json:"hidden_categories,omitempty"`
+	ExpandedCategories    []string                   `json:"expanded_categories,omitempty"`
+	ConfirmRequests       int                        `json:"confirm_requests"`
+	SeasonOrder           string                     `json:"season_order"`
+	ExpandPerspectives    int                        `json:"expand_perspectives"`
+	PrimaryPerspectives   []string                   `json:"primary_perspectives,omitempty"`
+	SessionTypes          []string                   `json:"session_types,omitempty"`
+	DownloadLocation      string                     `json:"download_location"`
+	DownloadFormat        string                     `json:"download_format"`
+	DownloadCollision     string                     `json:"download_collision"`
+	LiveURLRefresh        int                        `json:"live_url_refresh"`
+	LiveRecordingRetries  int                        `json:"live_recording_retries"`
+	DownloadMetadata      string                     `json:"download_metadata"`
+	MinDownloadBitrate    int                        `json:"min_download_bitrate"`
+	AbortLowBitrate       bool                       `json:"abort_low_bitrate"`
+	DownloadBandwidth     int                        `json:"download_bandwidth"`
+	DownloadConcurrency   int                        `json:"download_concurrency"`
+	VerifyDownloads       bool                       `json:"verify_downloads"`
+	RetentionDays         int                        `json:"retention_days"`
+	RetentionGB           float64                    `json:"retention_gb"`
+	EpisodeLabel          string                     `json:"episode_label"`
+	FlattenFolders        bool                       `json:"flatten_folders"`
+	DriverLabel           string                     `json:"driver_label"`
+	ShowEventCountry      bool                       `json:"show_event_country"`
+	InfoResolveTimeout    int                        `json:"info_resolve_timeout"`
+	InfoLabelWidth        int                        `json:"info_label_width"`
+	NameFallback          string                     `json:"name_fallback,omitempty"`
+	NameCacheDays         int                        `json:"name_cache_days"`
+	MaxIdleConnsPerHost   int                        `json:"max_idle_conns_per_host"`
+	IdleConnTimeout       int                        `json:"idle_conn_timeout"`
+	DisableKeepAlives     bool                       `json:"disable_keep_alives"`
+	ServePort             int                        `json:"serve_port"`
+	ServeAddress          string                     `json:"serve_address,omitempty"`
+	ServeProxy            bool                       `json:"serve_proxy"`
+	Proxy                 string                     `json:"proxy,omitempty"`
+	APIBaseURL            string                     `json:"api_base_url,omitempty"`
+	Profiles              map[string]json.RawMessage `json:"profiles,omitempty"`
+
+	// name of the active profile, empty if none is selected
+	profile string
 }
 
+// theme overrides the default colors. In the config it can also be the name of a bundled theme,
+// or an object with a preset whose colors are overridden by the other fields.
 type theme struct {
+	Preset              string `json:"preset,omitempty"`
 	BackgroundColor     string `json:"background_color"`
 	BorderColor         string `json:"border_color"`
 	CategoryNodeColor   string `json:"category_node_color"`
@@ -35,6 +121,10 @@ type theme struct {
 	LiveColor           string `json:"live_color"`
 	UpdateColor         string `json:"update_color"`
 	NoContentColor      string `json:"no_content_color"`
+	RateLimitColor      string `json:"rate_limit_color"`
+	GeoBlockColor       string `json:"geo_block_color"`
+	VerifiedColor       string `json:"verified_color"`
+	WarnColor           string `json:"warn_color"`
 	InfoColor           string `json:"info_color"`
 	ErrorColor          string `json:"error_color"`
 	TerminalAccentColor string `json:"terminal_accent_color"`
@@ -42,7 +132,415 @@ type theme struct {
 	MultiCommandColor   string `json:"multi_command_color"`
 }
 
-func loadConfig() (config, error) {
+var hexColorRegex = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+
+// checkConfig validates the config file and the selected profile and prints all problems to w
+func checkConfig(w io.Writer, profile string) error {
+	path, err := getConfigPath()
+	if err != nil {
+		return err
+	}
+	path += "config.json"
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		fmt.Fprintln(w, "no config found at", path+", the default config will be created on startup")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	errs := validateConfig(data)
+	var cfg config
+	if json.Unmarshal(data, &cfg) == nil {
+		if profile != "" {
+			cfg, err = cfg.withProfile(profile)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err = cfg.applyEnv(os.LookupEnv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		fmt.Fprintln(w, "config OK:", path)
+		return nil
+	}
+	for _, err := range errs {
+		fmt.Fprintln(w, err)
+	}
+	return fmt.Errorf("found %d problems in %s", len(errs), path)
+}
+
+// validateConfig checks the config file for unknown keys and invalid values, including all profiles
+func validateConfig(data []byte) []error {
+	var errs []error
+	var cfg config
+	err := decodeStrict(data, &cfg)
+	if err != nil {
+		// unknown keys are reported, but the rest of the config can still be checked
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return []error{err}
+		}
+		errs = append(errs, err)
+	}
+	if err := checkThemeKeys(data); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, cfg.validate()...)
+
+	for name, raw := range cfg.Profiles {
+		var p config
+		if err := decodeStrict(raw, &p); err != nil {
+			errs = append(errs, fmt.Errorf("profile '%s': %w", name, err))
+			continue
+		}
+		if err := checkThemeKeys(raw); err != nil {
+			errs = append(errs, fmt.Errorf("profile '%s': %w", name, err))
+		}
+		for _, err := range p.validate() {
+			errs = append(errs, fmt.Errorf("profile '%s': %w", name, err))
+		}
+	}
+	return errs
+}
+
+// checkThemeKeys reports unknown keys in the theme object, they aren't caught by decodeStrict because the theme can also be a string
+func checkThemeKeys(data []byte) error {
+	var raw struct {
+		Theme json.RawMessage `json:"theme"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil || !bytes.HasPrefix(bytes.TrimSpace(raw.Theme), []byte("{")) {
+		return nil
+	}
+	var fields themeFields
+	if err := decodeStrict(raw.Theme, &fields); err != nil {
+		return fmt.Errorf("theme: %w", err)
+	}
+	return nil
+}
+
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// validate checks the values of all options that aren't free text
+func (cfg config) validate() []error {
+	var errs []error
+	colors := []struct {
+		name  string
+		value string
+	}{
+		{"background_color", cfg.Theme.BackgroundColor},
+		{"border_color", cfg.Theme.BorderColor},
+		{"category_node_color", cfg.Theme.CategoryNodeColor},
+		{"folder_node_color", cfg.Theme.FolderNodeColor},
+		{"item_node_color", cfg.Theme.ItemNodeColor},
+		{"action_node_color", cfg.Theme.ActionNodeColor},
+		{"loading_color", cfg.Theme.LoadingColor},
+		{"live_color", cfg.Theme.LiveColor},
+		{"update_color", cfg.Theme.UpdateColor},
+		{"no_content_color", cfg.Theme.NoContentColor},
+		{"rate_limit_color", cfg.Theme.RateLimitColor},
+		{"geo_block_color", cfg.Theme.GeoBlockColor},
+		{"verified_color", cfg.Theme.VerifiedColor},
+		{"warn_color", cfg.Theme.WarnColor},
+		{"info_color", cfg.Theme.InfoColor},
+		{"error_color", cfg.Theme.ErrorColor},
+		{"terminal_accent_color", cfg.Theme.TerminalAccentColor},
+		{"terminal_text_color", cfg.Theme.TerminalTextColor},
+		{"multi_command_color", cfg.Theme.MultiCommandColor},
+	}
+	for _, c := range colors {
+		if c.value != "" && !hexColorRegex.MatchString(c.value) {
+			errs = append(errs, fmt.Errorf("theme.%s: '%s' is not a hex color like #ff0000", c.name, c.value))
+		}
+	}
+	if cfg.Theme.Preset != "" && findTheme(cfg.Theme.Preset) < 0 {
+		errs = append(errs, fmt.Errorf("theme: unknown preset '%s', must be one of %s", cfg.Theme.Preset, strings.Join(themeNames(), ", ")))
+	}
+
+	switch cfg.DuplicatePlayback {
+	case "", duplicateAllow, duplicateDeny, duplicateFocus:
+	default:
+		errs = append(errs, fmt.Errorf("duplicate_playback: '%s' must be allow, deny or focus", cfg.DuplicatePlayback))
+	}
+	switch cfg.NameFallback {
+	case "", "id", "placeholder":
+	default:
+		errs = append(errs, fmt.Errorf("name_fallback: '%s' must be id or placeholder", cfg.NameFallback))
+	}
+	switch cfg.SeasonOrder {
+	case "", "ascending", "descending":
+	default:
+		errs = append(errs, fmt.Errorf("season_order: '%s' must be ascending or descending", cfg.SeasonOrder))
+	}
+	switch cfg.ColorMode {
+	case "", "auto", "256", "16":
+	default:
+		errs = append(errs, fmt.Errorf("color_mode: '%s' must be auto, 256 or 16", cfg.ColorMode))
+	}
+	if _, err := parseLogLevel(cfg.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	switch cfg.MainFeedAction {
+	case "", "select", "play":
+	default:
+		errs = append(errs, fmt.Errorf("main_feed_action: '%s' must be select or play", cfg.MainFeedAction))
+	}
+	switch strings.ToLower(cfg.DownloadMetadata) {
+	case "", "json", "nfo":
+	default:
+		errs = append(errs, fmt.Errorf("download_metadata: '%s' must be json or nfo", cfg.DownloadMetadata))
+	}
+	switch cfg.LiveReplay {
+	case "", "live", "replay", "both":
+	default:
+		errs = append(errs, fmt.Errorf("live_replay: '%s' must be live, replay or both", cfg.LiveReplay))
+	}
+	switch cfg.EnterAction {
+	case "", "expand", "play":
+	default:
+		errs = append(errs, fmt.Errorf("enter_action: '%s' must be expand or play", cfg.EnterAction))
+	}
+	switch cfg.DownloadCollision {
+	case "", "rename", "skip", "overwrite":
+	default:
+		errs = append(errs, fmt.Errorf("download_collision: '%s' must be rename, skip or overwrite", cfg.DownloadCollision))
+	}
+	if _, unknown := fillEpisodeTemplate(cfg.EpisodeLabel, episode{}); len(unknown) > 0 {
+		errs = append(errs, fmt.Errorf("episode_label: unknown placeholders %v", unknown))
+	}
+	if _, unknown := fillDriverTemplate(cfg.DriverLabel, "", ""); len(unknown) > 0 {
+		errs = append(errs, fmt.Errorf("driver_label: unknown placeholders %v", unknown))
+	}
+	for i, com := range cfg.CustomPlaybackOptions {
+		if len(com.Command) == 0 {
+			errs = append(errs, fmt.Errorf("custom_playback_options: option %d '%s' has no command", i+1, com.Title))
+		}
+	}
+	if !cfg.knownPlayer() {
+		errs = append(errs, fmt.Errorf("default_player: '%s' must be mpv, vlc or the title of a custom playback option", cfg.DefaultPlayer))
+	}
+	for i, multi := range cfg.MultiCommand {
+		if len(multi.Targets) == 0 {
+			errs = append(errs, fmt.Errorf("multi_commands: command %d '%s' has no targets", i+1, multi.Title))
+		}
+	}
+	if !validQuality(cfg.Quality) {
+		errs = append(errs, fmt.Errorf("quality: '%s' must be max, min or a bitrate in kbit/s", cfg.Quality))
+	}
+	for perspective, quality := range cfg.PerspectiveQuality {
+		if !validQuality(quality) {
+			errs = append(errs, fmt.Errorf("perspective_quality: '%s' for %s must be max, min or a bitrate in kbit/s", quality, perspective))
+		}
+	}
+	if err := validateAPIBaseURL(cfg.APIBaseURL); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Proxy != "" {
+		if u, err := url.Parse(cfg.Proxy); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			errs = append(errs, fmt.Errorf("proxy: '%s' must be an http, https or socks5 URL", cfg.Proxy))
+		}
+	}
+	if cfg.ServePort < 0 || cfg.ServePort > 65535 {
+		errs = append(errs, fmt.Errorf("serve_port: %d is not a valid port", cfg.ServePort))
+	}
+	if _, _, err := net.SplitHostPort(cfg.ServeAddress); err == nil {
+		errs = append(errs, fmt.Errorf("serve_address: '%s' must not contain a port, use serve_port", cfg.ServeAddress))
+	}
+	for event := range cfg.Notifications {
+		known := false
+		for _, e := range notifyEvents {
+			known = known || e == event
+		}
+		if !known {
+			errs = append(errs, fmt.Errorf("notifications: unknown event '%s', must be one of %s", event, strings.Join(notifyEvents, ", ")))
+		}
+	}
+	if cfg.TreeIndent != nil && *cfg.TreeIndent < 0 {
+		errs = append(errs, errors.New("tree_indent: must not be negative"))
+	}
+	for kind := range cfg.TreePrefixes {
+		switch kind {
+		case folderPrefix, leafPrefix, actionPrefix:
+		default:
+			errs = append(errs, fmt.Errorf("tree_prefixes: unknown node kind '%s', must be folder, leaf or action", kind))
+		}
+	}
+	for state := range cfg.NodeIcons {
+		switch state {
+		case iconNew, iconPlaying, iconPlayed, iconDownloaded:
+		default:
+			errs = append(errs, fmt.Errorf("node_icons: unknown state '%s', must be new, playing, played or downloaded", state))
+		}
+	}
+	for action, key := range cfg.KeyBindings {
+		if _, ok := defaultKeyBindings[action]; !ok {
+			errs = append(errs, fmt.Errorf("key_bindings: unknown action '%s', must be one of %s", action, strings.Join(keyActions(), ", ")))
+		} else if _, err := parseKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("key_bindings: %s: %w", action, err))
+		}
+	}
+	if cfg.WatchParty.Host && cfg.WatchParty.Location == "" {
+		errs = append(errs, errors.New("watch_party: the host needs a location"))
+	}
+	if cfg.ConfirmRequests < 0 {
+		errs = append(errs, errors.New("confirm_requests: must not be negative"))
+	}
+	if cfg.RedrawInterval < 0 {
+		errs = append(errs, errors.New("redraw_interval: must not be negative"))
+	}
+	if cfg.RetentionDays < 0 {
+		errs = append(errs, errors.New("retention_days: must not be negative"))
+	}
+	if cfg.RetentionGB < 0 {
+		errs = append(errs, errors.New("retention_gb: must not be negative"))
+	}
+	if cfg.DownloadBandwidth < 0 {
+		errs = append(errs, errors.New("download_bandwidth: must not be negative"))
+	}
+	if cfg.DownloadConcurrency < 0 {
+		errs = append(errs, errors.New("download_concurrency: must not be negative"))
+	}
+	if cfg.MinDownloadBitrate < 0 {
+		errs = append(errs, errors.New("min_download_bitrate: must not be negative"))
+	}
+	for _, t := range cfg.SessionTypes {
+		if !containsFold(sessionTypes, t) {
+			errs = append(errs, fmt.Errorf("session_types: unknown type '%s', must be one of %s", t, strings.Join(sessionTypes, ", ")))
+		}
+	}
+	if cfg.LiveURLRefresh < 0 {
+		errs = append(errs, errors.New("live_url_refresh: must not be negative"))
+	}
+	if cfg.LiveRecordingRetries < 0 {
+		errs = append(errs, errors.New("live_recording_retries: must not be negative"))
+	}
+	if cfg.ExpandPerspectives < 0 {
+		errs = append(errs, errors.New("expand_perspectives: must not be negative"))
+	}
+	if cfg.InfoLabelWidth < 0 {
+		errs = append(errs, errors.New("info_label_width: must not be negative"))
+	}
+	if cfg.NameCacheDays < 0 {
+		errs = append(errs, errors.New("name_cache_days: must not be negative"))
+	}
+	if cfg.PlayerRelaunch < 0 {
+		errs = append(errs, errors.New("player_relaunch: must not be negative"))
+	}
+	if cfg.PlayerStartupTimeout < 0 {
+		errs = append(errs, errors.New("player_startup_timeout: must not be negative"))
+	}
+	if len(cfg.PostPlayCommand) > 0 && cfg.PostPlayCommand[0] == "" {
+		errs = append(errs, errors.New("post_play_command: the first argument must be the program to run"))
+	}
+	if len(cfg.URLResolverCommand) > 0 && cfg.URLResolverCommand[0] == "" {
+		errs = append(errs, errors.New("url_resolver_command: the first argument must be the program to run"))
+	}
+	if cfg.IdleTimeout < 0 {
+		errs = append(errs, errors.New("idle_timeout: must not be negative"))
+	}
+	if cfg.LiveRetryTimeout < 0 {
+		errs = append(errs, errors.New("live_retry_timeout: must not be negative"))
+	}
+	return errs
+}
+
+// loadConfig reads the config file and applies the profile and the environment variables
+func loadConfig(profile string) (config, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return cfg, err
+	}
+	return cfg.resolve(profile, os.LookupEnv)
+}
+
+// resolve applies the profile and then the environment variables to the config from the file.
+// Command line flags take precedence over both, they are applied afterwards with applyFlags.
+func (cfg config) resolve(profile string, lookup func(string) (string, bool)) (config, error) {
+	var err error
+	if profile != "" {
+		cfg, err = cfg.withProfile(profile)
+		if err != nil {
+			return cfg, err
+		}
+	}
+	err = cfg.applyEnv(lookup)
+	if err != nil {
+		return cfg, err
+	}
+	// a malformed base URL would only fail later with confusing request errors
+	if err := validateAPIBaseURL(cfg.APIBaseURL); err != nil {
+		return cfg, err
+	}
+	if cfg.TreeRatio < 1 {
+		cfg.TreeRatio = 1
+	}
+	if cfg.OutputRatio < 1 {
+		cfg.OutputRatio = 1
+	}
+	return cfg, nil
+}
+
+// applyFlags overrides config options with the command line flags that were set
+func (cfg *config) applyFlags(debug bool) {
+	if debug {
+		cfg.Debug = true
+	}
+}
+
+// knownPlayer checks if default_player is empty, mpv, vlc or the title of a custom playback option
+func (cfg config) knownPlayer() bool {
+	switch strings.ToLower(cfg.DefaultPlayer) {
+	case "", "mpv", "vlc":
+		return true
+	}
+	for _, com := range cfg.CustomPlaybackOptions {
+		if strings.EqualFold(com.Title, cfg.DefaultPlayer) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEnv overrides config options with the values of the corresponding environment variables
+func (cfg *config) applyEnv(lookup func(string) (string, bool)) error {
+	for name, field := range map[string]*string{
+		"F1VIEWER_LANG":            &cfg.Lang,
+		"F1VIEWER_LOG_LOCATION":    &cfg.LogLocation,
+		"F1VIEWER_DOWNLOAD_DIR":    &cfg.DownloadLocation,
+		"F1VIEWER_DOWNLOAD_FORMAT": &cfg.DownloadFormat,
+		"F1VIEWER_COLOR_MODE":      &cfg.ColorMode,
+		"F1VIEWER_LOG_LEVEL":       &cfg.LogLevel,
+		"F1VIEWER_PLAYER":          &cfg.DefaultPlayer,
+	} {
+		if value, ok := lookup(name); ok {
+			*field = value
+		}
+	}
+	for name, field := range map[string]*bool{
+		"F1VIEWER_CHECK_UPDATES":     &cfg.CheckUpdate,
+		"F1VIEWER_SAVE_LOGS":         &cfg.SaveLogs,
+		"F1VIEWER_HORIZONTAL_LAYOUT": &cfg.HorizontalLayout,
+		"F1VIEWER_DEBUG":             &cfg.Debug,
+	} {
+		if value, ok := lookup(name); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %w", name, err)
+			}
+			*field = b
+		}
+	}
+	return nil
+}
+
+// readConfig reads the config file without applying a profile, a default config is created if none exists
+func readConfig() (config, error) {
 	var cfg config
 	path, err := getConfigPath()
 	if err != nil {
@@ -51,11 +549,20 @@ func loadConfig() (config, error) {
 
 	if _, err = os.Stat(path + "config.json"); os.IsNotExist(err) {
 		cfg.LiveRetryTimeout = 60
+		cfg.IdleTimeout = 30
+		cfg.SeasonOrder = "descending"
+		cfg.ConfirmRequests = 50
+		cfg.LiveURLRefresh = 20
+		cfg.LiveRecordingRetries = 5
+		cfg.NameCacheDays = 30
 		cfg.Lang = "en"
 		cfg.CheckUpdate = true
 		cfg.SaveLogs = true
+		cfg.LogLevel = "info"
 		cfg.TreeRatio = 1
 		cfg.OutputRatio = 1
+		cfg.ColorMode = "auto"
+		cfg.DownloadCollision = "rename"
 		err = cfg.save()
 		return cfg, err
 	}
@@ -66,14 +573,33 @@ func loadConfig() (config, error) {
 		return cfg, err
 	}
 	err = json.Unmarshal(data, &cfg)
-	if cfg.TreeRatio < 1 {
-		cfg.TreeRatio = 1
+	return cfg, err
+}
+
+// withProfile returns a copy of the config with the fields set in the given profile overridden
+func (cfg config) withProfile(name string) (config, error) {
+	raw, ok := cfg.Profiles[name]
+	if !ok {
+		return cfg, fmt.Errorf("profile '%s' does not exist", name)
 	}
-	if cfg.OutputRatio < 1 {
-		cfg.OutputRatio = 1
+	err := json.Unmarshal(raw, &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("could not parse profile '%s': %w", name, err)
 	}
-	cfg.Theme.apply()
-	return cfg, err
+	cfg.profile = name
+	return cfg, nil
+}
+
+// saveConfig applies update to the active config and persists the change to the config file.
+// Profile overrides are not written back to the file.
+func (session *viewerSession) saveConfig(update func(*config)) error {
+	cfg, err := readConfig()
+	if err != nil {
+		return err
+	}
+	update(&cfg)
+	update(&session.cfg)
+	return cfg.save()
 }
 
 func getConfigPath() (string, error) {
@@ -120,17 +646,34 @@ func getLogPath(cfg config) (string, error) {
 	return path, err
 }
 
+func getDownloadPath(cfg config) (string, error) {
+	if cfg.DownloadLocation == "" {
+		return os.Getwd()
+	}
+	_, err := os.Stat(cfg.DownloadLocation)
+	if os.IsNotExist(err) {
+		err = os.MkdirAll(cfg.DownloadLocation, os.ModePerm)
+	}
+	return cfg.DownloadLocation, err
+}
+
 func (cfg config) save() error {
 	path, err := getConfigPath()
 	if err != nil {
 		return err
 	}
+	return cfg.saveTo(path + "config.json")
+}
+
+// saveTo writes the config to the file.
+// The config contains commands f1viewer runs, so only the user can read or change it.
+func (cfg config) saveTo(file string) error {
 	data, err := json.MarshalIndent(&cfg, "", "\t")
 	if err != nil {
 		return fmt.Errorf("error marshaling config: %v", err)
 	}
 
-	err = ioutil.WriteFile(path+"config.json", data, os.ModePerm)
+	err = writeFileAtomic(file, data, 0600)
 	if err != nil {
 		return fmt.Errorf("error saving config: %v", err)
 	}
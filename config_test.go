@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithProfile(t *testing.T) {
+	t.Parallel()
+	cfg := config{
+		Lang:             "en",
+		HorizontalLayout: false,
+		Theme:            theme{LiveColor: "#ff0000", InfoColor: "#00ff00"},
+		Profiles: map[string]json.RawMessage{
+			"laptop": json.RawMessage(`{"horizontal_layout": true, "theme": {"live_color": "#0000ff"}}`),
+		},
+	}
+
+	merged, err := cfg.withProfile("laptop")
+	assert.NoError(t, err)
+	assert.Equal(t, "laptop", merged.profile)
+	assert.True(t, merged.HorizontalLayout)
+	assert.Equal(t, "en", merged.Lang)
+	assert.Equal(t, "#0000ff", merged.Theme.LiveColor)
+	assert.Equal(t, "#00ff00", merged.Theme.InfoColor)
+
+	// the base config must not be modified
+	assert.False(t, cfg.HorizontalLayout)
+	assert.Equal(t, "#ff0000", cfg.Theme.LiveColor)
+
+	_, err = cfg.withProfile("desktop")
+	assert.EqualError(t, err, "profile 'desktop' does not exist")
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Parallel()
+	env := map[string]string{
+		"F1VIEWER_LANG":          "de",
+		"F1VIEWER_DOWNLOAD_DIR":  "/downloads",
+		"F1VIEWER_CHECK_UPDATES": "false",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	cfg := config{Lang: "en", CheckUpdate: true, SaveLogs: true}
+	assert.NoError(t, cfg.applyEnv(lookup))
+	assert.Equal(t, "de", cfg.Lang)
+	assert.Equal(t, "/downloads", cfg.DownloadLocation)
+	assert.False(t, cfg.CheckUpdate)
+	assert.True(t, cfg.SaveLogs)
+
+	env["F1VIEWER_SAVE_LOGS"] = "maybe"
+	assert.Error(t, cfg.applyEnv(lookup))
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	t.Parallel()
+	var file config
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"preferred_language": "en",
+		"debug": false,
+		"profiles": {"tv": {"preferred_language": "fr", "debug": true, "default_player": "mpv"}}
+	}`), &file))
+	env := map[string]string{"F1VIEWER_DEBUG": "false", "F1VIEWER_PLAYER": "vlc"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	// the profile overrides the file, the environment overrides the profile
+	cfg, err := file.resolve("tv", lookup)
+	assert.NoError(t, err)
+	assert.Equal(t, "fr", cfg.Lang)
+	assert.Equal(t, "vlc", cfg.DefaultPlayer)
+	assert.False(t, cfg.Debug)
+
+	// -d overrides the environment
+	cfg.applyFlags(true)
+	assert.True(t, cfg.Debug)
+	cfg.applyFlags(false)
+	assert.True(t, cfg.Debug)
+}
+
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+	assert.Empty(t, validateConfig([]byte(`{"color_mode": "256", "theme": {"live_color": "#ff0000"}}`)))
+
+	errs := validateConfig([]byte(`{
+		"colour_mode": "256",
+		"download_collision": "replace",
+		"theme": {"live_color": "red"},
+		"profiles": {"tv": {"horizontal_layout": true, "tree_size": 2}}
+	}`))
+	assert.Len(t, errs, 4)
+
+	assert.Len(t, validateConfig([]byte(`{"color_mode": 256}`)), 1)
+}
+
+func TestThemePreset(t *testing.T) {
+	t.Parallel()
+	var cfg config
+	assert.NoError(t, json.Unmarshal([]byte(`{"theme": "nord"}`), &cfg))
+	assert.Equal(t, theme{Preset: "nord"}, cfg.Theme)
+	data, err := json.Marshal(cfg.Theme)
+	assert.NoError(t, err)
+	assert.Equal(t, `"nord"`, string(data))
+
+	// colors layer on top of the preset and profiles can override single fields
+	cfg.Profiles = map[string]json.RawMessage{"tv": json.RawMessage(`{"theme": {"live_color": "#0000ff"}}`)}
+	merged, err := cfg.withProfile("tv")
+	assert.NoError(t, err)
+	assert.Equal(t, theme{Preset: "nord", LiveColor: "#0000ff"}, merged.Theme)
+
+	assert.Empty(t, validateConfig([]byte(`{"theme": {"preset": "Dracula", "live_color": "#ff0000"}}`)))
+	assert.Len(t, validateConfig([]byte(`{"theme": "solarized"}`)), 1)
+	assert.Len(t, validateConfig([]byte(`{"theme": {"live_colour": "#ff0000"}}`)), 1)
+}
+
+func TestSetPreset(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.json")
+
+	cfg := config{
+		Theme: theme{Preset: "nord", LiveColor: "#ff0000"},
+		Profiles: map[string]json.RawMessage{
+			"tv":       json.RawMessage(`{"preferred_language": "de", "theme": {"preset": "nord", "item_node_color": "#00ff00"}}`),
+			"named":    json.RawMessage(`{"theme": "nord"}`),
+			"colors":   json.RawMessage(`{"theme": {"item_node_color": "#00ff00"}}`),
+			"unthemed": json.RawMessage(`{"preferred_language": "de"}`),
+		},
+	}
+	for _, profile := range []string{"", "tv", "named", "colors", "unthemed"} {
+		assert.NoError(t, cfg.setPreset("dracula", profile))
+	}
+	assert.NoError(t, cfg.saveTo(file))
+	data, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	var saved config
+	assert.NoError(t, json.Unmarshal(data, &saved))
+
+	// the overrides survive, the preset is used with and without the profile
+	assert.Equal(t, theme{Preset: "dracula", LiveColor: "#ff0000"}, saved.Theme)
+	for profile, want := range map[string]theme{
+		"tv":       {Preset: "dracula", LiveColor: "#ff0000", ItemNodeColor: "#00ff00"},
+		"named":    {Preset: "dracula", LiveColor: "#ff0000"},
+		"colors":   {Preset: "dracula", LiveColor: "#ff0000", ItemNodeColor: "#00ff00"},
+		"unthemed": {Preset: "dracula", LiveColor: "#ff0000"},
+	} {
+		merged, err := saved.withProfile(profile)
+		assert.NoError(t, err)
+		assert.Equal(t, want, merged.Theme, profile)
+	}
+	merged, err := saved.withProfile("tv")
+	assert.NoError(t, err)
+	assert.Equal(t, "de", merged.Lang)
+}
+
+func TestThemeNodeColorsUnique(t *testing.T) {
+	t.Parallel()
+	session := &viewerSession{}
+	themes := map[string]themeColors{"built-in": defaultTheme()}
+	for _, name := range themeNames() {
+		themes[name] = session.loadTheme(theme{Preset: name})
+	}
+	for name, colors := range themes {
+		seen := make(map[tcell.Color]bool)
+		for _, c := range colors.nodeColors() {
+			assert.False(t, seen[c], "theme %s uses %s for more than one node color", name, colortoHexString(c))
+			seen[c] = true
+		}
+	}
+}
+
+func TestSaveConfigMode(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.json")
+
+	assert.NoError(t, config{Lang: "en"}.saveTo(file))
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// configs that were saved with too many permissions are fixed
+	assert.NoError(t, os.Chmod(file, 0777))
+	assert.NoError(t, config{Lang: "de"}.saveTo(file))
+	info, err = os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// other files keep a more restrictive mode
+	other := filepath.Join(dir, "party.json")
+	assert.NoError(t, ioutil.WriteFile(other, nil, 0600))
+	assert.NoError(t, os.Chmod(other, 0640))
+	assert.NoError(t, writeFileAtomic(other, []byte("{}"), 0644))
+	info, err = os.Stat(other)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
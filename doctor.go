@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// doctorCheck is one check of -doctor, run returns what was found or why the check failed
+type doctorCheck struct {
+	name string
+	run  func() (string, error)
+}
+
+// runDoctor runs the checks and prints a pass or fail line for each of them
+func runDoctor(w io.Writer, checks []doctorCheck) error {
+	failed := 0
+	for _, check := range checks {
+		detail, err := check.run()
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "[FAIL] %s: %v\n", check.name, err)
+			continue
+		}
+		fmt.Fprintf(w, "[PASS] %s: %s\n", check.name, detail)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	fmt.Fprintln(w, "all checks passed")
+	return nil
+}
+
+// doctor checks the setup of f1viewer, the report can be attached to bug reports
+func doctor(w io.Writer, profile string) error {
+	// the player detection logs what it can't find, the report already says that
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	cfg := config{}
+	configErr := error(nil)
+	if path, err := getConfigPath(); err == nil {
+		if _, err := os.Stat(path + "config.json"); err == nil {
+			cfg, configErr = loadConfig(profile)
+		}
+	}
+	session := &viewerSession{cfg: cfg, commands: make(map[string]bool), logLevel: levelError}
+	session.configureAPI()
+	session.checkCommands("vlc", "mpv", "ffmpeg", "ffprobe")
+
+	return runDoctor(w, []doctorCheck{
+		{"config", func() (string, error) {
+			var out bytes.Buffer
+			if err := checkConfig(&out, profile); err != nil {
+				return "", fmt.Errorf("%w\n%s", err, strings.TrimSpace(out.String()))
+			}
+			if configErr != nil {
+				return "", configErr
+			}
+			return strings.TrimSpace(out.String()), nil
+		}},
+		{"players", func() (string, error) {
+			var found []string
+			for _, player := range []string{"mpv", "vlc"} {
+				if session.commandAvailable(player) {
+					found = append(found, player)
+				}
+			}
+			if len(found) == 0 {
+				if session.openerAvailable() {
+					return "", fmt.Errorf("found neither MPV nor VLC, content can only be opened with the system default application")
+				}
+				return "", fmt.Errorf("found neither MPV nor VLC")
+			}
+			return "found " + strings.Join(found, " and "), nil
+		}},
+		{"ffmpeg", func() (string, error) {
+			if !session.commandAvailable("ffmpeg") {
+				return "", fmt.Errorf("not found, it's needed for downloads with download_format")
+			}
+			return "found", nil
+		}},
+		{"ffprobe", func() (string, error) {
+			switch {
+			case session.commandAvailable("ffprobe"):
+				return "found", nil
+			case cfg.VerifyDownloads:
+				return "", fmt.Errorf("not found, it's needed for verify_downloads")
+			default:
+				return "not found, it's only needed for verify_downloads", nil
+			}
+		}},
+		{"API", func() (string, error) {
+			start := time.Now()
+			resp, err := session.apiClient.Get(session.apiEndpoint)
+			if err != nil {
+				return "", err
+			}
+			resp.Body.Close()
+			return fmt.Sprintf("%s reachable in %s", session.apiEndpoint, time.Since(start).Round(time.Millisecond)), nil
+		}},
+		{"config directory", func() (string, error) {
+			path, err := getConfigPath()
+			if err != nil {
+				return "", err
+			}
+			return path, checkWritable(path)
+		}},
+		{"download location", func() (string, error) {
+			path, err := getDownloadPath(cfg)
+			if err != nil {
+				return "", err
+			}
+			return path, checkWritable(path)
+		}},
+		{"credentials", func() (string, error) {
+			if err := session.openRing(); err != nil {
+				return "", fmt.Errorf("could not access credential store: %w", err)
+			}
+			if err := session.loadCredentials(); err != nil {
+				return "", err
+			}
+			return "saved", nil
+		}},
+	})
+}
+
+// checkWritable creates and removes a file in the directory
+func checkWritable(dir string) error {
+	path := filepath.Join(dir, ".f1viewer-doctor")
+	if err := writeFileAtomic(path, []byte("ok"), 0600); err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	return os.Remove(path)
+}
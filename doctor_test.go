@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunDoctor(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	err := runDoctor(&out, []doctorCheck{
+		{"config", func() (string, error) { return "config OK", nil }},
+		{"players", func() (string, error) { return "", errors.New("found neither MPV nor VLC") }},
+	})
+	assert.EqualError(t, err, "1 of 2 checks failed")
+	assert.Equal(t, "[PASS] config: config OK\n[FAIL] players: found neither MPV nor VLC\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, runDoctor(&out, []doctorCheck{{"API", func() (string, error) { return "reachable", nil }}}))
+	assert.Contains(t, out.String(), "all checks passed")
+}
+
+func TestCheckWritable(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, checkWritable(dir))
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Error(t, checkWritable(filepath.Join(dir, "missing")))
+}
@@ -4,13 +4,24 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/rivo/tview"
 )
 
 // returns valid m3u8 URL as string
-func getPlayableURL(assetID, token string) (string, error) {
+func (session *viewerSession) getPlayableURL(assetID, token string) (string, error) {
 	type channelContainer struct {
 		ChannelURL string `json:"channel_url"`
 	}
@@ -31,12 +42,12 @@ func getPlayableURL(assetID, token string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	req, err := http.NewRequest("POST", "https://f1tv.formula1.com/api/viewings/", bytes.NewReader(body))
+	req, err := http.NewRequest("POST", session.apiEndpoint+"viewings/", bytes.NewReader(body))
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("Authorization", "JWT "+token)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := session.apiClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -81,3 +92,437 @@ func getPlayableURL(assetID, token string) (string, error) {
 	parsed, err := url.Parse(urlString)
 	return parsed.String(), err
 }
+
+// downloadAsset saves the content to the download location.
+// If a container format is configured the stream is remuxed with ffmpeg, otherwise the raw transport stream is saved.
+// node is used to find the metadata saved next to the download if download_metadata is set.
+func (session *viewerSession) downloadAsset(epID string, t Titles, node *tview.TreeNode) error {
+	proc, ok := session.trackProcess(downloadKey(epID), t.String(), true)
+	if !ok {
+		return fmt.Errorf("%s is already being downloaded", t.String())
+	}
+	defer session.untrackProcess(proc)
+
+	dir, err := getDownloadPath(session.cfg)
+	if err != nil {
+		return fmt.Errorf("could not get download location: %w", err)
+	}
+	streamURL, err := session.playableURL(epID)
+	if err != nil {
+		return err
+	}
+
+	format := strings.ToLower(session.cfg.DownloadFormat)
+	if format != "" && format != "ts" && !session.commandAvailable("ffmpeg") {
+		session.logWarn("ffmpeg is not available, saving the raw stream instead of ", format)
+		format = ""
+	}
+	file := downloadFile(dir, t, format)
+	format = strings.TrimPrefix(filepath.Ext(file), ".")
+	resolved, err := resolveCollision(file, session.cfg.DownloadCollision)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resolved == "":
+		session.logInfo("skipping download, file already exists: ", file)
+		return nil
+	case resolved != file:
+		session.logInfo("file already exists, saving download as ", resolved)
+		file = resolved
+	case strings.ToLower(session.cfg.DownloadCollision) == "overwrite":
+		session.logInfo("overwriting existing file ", file)
+	}
+
+	program, err := session.downloadVariant(streamURL, t)
+	if err != nil {
+		return err
+	}
+
+	session.logInfo("downloading ", file)
+	// the raw downloader only saves the segments that are in the playlist when it starts,
+	// so live streams are recorded with ffmpeg if possible
+	useFFmpeg := format != "ts" || (t.Live && session.commandAvailable("ffmpeg"))
+	if t.Live && !useFFmpeg {
+		session.logWarn("ffmpeg is not available, only the current part of the live stream is saved")
+	}
+	switch {
+	case !useFFmpeg:
+		err = downloadHLS(session.streamClient, streamURL, file, session.quality(t), session.cfg.DownloadConcurrency, session.downloadLimiter())
+	case t.Live:
+		err = session.recordLive(proc, epID, streamURL, file, program)
+	default:
+		err = session.runRecording(proc, streamURL, file, program, false)
+	}
+	session.notifyExited(proc, err)
+	if err != nil {
+		return err
+	}
+	session.logInfo("download finished: ", file)
+	session.markIcon(iconDownloaded, epID)
+	if session.cfg.VerifyDownloads {
+		session.verifyDownload(file, t, node)
+	}
+	if session.cfg.DownloadMetadata != "" {
+		path, err := writeSidecar(file, session.cfg.DownloadMetadata, session.contentMetadata(node, t))
+		if err != nil {
+			session.logWarn("could not save metadata: ", err)
+		} else {
+			session.logDebug("saved metadata to ", path)
+		}
+	}
+	return nil
+}
+
+// downloadFile returns the path content is downloaded to, without a format the raw .ts stream is saved
+func downloadFile(dir string, t Titles, format string) string {
+	if format == "" {
+		format = "ts"
+	}
+	return filepath.Join(dir, t.String()+"."+format)
+}
+
+// ffmpegArgs is the command that saves the stream to the file, remuxed to the file's container format.
+// ffmpeg reads each variant of a master playlist as a program, if program isn't -1 only that variant is saved.
+func ffmpegArgs(streamURL, file string, program int) []string {
+	args := []string{"ffmpeg", "-hide_banner", "-loglevel", "error", "-n", "-i", streamURL}
+	if program >= 0 {
+		args = append(args, "-map", fmt.Sprintf("0:p:%d", program))
+	}
+	return append(args, "-c", "copy", file)
+}
+
+// copyDownloadCommand copies the ffmpeg command that downloads the content to the clipboard,
+// so it can be run manually with other options. If the clipboard isn't available it's printed instead.
+func (session *viewerSession) copyDownloadCommand(epID string, t Titles) error {
+	dir, err := getDownloadPath(session.cfg)
+	if err != nil {
+		return fmt.Errorf("could not get download location: %w", err)
+	}
+	streamURL, err := session.playableURL(epID)
+	if err != nil {
+		return err
+	}
+	format := strings.ToLower(session.cfg.DownloadFormat)
+	command := shellJoin(ffmpegArgs(streamURL, downloadFile(dir, t, format), -1))
+	if (format == "" || format == "ts") && !t.Live {
+		session.logDebug("f1viewer saves the raw stream without ffmpeg, the command does the same with ffmpeg")
+	}
+	if err := clipboard.WriteAll(command); err != nil {
+		session.logWarn("could not copy the command: ", err)
+		session.logInfo(command)
+		return nil
+	}
+	session.logInfo("download command copied to clipboard, the stream URL expires after a while")
+	return nil
+}
+
+// shellJoin quotes the arguments for the shell of the OS, so the command can be pasted into a terminal
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg, runtime.GOOS == "windows")
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(arg string, windows bool) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'$&|;<>()*?[]{}`!#~%^") {
+		return arg
+	}
+	if windows {
+		return `"` + strings.ReplaceAll(arg, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// resolveCollision returns the file a download should be saved to if the file already exists.
+// Depending on the policy a numbered file name is chosen, the existing file is removed, or an empty string is returned to skip the download.
+func resolveCollision(file, policy string) (string, error) {
+	_, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		return file, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	switch strings.ToLower(policy) {
+	case "skip":
+		return "", nil
+	case "overwrite":
+		return file, os.Remove(file)
+	case "", "rename":
+		ext := filepath.Ext(file)
+		base := strings.TrimSuffix(file, ext)
+		for i := 1; ; i++ {
+			candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+			if _, err := os.Stat(candidate); os.IsNotExist(err) {
+				return candidate, nil
+			}
+		}
+	default:
+		return "", fmt.Errorf("invalid download_collision '%s', must be rename, skip or overwrite", policy)
+	}
+}
+
+// watchAndRecord downloads the content and plays it with the default player at the same time.
+// The node's label shows which of the two is still running.
+func (session *viewerSession) watchAndRecord(node *tview.TreeNode, epID string, t Titles) {
+	commands := session.playerCommands(t)
+	if len(commands) == 0 {
+		session.logError("can't watch and record, no player is available")
+		return
+	}
+	player := commands[0]
+	label := nodeLabel(node)
+
+	go func() {
+		err := session.downloadAsset(epID, t, node)
+		if err != nil {
+			session.logError("recording failed: ", err)
+		}
+	}()
+	err := session.runCustomCommand(commandContext{Titles: t, EpID: epID, CustomOptions: player, Node: node})
+	if err != nil {
+		session.logError(err)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		var state []string
+		if session.processRunning(downloadKey(epID)) {
+			state = append(state, "recording")
+		}
+		if session.processRunning(epID + "|" + player.Title) {
+			state = append(state, "playing")
+		}
+		if len(state) == 0 {
+			setLabel(node, label)
+			session.draw()
+			return
+		}
+		setLabel(node, label+" ("+strings.Join(state, ", ")+")")
+		session.draw()
+	}
+}
+
+type variant struct {
+	bandwidth int
+	uri       string
+}
+
+// selectVariant returns the index of the variant matching the quality: the highest or lowest bandwidth for max and min,
+// or the highest bandwidth that doesn't exceed a bitrate in kbit/s. If every variant exceeds it the lowest one is used.
+func selectVariant(variants []variant, quality string) int {
+	limit := 0
+	if kbits, err := strconv.Atoi(quality); err == nil && kbits > 0 {
+		limit = kbits * 1000
+	}
+	best, lowest := -1, 0
+	for i, v := range variants {
+		if v.bandwidth < variants[lowest].bandwidth {
+			lowest = i
+		}
+		if limit > 0 && v.bandwidth > limit {
+			continue
+		}
+		if best < 0 || v.bandwidth > variants[best].bandwidth {
+			best = i
+		}
+	}
+	if quality == "min" || best < 0 {
+		return lowest
+	}
+	return best
+}
+
+// downloadVariant selects the variant of the stream that is downloaded, or returns -1 if the stream has no variants.
+// If its bitrate is below min_download_bitrate an error is logged, with abort_low_bitrate the download is aborted.
+func (session *viewerSession) downloadVariant(streamURL string, t Titles) (int, error) {
+	lines, err := getPlaylist(session.streamClient, streamURL)
+	if err != nil {
+		return -1, err
+	}
+	variants := parseVariants(lines)
+	if len(variants) == 0 {
+		return -1, nil
+	}
+	i := selectVariant(variants, session.quality(t))
+	kbits := variants[i].bandwidth / 1000
+	session.logDebug(fmt.Sprintf("downloading the %d kbit/s variant of %s", kbits, t.String()))
+	if min := session.cfg.MinDownloadBitrate; min > 0 && kbits < min {
+		msg := fmt.Sprintf("the selected variant of %s only has %d kbit/s, less than min_download_bitrate (%d kbit/s)", t.String(), kbits, min)
+		if session.cfg.AbortLowBitrate {
+			return i, errors.New(msg + ", the download was aborted")
+		}
+		session.logError(msg)
+	}
+	return i, nil
+}
+
+// downloadHLS saves all segments of an HLS stream to a file.
+// For master playlists the variant that matches the quality is downloaded.
+// concurrency segments are downloaded at the same time, limiter limits the bandwidth if it isn't nil.
+func downloadHLS(client *http.Client, playlistURL, file, quality string, concurrency int, limiter *rateLimiter) error {
+	base, err := url.Parse(playlistURL)
+	if err != nil {
+		return err
+	}
+	lines, err := getPlaylist(client, base.String())
+	if err != nil {
+		return err
+	}
+
+	if variants := parseVariants(lines); len(variants) > 0 {
+		best := variants[selectVariant(variants, quality)]
+		base, err = base.Parse(best.uri)
+		if err != nil {
+			return err
+		}
+		lines, err = getPlaylist(client, base.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	segments, err := parseSegments(lines)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	urls := make([]string, len(segments))
+	for i, segment := range segments {
+		segmentURL, err := base.Parse(segment)
+		if err != nil {
+			return err
+		}
+		urls[i] = segmentURL.String()
+	}
+	return downloadSegments(client, urls, out, concurrency, limiter)
+}
+
+type segmentResult struct {
+	data []byte
+	err  error
+}
+
+// downloadSegments downloads up to concurrency segments at the same time and writes them in order
+func downloadSegments(client *http.Client, urls []string, w io.Writer, concurrency int, limiter *rateLimiter) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]chan segmentResult, len(urls))
+	for i := range results {
+		results[i] = make(chan segmentResult, 1)
+	}
+	// a slot is freed once a segment was written, so at most concurrency segments are kept in memory
+	slots := make(chan struct{}, concurrency)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i, u := range urls {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func(i int, u string) {
+				var buf bytes.Buffer
+				err := downloadSegment(client, u, &buf, limiter)
+				results[i] <- segmentResult{data: buf.Bytes(), err: err}
+			}(i, u)
+		}
+	}()
+	for i := range urls {
+		result := <-results[i]
+		<-slots
+		if result.err != nil {
+			return result.err
+		}
+		if _, err := w.Write(result.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getPlaylist(client *http.Client, playlistURL string) ([]string, error) {
+	resp, err := client.Get(playlistURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return lines, nil
+}
+
+func downloadSegment(client *http.Client, segmentURL string, w io.Writer, limiter *rateLimiter) error {
+	resp, err := client.Get(segmentURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, throttle(resp.Body, limiter))
+	return err
+}
+
+// parseVariants returns the variant streams of a master playlist
+func parseVariants(lines []string) []variant {
+	var variants []variant
+	var current *variant
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			current = &variant{}
+			for _, attr := range strings.Split(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"), ",") {
+				if strings.HasPrefix(attr, "BANDWIDTH=") {
+					current.bandwidth, _ = strconv.Atoi(strings.TrimPrefix(attr, "BANDWIDTH="))
+				}
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case current != nil:
+			current.uri = line
+			variants = append(variants, *current)
+			current = nil
+		}
+	}
+	return variants
+}
+
+// parseSegments returns the segment URIs of a media playlist
+func parseSegments(lines []string) ([]string, error) {
+	var segments []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#EXT-X-KEY:") && !strings.Contains(line, "METHOD=NONE") {
+			return nil, errors.New("the stream is encrypted, downloading it requires ffmpeg")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		segments = append(segments, line)
+	}
+	return segments, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVariants(t *testing.T) {
+	t.Parallel()
+	playlist := []string{
+		"#EXTM3U",
+		`#EXT-X-STREAM-INF:BANDWIDTH=1200000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=640x360`,
+		"low/index.m3u8",
+		`#EXT-X-STREAM-INF:BANDWIDTH=6000000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080`,
+		"high/index.m3u8",
+		"",
+	}
+	variants := parseVariants(playlist)
+	assert.Equal(t, []variant{
+		{bandwidth: 1200000, uri: "low/index.m3u8"},
+		{bandwidth: 6000000, uri: "high/index.m3u8"},
+	}, variants)
+
+	assert.Empty(t, parseVariants([]string{"#EXTM3U", "#EXTINF:6.0,", "segment0.ts"}))
+}
+
+func TestParseSegments(t *testing.T) {
+	t.Parallel()
+	playlist := []string{
+		"#EXTM3U",
+		"#EXT-X-TARGETDURATION:6",
+		"#EXTINF:6.0,",
+		"segment0.ts",
+		"#EXTINF:6.0,",
+		"https://cdn.example.com/segment1.ts",
+		"#EXT-X-ENDLIST",
+	}
+	segments, err := parseSegments(playlist)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"segment0.ts", "https://cdn.example.com/segment1.ts"}, segments)
+
+	_, err = parseSegments([]string{`#EXT-X-KEY:METHOD=AES-128,URI="key"`, "segment0.ts"})
+	assert.Error(t, err)
+}
+
+func TestResolveCollision(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "race.ts")
+	resolved, err := resolveCollision(file, "skip")
+	assert.NoError(t, err)
+	assert.Equal(t, file, resolved)
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("race"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "race (1).ts"), []byte("race"), 0644))
+
+	resolved, err = resolveCollision(file, "")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "race (2).ts"), resolved)
+
+	resolved, err = resolveCollision(file, "skip")
+	assert.NoError(t, err)
+	assert.Empty(t, resolved)
+
+	_, err = resolveCollision(file, "replace")
+	assert.Error(t, err)
+
+	resolved, err = resolveCollision(file, "overwrite")
+	assert.NoError(t, err)
+	assert.Equal(t, file, resolved)
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestShellQuote(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "ffmpeg", shellQuote("ffmpeg", false))
+	assert.Equal(t, `'https://example.com/a.m3u8?a=1&b=2'`, shellQuote("https://example.com/a.m3u8?a=1&b=2", false))
+	assert.Equal(t, `'Lewis'\''s onboard.mp4'`, shellQuote("Lewis's onboard.mp4", false))
+	assert.Equal(t, `"C:\Videos\Monaco Race.mp4"`, shellQuote(`C:\Videos\Monaco Race.mp4`, true))
+	assert.Equal(t, "''", shellQuote("", false))
+}
+
+func TestDownloadFile(t *testing.T) {
+	t.Parallel()
+	titles := Titles{EventTitle: "Monaco", SessionTitle: "Race"}
+	assert.Equal(t, filepath.Join("videos", titles.String()+".ts"), downloadFile("videos", titles, ""))
+	assert.Equal(t, filepath.Join("videos", titles.String()+".mkv"), downloadFile("videos", titles, "mkv"))
+}
+
+func TestSelectVariant(t *testing.T) {
+	t.Parallel()
+	variants := []variant{{bandwidth: 3000000}, {bandwidth: 1200000}, {bandwidth: 6000000}}
+	assert.Equal(t, 2, selectVariant(variants, ""))
+	assert.Equal(t, 2, selectVariant(variants, "max"))
+	assert.Equal(t, 1, selectVariant(variants, "min"))
+	assert.Equal(t, 0, selectVariant(variants, "4000"))
+	assert.Equal(t, 1, selectVariant(variants, "500"))
+
+	assert.Equal(t, []string{"ffmpeg", "-hide_banner", "-loglevel", "error", "-n", "-i", "url", "-map", "0:p:2", "-c", "copy", "out.mp4"}, ffmpegArgs("url", "out.mp4", 2))
+	assert.NotContains(t, ffmpegArgs("url", "out.mp4", -1), "-map")
+}
+
+func TestDownloadVariant(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nlow.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=1500000\nmid.m3u8\n")
+	}))
+	defer server.Close()
+	_, s := newTestApp(t, 20, 5)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+	s.cfg.MinDownloadBitrate = 1000
+	s.configureAPI()
+
+	program, err := s.downloadVariant(server.URL, Titles{SessionTitle: "Race"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, program)
+
+	s.cfg.Quality = "1000"
+	s.cfg.AbortLowBitrate = true
+	_, err = s.downloadVariant(server.URL, Titles{SessionTitle: "Race"})
+	assert.EqualError(t, err, "the selected variant of Race only has 800 kbit/s, less than min_download_bitrate (1000 kbit/s), the download was aborted")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// the loading animation doesn't blink faster than this
+const minBlinkInterval = 200 * time.Millisecond
+
+// redrawState tracks whether a redraw is already scheduled, so updates within redraw_interval are drawn together
+type redrawState struct {
+	lock    sync.Mutex
+	pending bool
+}
+
+// redrawInterval returns how long updates are collected before the screen is redrawn, or 0 to redraw right away
+func (session *viewerSession) redrawInterval() time.Duration {
+	return time.Duration(session.cfg.RedrawInterval) * time.Millisecond
+}
+
+// draw redraws the screen. With redraw_interval the redraw is delayed and every update until then is drawn with it,
+// which keeps the UI responsive over slow connections like SSH. Without it the redraw is queued right away.
+// The redraw is queued from another goroutine, so draw never blocks and is safe to call from the UI goroutine.
+func (session *viewerSession) draw() {
+	session.redraw.lock.Lock()
+	defer session.redraw.lock.Unlock()
+	if session.redraw.pending {
+		return
+	}
+	session.redraw.pending = true
+	time.AfterFunc(session.redrawInterval(), func() {
+		session.redraw.lock.Lock()
+		session.redraw.pending = false
+		session.redraw.lock.Unlock()
+		session.app.Draw()
+	})
+}
+
+// blinkInterval returns how often loading nodes change their color, never more often than the screen is redrawn
+func (session *viewerSession) blinkInterval() time.Duration {
+	if interval := session.redrawInterval(); interval > minBlinkInterval {
+		return interval
+	}
+	return minBlinkInterval
+}
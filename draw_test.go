@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDraw(t *testing.T) {
+	t.Parallel()
+	simScreen, s := newTestApp(t, 20, 5)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+	s.cfg.RedrawInterval = 300
+	assert.Equal(t, 300*time.Millisecond, s.blinkInterval())
+
+	s.app.QueueUpdate(func() {
+		s.tree.GetRoot().AddChild(tview.NewTreeNode("Monaco"))
+		// doesn't block in the UI goroutine
+		s.draw()
+		s.draw()
+	})
+	assert.True(t, s.redrawPending())
+	assert.Eventually(t, func() bool {
+		return !s.redrawPending() && strings.Contains(toTextScreen(simScreen), "Monaco")
+	}, time.Second, 10*time.Millisecond)
+
+	s.cfg.RedrawInterval = 0
+	assert.Equal(t, minBlinkInterval, s.blinkInterval())
+	s.app.QueueUpdate(func() {
+		s.tree.GetRoot().AddChild(tview.NewTreeNode("Imola"))
+		// more redraws than tview's update queue holds
+		for i := 0; i < 200; i++ {
+			s.draw()
+		}
+	})
+	assert.Eventually(t, func() bool {
+		return !s.redrawPending() && strings.Contains(toTextScreen(simScreen), "Imola")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (session *viewerSession) redrawPending() bool {
+	session.redraw.lock.Lock()
+	defer session.redraw.lock.Unlock()
+	return session.redraw.pending
+}
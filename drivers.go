@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// driverContent is an episode or onboard perspective a driver appears in
+type driverContent struct {
+	// the episode UID or perspective URL, content is only indexed once
+	id      string
+	label   string
+	newNode func() *tview.TreeNode
+}
+
+// indexDriverContent adds loaded content to the drivers it features, so it is listed under By Driver
+func (session *viewerSession) indexDriverContent(drivers []string, id, label string, newNode func() *tview.TreeNode) {
+	session.driverLock.Lock()
+	defer session.driverLock.Unlock()
+	if session.driverIndex == nil {
+		session.driverIndex = make(map[string][]driverContent)
+	}
+	for _, driver := range drivers {
+		known := false
+		for _, c := range session.driverIndex[driver] {
+			if c.id == id {
+				known = true
+				break
+			}
+		}
+		if !known {
+			session.driverIndex[driver] = append(session.driverIndex[driver], driverContent{id: id, label: label, newNode: newNode})
+		}
+	}
+}
+
+// getDriversNode returns the By Driver category, it lists the content of everything that was loaded so far
+// and is rebuilt every time it's expanded.
+func (session *viewerSession) getDriversNode() *tview.TreeNode {
+	node := tview.NewTreeNode("By Driver").
+		SetColor(session.theme.CategoryNodeColor).
+		SetReference(&NodeMetadata{nodeType: CategoryNode, titles: Titles{CategoryTitle: "By Driver"}})
+
+	load := session.withBlink(node, func() {
+		children := session.getDriverNodes()
+		if len(children) == 0 {
+			children = append(children, tview.NewTreeNode("no drivers yet, open sessions or episodes to list their drivers here").
+				SetColor(session.theme.NoContentColor).
+				SetReference(&NodeMetadata{nodeType: MiscNode}))
+		}
+		node.SetChildren(children)
+		session.styleNodes(node)
+	}, nil)
+	node.SetSelectedFunc(func() {
+		// collapsing doesn't need a reload
+		if node.IsExpanded() && len(node.GetChildren()) > 0 {
+			return
+		}
+		node.SetExpanded(true)
+		load()
+	})
+	return node
+}
+
+// getDriverNodes returns a node for each indexed driver, sorted by name, with the content they appear in
+func (session *viewerSession) getDriverNodes() []*tview.TreeNode {
+	session.driverLock.Lock()
+	index := make(map[string][]driverContent, len(session.driverIndex))
+	var drivers []string
+	for driver, content := range session.driverIndex {
+		index[driver] = append([]driverContent(nil), content...)
+		drivers = append(drivers, driver)
+	}
+	session.driverLock.Unlock()
+
+	names := session.resolveNames(drivers, "driver", session.getDriverName)
+	order := make([]int, len(drivers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return strings.ToLower(names[order[a]]) < strings.ToLower(names[order[b]])
+	})
+
+	var nodes []*tview.TreeNode
+	for _, i := range order {
+		content := index[drivers[i]]
+		sort.SliceStable(content, func(a, b int) bool {
+			return content[a].label < content[b].label
+		})
+		driverNode := tview.NewTreeNode(names[i]).
+			SetColor(session.theme.FolderNodeColor).
+			SetExpanded(false).
+			SetReference(&NodeMetadata{nodeType: MiscNode, id: drivers[i], titles: Titles{CategoryTitle: "By Driver"}})
+		for _, c := range content {
+			driverNode.AddChild(c.newNode())
+		}
+		nodes = append(nodes, driverNode)
+	}
+	return nodes
+}
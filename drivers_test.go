@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriverIndex(t *testing.T) {
+	t.Parallel()
+	session := &viewerSession{
+		theme:     defaultTheme(),
+		nameCache: map[string]string{"/api/driver/1/": "Max Verstappen", "/api/driver/2/": "Lewis Hamilton"},
+	}
+	content := func(label string) func() *tview.TreeNode {
+		return func() *tview.TreeNode { return tview.NewTreeNode(label) }
+	}
+	session.indexDriverContent([]string{"/api/driver/1/", "/api/driver/2/"}, "ep_1", "Race Highlights", content("Race Highlights"))
+	session.indexDriverContent([]string{"/api/driver/1/"}, "/api/channels/1/", "Race - VER", content("Race - VER"))
+	// content that is loaded again isn't listed twice
+	session.indexDriverContent([]string{"/api/driver/1/"}, "ep_1", "Race Highlights", content("Race Highlights"))
+
+	nodes := session.getDriverNodes()
+	if assert.Len(t, nodes, 2) {
+		assert.Equal(t, "Lewis Hamilton", nodes[0].GetText())
+		assert.Equal(t, "Max Verstappen", nodes[1].GetText())
+		var labels []string
+		for _, child := range nodes[1].GetChildren() {
+			labels = append(labels, child.GetText())
+		}
+		assert.Equal(t, []string{"Race - VER", "Race Highlights"}, labels)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// formats the tree can be exported in
+var exportFormats = []string{"txt", "md", "json"}
+
+// exportedNode is a node of the tree in the JSON export
+type exportedNode struct {
+	Label    string          `json:"label"`
+	Type     string          `json:"type"`
+	ID       string          `json:"id,omitempty"`
+	Children []*exportedNode `json:"children,omitempty"`
+}
+
+var nodeTypeNames = map[NodeType]string{
+	CategoryNode: "category",
+	EventNode:    "event",
+	PlayableNode: "episode",
+	StreamNode:   "perspective",
+	MiscNode:     "folder",
+}
+
+// exportTree converts the loaded nodes below node, actions like the player options are left out
+func exportTree(node *tview.TreeNode) []*exportedNode {
+	var nodes []*exportedNode
+	for _, child := range node.GetChildren() {
+		ref, ok := child.GetReference().(*NodeMetadata)
+		if !ok || ref.nodeType == ActionNode {
+			continue
+		}
+		nodes = append(nodes, &exportedNode{
+			Label:    nodeLabel(child),
+			Type:     nodeTypeNames[ref.nodeType],
+			ID:       ref.id,
+			Children: exportTree(child),
+		})
+	}
+	return nodes
+}
+
+// formatTree writes the exported nodes as an indented text outline, a markdown list or JSON
+func formatTree(nodes []*exportedNode, format string) ([]byte, error) {
+	if format == "json" {
+		return json.MarshalIndent(nodes, "", "\t")
+	}
+	var b strings.Builder
+	var write func(nodes []*exportedNode, depth int)
+	write = func(nodes []*exportedNode, depth int) {
+		for _, n := range nodes {
+			switch format {
+			case "md":
+				fmt.Fprintf(&b, "%s- %s\n", strings.Repeat("  ", depth), n.Label)
+			default:
+				fmt.Fprintf(&b, "%s%s\n", strings.Repeat("\t", depth), n.Label)
+			}
+			write(n.Children, depth+1)
+		}
+	}
+	write(nodes, 0)
+	return []byte(b.String()), nil
+}
+
+// exportTreeDialog asks for a format and saves everything that is loaded to the download location
+func (session *viewerSession) exportTreeDialog() {
+	buttons := append(append([]string(nil), exportFormats...), "Cancel")
+	session.showModal("Export the loaded tree as", buttons, func(format string) {
+		session.app.SetFocus(session.tree)
+		if format == "Cancel" {
+			return
+		}
+		path, err := session.saveTree(format, time.Now())
+		if err != nil {
+			session.logError("could not export the tree: ", err)
+			return
+		}
+		session.logInfo("exported the tree to ", path)
+	})
+}
+
+func (session *viewerSession) saveTree(format string, now time.Time) (string, error) {
+	data, err := formatTree(exportTree(session.tree.GetRoot()), format)
+	if err != nil {
+		return "", err
+	}
+	dir, err := getDownloadPath(session.cfg)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "f1viewer-tree-"+now.Format("2006-01-02-150405")+"."+format)
+	return path, writeFileAtomic(path, data, 0644)
+}
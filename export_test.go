@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportTree(t *testing.T) {
+	t.Parallel()
+	root := tview.NewTreeNode("root")
+	seasons := tview.NewTreeNode("Full Seasons").SetReference(&NodeMetadata{nodeType: CategoryNode})
+	event := tview.NewTreeNode("Monaco Grand Prix").SetReference(&NodeMetadata{nodeType: EventNode, id: "/api/event-occurrence/ev_1/"}).SetExpanded(false)
+	stream := tview.NewTreeNode("Main Feed").SetReference(&NodeMetadata{nodeType: StreamNode, id: "/api/channels/ch_1/"})
+	stream.AddChild(tview.NewTreeNode("Play with MPV").SetReference(&NodeMetadata{nodeType: ActionNode}))
+	root.AddChild(seasons.AddChild(event.AddChild(stream)))
+	// tree_prefixes and node_icons aren't exported
+	decorate(stream, "♪ ", "✓ ", "")
+
+	nodes := exportTree(root)
+
+	text, err := formatTree(nodes, "txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "Full Seasons\n\tMonaco Grand Prix\n\t\tMain Feed\n", string(text))
+
+	md, err := formatTree(nodes, "md")
+	assert.NoError(t, err)
+	assert.Equal(t, "- Full Seasons\n  - Monaco Grand Prix\n    - Main Feed\n", string(md))
+
+	data, err := formatTree(nodes, "json")
+	assert.NoError(t, err)
+	var decoded []exportedNode
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	if assert.Len(t, decoded, 1) && assert.Len(t, decoded[0].Children, 1) {
+		ev := decoded[0].Children[0]
+		assert.Equal(t, "event", ev.Type)
+		assert.Equal(t, "/api/event-occurrence/ev_1/", ev.ID)
+		assert.Equal(t, "perspective", ev.Children[0].Type)
+		assert.Empty(t, ev.Children[0].Children)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// session types session_types can contain
+var sessionTypes = []string{"race", "qualifying", "sprint", "practice", "other"}
+
+// matches practice session abbreviations like FP1
+var practiceRegex = regexp.MustCompile(`\bfp\d\b`)
+
+// the usual names of sessions, they are matched before looking for keywords
+var sessionNames = map[string]string{
+	"race":              "race",
+	"grand prix":        "race",
+	"qualifying":        "qualifying",
+	"sprint qualifying": "qualifying",
+	"sprint shootout":   "qualifying",
+	"sprint":            "sprint",
+	"sprint race":       "sprint",
+	"practice 1":        "practice",
+	"practice 2":        "practice",
+	"practice 3":        "practice",
+}
+
+// programmes around the sessions, like the pre-race buildup, are other content even if they're named after a session
+var otherKeywords = []string{"buildup", "build-up", "build up", "show", "press", "preview", "review", "analysis", "highlights", "interview"}
+
+// sessionType returns race, qualifying, sprint or practice for a session name, or other if it's none of them
+func sessionType(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if t, ok := sessionNames[name]; ok {
+		return t
+	}
+	for _, keyword := range otherKeywords {
+		if strings.Contains(name, keyword) {
+			return "other"
+		}
+	}
+	switch {
+	case strings.Contains(name, "shootout") || strings.Contains(name, "quali"):
+		return "qualifying"
+	case strings.Contains(name, "sprint"):
+		return "sprint"
+	case strings.Contains(name, "practice") || practiceRegex.MatchString(name):
+		return "practice"
+	case strings.Contains(name, "race") || strings.Contains(name, "grand prix"):
+		return "race"
+	default:
+		return "other"
+	}
+}
+
+// showSession checks if a node under an event is shown with the session filter, only sessions are filtered
+func (session *viewerSession) showSession(node *tview.TreeNode, types []string) bool {
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok {
+		return true
+	}
+	s, ok := ref.metadata.(sessionStruct)
+	if !ok {
+		return true
+	}
+	name := s.SessionName
+	if name == "" {
+		name = s.Name
+	}
+	return containsFold(types, sessionType(name))
+}
+
+// filterEventSessions remembers all sessions of a loaded event and shows the ones that match the session filter
+func (session *viewerSession) filterEventSessions(eventNode *tview.TreeNode, children []*tview.TreeNode) {
+	session.filterLock.Lock()
+	if session.eventSessions == nil {
+		session.eventSessions = make(map[*tview.TreeNode][]*tview.TreeNode)
+	}
+	session.eventSessions[eventNode] = children
+	session.filterLock.Unlock()
+	session.applySessionFilter(eventNode, children)
+}
+
+func (session *viewerSession) applySessionFilter(eventNode *tview.TreeNode, children []*tview.TreeNode) {
+	if !session.sessionFilterOn() {
+		eventNode.SetChildren(children)
+		session.styleNodes(eventNode)
+		return
+	}
+	var shown []*tview.TreeNode
+	for _, child := range children {
+		if session.showSession(child, session.cfg.SessionTypes) {
+			shown = append(shown, child)
+		}
+	}
+	if len(shown) == 0 && len(children) > 0 {
+		shown = append(shown, tview.NewTreeNode("no "+strings.Join(session.cfg.SessionTypes, " or ")+" sessions, press F to show all").
+			SetColor(session.theme.NoContentColor).
+			SetReference(&NodeMetadata{nodeType: MiscNode}))
+	}
+	eventNode.SetChildren(shown)
+	session.styleNodes(eventNode)
+}
+
+func (session *viewerSession) sessionFilterOn() bool {
+	session.filterLock.Lock()
+	defer session.filterLock.Unlock()
+	return !session.showAllSessions && len(session.cfg.SessionTypes) > 0
+}
+
+// toggleSessionFilter switches between showing only the session_types and all sessions of loaded events
+func (session *viewerSession) toggleSessionFilter() {
+	if len(session.cfg.SessionTypes) == 0 {
+		session.logInfo("set session_types in the config to only show some types of sessions")
+		return
+	}
+	session.filterLock.Lock()
+	session.showAllSessions = !session.showAllSessions
+	events := make(map[*tview.TreeNode][]*tview.TreeNode, len(session.eventSessions))
+	for node, children := range session.eventSessions {
+		events[node] = children
+	}
+	session.filterLock.Unlock()
+
+	for node, children := range events {
+		session.applySessionFilter(node, children)
+	}
+	if session.sessionFilterOn() {
+		session.logInfo("only showing ", strings.Join(session.cfg.SessionTypes, ", "), " sessions")
+	} else {
+		session.logInfo("showing all sessions")
+	}
+}
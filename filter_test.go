@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionType(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"Race":                "race",
+		"Monaco Grand Prix":   "race",
+		"Qualifying":          "qualifying",
+		"Sprint Qualifying":   "qualifying",
+		"Sprint Shootout":     "qualifying",
+		"Sprint":              "sprint",
+		"Practice 1":          "practice",
+		"FP2":                 "practice",
+		"Sprint Race":         "sprint",
+		"Pre-Race Buildup":    "other",
+		"Grand Prix Show":     "other",
+		"Race Highlights":     "other",
+		"Drivers Press Event": "other",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, sessionType(name), name)
+	}
+}
+
+func TestSessionFilter(t *testing.T) {
+	t.Parallel()
+
+	_, s := newTestApp(t, 80, 20)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+	s.cfg.SessionTypes = []string{"race", "qualifying"}
+
+	newSession := func(name string) *tview.TreeNode {
+		return tview.NewTreeNode(name).SetReference(&NodeMetadata{nodeType: PlayableNode, metadata: sessionStruct{Name: name}})
+	}
+	bonus := tview.NewTreeNode("Bonus Content").SetReference(&NodeMetadata{nodeType: MiscNode})
+	children := []*tview.TreeNode{newSession("Practice 1"), newSession("Qualifying"), newSession("Race"), bonus}
+	event := tview.NewTreeNode("Monaco")
+	s.filterEventSessions(event, children)
+	assert.Equal(t, []*tview.TreeNode{children[1], children[2], bonus}, event.GetChildren())
+
+	s.toggleSessionFilter()
+	assert.Equal(t, children, event.GetChildren())
+	s.toggleSessionFilter()
+	assert.Equal(t, []*tview.TreeNode{children[1], children[2], bonus}, event.GetChildren())
+
+	practice := tview.NewTreeNode("Testing")
+	s.filterEventSessions(practice, []*tview.TreeNode{newSession("Practice 1")})
+	if assert.Len(t, practice.GetChildren(), 1) {
+		assert.Contains(t, practice.GetChildren()[0].GetText(), "press F to show all")
+	}
+}
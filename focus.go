@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// what happens when a playback option is selected again while it's running, set with duplicate_playback
+const (
+	duplicateAllow = "allow"
+	duplicateDeny  = "deny"
+	duplicateFocus = "focus"
+)
+
+// focusPlayer brings the window of the running player with the key to the front
+func (session *viewerSession) focusPlayer(key string) {
+	var pid int
+	session.processLock.Lock()
+	for _, p := range session.processes {
+		if p.key == key && p.cmd != nil && p.cmd.Process != nil {
+			pid = p.cmd.Process.Pid
+		}
+	}
+	session.processLock.Unlock()
+	if pid == 0 {
+		// the player is still starting
+		return
+	}
+	args := focusCommand(runtime.GOOS, pid)
+	if len(args) == 0 {
+		session.logDebug("focusing players is not supported on ", runtime.GOOS)
+		return
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		session.logWarn("can't focus the player, ", args[0], " is not installed")
+		return
+	}
+	go func() {
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			session.logWarn("could not focus the player: ", err, " ", strings.TrimSpace(string(out)))
+		}
+	}()
+}
+
+// focusCommand returns the command that brings the window of the process to the front, or nil if there is none
+func focusCommand(goos string, pid int) []string {
+	id := strconv.Itoa(pid)
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"xdotool", "search", "--onlyvisible", "--pid", id, "windowactivate"}
+	case "darwin":
+		return []string{"osascript", "-e", "tell application \"System Events\" to set frontmost of the first process whose unix id is " + id + " to true"}
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-Command", "(New-Object -ComObject WScript.Shell).AppActivate(" + id + ")"}
+	}
+	return nil
+}
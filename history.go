@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/rivo/tview"
+)
+
+// how many nodes the jump history remembers
+const maxJumpHistory = 50
+
+// jumpHistory remembers the nodes f1viewer jumped to, eg. search results, so you can go back and forth between them
+type jumpHistory struct {
+	lock  sync.Mutex
+	nodes []*tview.TreeNode
+	// the entry that was visited last
+	pos int
+}
+
+// push adds a node after the current entry, entries that were gone back from are dropped
+func (h *jumpHistory) push(node *tview.TreeNode) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if len(h.nodes) > 0 {
+		h.nodes = h.nodes[:h.pos+1]
+		if h.nodes[h.pos] == node {
+			return
+		}
+	}
+	h.nodes = append(h.nodes, node)
+	if len(h.nodes) > maxJumpHistory {
+		h.nodes = h.nodes[len(h.nodes)-maxJumpHistory:]
+	}
+	h.pos = len(h.nodes) - 1
+}
+
+// step moves by offset entries and returns the node there, entries valid returns false for are skipped
+func (h *jumpHistory) step(offset int, valid func(*tview.TreeNode) bool) (*tview.TreeNode, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for i := h.pos + offset; i >= 0 && i < len(h.nodes); i += offset {
+		if valid(h.nodes[i]) {
+			h.pos = i
+			return h.nodes[i], true
+		}
+	}
+	return nil, false
+}
+
+// jumpTo selects the node and adds it to the jump history, after the node that was selected before
+func (session *viewerSession) jumpTo(node *tview.TreeNode) {
+	if current := session.tree.GetCurrentNode(); current != nil {
+		session.history.push(current)
+	}
+	session.history.push(node)
+	session.tree.SetCurrentNode(node)
+}
+
+// historyStep goes back to the previous node in the jump history with a negative offset, or forward with a positive one.
+// If you moved somewhere else since the last jump, that node is remembered first so you can come back to it.
+func (session *viewerSession) historyStep(offset int) {
+	root := session.tree.GetRoot()
+	if current := session.tree.GetCurrentNode(); current != nil && offset < 0 {
+		session.history.push(current)
+	}
+	node, ok := session.history.step(offset, func(n *tview.TreeNode) bool {
+		return nodePath(root, n) != nil
+	})
+	if !ok {
+		if offset < 0 {
+			session.logInfo("there is nothing to go back to")
+		} else {
+			session.logInfo("there is nothing to go forward to")
+		}
+		return
+	}
+	// make sure the node is visible
+	for _, parent := range nodePath(root, node) {
+		parent.SetExpanded(true)
+	}
+	session.tree.SetCurrentNode(node)
+}
+
+// nodePath returns the ancestors of the target below root, or nil if it isn't in the tree
+func nodePath(root, target *tview.TreeNode) []*tview.TreeNode {
+	if root == target {
+		return []*tview.TreeNode{}
+	}
+	for _, child := range root.GetChildren() {
+		if path := nodePath(child, target); path != nil {
+			return append([]*tview.TreeNode{root}, path...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJumpHistory(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	root := s.tree.GetRoot()
+	season := tview.NewTreeNode("2020").SetExpanded(false)
+	event := tview.NewTreeNode("Monaco")
+	season.AddChild(event)
+	other := tview.NewTreeNode("Documentaries")
+	root.AddChild(season)
+	root.AddChild(other)
+	s.tree.SetCurrentNode(other)
+
+	s.jumpTo(event)
+	assert.Equal(t, event, s.tree.GetCurrentNode())
+
+	s.historyStep(-1)
+	assert.Equal(t, other, s.tree.GetCurrentNode())
+	s.historyStep(1)
+	assert.Equal(t, event, s.tree.GetCurrentNode())
+
+	// the node you moved to is remembered when going back
+	season.SetExpanded(false)
+	s.tree.SetCurrentNode(root)
+	s.historyStep(-1)
+	assert.Equal(t, event, s.tree.GetCurrentNode())
+	assert.True(t, season.IsExpanded())
+	s.historyStep(1)
+	assert.Equal(t, root, s.tree.GetCurrentNode())
+
+	// removed nodes are skipped
+	season.ClearChildren()
+	s.historyStep(-1)
+	assert.Equal(t, other, s.tree.GetCurrentNode())
+}
+
+func TestNodePath(t *testing.T) {
+	t.Parallel()
+	root := tview.NewTreeNode("root")
+	child := tview.NewTreeNode("child")
+	grandchild := tview.NewTreeNode("grandchild")
+	root.AddChild(child)
+	child.AddChild(grandchild)
+
+	assert.Equal(t, []*tview.TreeNode{root, child}, nodePath(root, grandchild))
+	assert.Empty(t, nodePath(root, root))
+	assert.NotNil(t, nodePath(root, root))
+	assert.Nil(t, nodePath(root, tview.NewTreeNode("other")))
+}
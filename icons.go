@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+)
+
+// states node_icons can show, the icons of a node are shown in this order
+const (
+	iconNew        = "new"
+	iconPlaying    = "playing"
+	iconPlayed     = "played"
+	iconDownloaded = "downloaded"
+)
+
+var iconStates = []string{iconNew, iconPlaying, iconPlayed, iconDownloaded}
+
+// iconState is what node_icons are shown for, keyed by content or event ID
+type iconState struct {
+	lock       sync.Mutex
+	played     map[string]bool
+	downloaded map[string]bool
+	newEvents  map[string]bool
+}
+
+// loadIconState reads which content was played before, from the watch stats
+func (session *viewerSession) loadIconState() {
+	stats, err := session.loadStats()
+	if err != nil {
+		session.logDebug("could not load the played content for node_icons: ", err)
+	}
+	session.icons.lock.Lock()
+	defer session.icons.lock.Unlock()
+	session.icons.played = make(map[string]bool, len(stats))
+	for id := range stats {
+		session.icons.played[id] = true
+	}
+}
+
+func (session *viewerSession) markIcon(state, id string) {
+	session.icons.lock.Lock()
+	defer session.icons.lock.Unlock()
+	var states *map[string]bool
+	switch state {
+	case iconPlayed:
+		states = &session.icons.played
+	case iconDownloaded:
+		states = &session.icons.downloaded
+	case iconNew:
+		states = &session.icons.newEvents
+	default:
+		return
+	}
+	if *states == nil {
+		*states = make(map[string]bool)
+	}
+	(*states)[id] = true
+	session.refreshIcons(id)
+}
+
+// isDownloaded checks if the content was downloaded, the download location is only checked the first time
+func (session *viewerSession) isDownloaded(id string, t Titles) bool {
+	session.icons.lock.Lock()
+	downloaded, ok := session.icons.downloaded[id]
+	session.icons.lock.Unlock()
+	if ok {
+		return downloaded
+	}
+	if dir, err := getDownloadPath(session.cfg); err == nil {
+		_, err := os.Stat(downloadFile(dir, t, strings.ToLower(session.cfg.DownloadFormat)))
+		downloaded = err == nil
+	}
+	session.icons.lock.Lock()
+	defer session.icons.lock.Unlock()
+	if session.icons.downloaded == nil {
+		session.icons.downloaded = make(map[string]bool)
+	}
+	session.icons.downloaded[id] = downloaded
+	return downloaded
+}
+
+// contentPlaying checks if a player is running for the content
+func (session *viewerSession) contentPlaying(id string) bool {
+	session.processLock.Lock()
+	defer session.processLock.Unlock()
+	for _, p := range session.processes {
+		if !p.download && strings.HasPrefix(p.key, id+"|") {
+			return true
+		}
+	}
+	return false
+}
+
+// iconID returns the ID the states of the node are tracked under, events by their ID and content by its playable ID
+func iconID(ref *NodeMetadata) (string, bool) {
+	if ref.nodeType == EventNode {
+		return ref.id, ref.id != ""
+	}
+	return playableID(ref)
+}
+
+// refreshIcons updates the icons of the nodes of the content or event after its state changed.
+// States change on the player and download goroutines, so the tree is updated on the UI goroutine.
+func (session *viewerSession) refreshIcons(id string) {
+	if len(session.cfg.NodeIcons) == 0 || session.app == nil || session.tree == nil {
+		return
+	}
+	go session.app.QueueUpdateDraw(func() {
+		session.tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+			ref, ok := node.GetReference().(*NodeMetadata)
+			if !ok {
+				return true
+			}
+			if nodeID, ok := iconID(ref); ok && nodeID == id {
+				decorate(node, ref.prefix, session.nodeIcons(node), ref.mark)
+			}
+			return true
+		})
+	})
+}
+
+// nodeIcons returns the configured icons for the states of the node, followed by a space
+func (session *viewerSession) nodeIcons(node *tview.TreeNode) string {
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok {
+		return ""
+	}
+	active := make(map[string]bool)
+	if ref.nodeType == EventNode {
+		session.icons.lock.Lock()
+		active[iconNew] = session.icons.newEvents[ref.id]
+		session.icons.lock.Unlock()
+	}
+	if id, ok := playableID(ref); ok {
+		session.icons.lock.Lock()
+		active[iconPlayed] = session.icons.played[id]
+		session.icons.lock.Unlock()
+		active[iconPlaying] = session.contentPlaying(id)
+		if _, ok := session.cfg.NodeIcons[iconDownloaded]; ok {
+			active[iconDownloaded] = session.isDownloaded(id, ref.titles)
+		}
+	}
+	var icons string
+	for _, state := range iconStates {
+		if active[state] {
+			icons += session.cfg.NodeIcons[state]
+		}
+	}
+	if icons == "" {
+		return ""
+	}
+	return icons + " "
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// idleTracker remembers the last input, so background polling can be paused while nobody uses f1viewer
+type idleTracker struct {
+	lock      sync.Mutex
+	lastInput time.Time
+	// closed on the next input, nil if nothing is waiting for it
+	wake chan struct{}
+}
+
+// markActive records an input and wakes up everything that waits for one
+func (t *idleTracker) markActive(now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lastInput = now
+	if t.wake != nil {
+		close(t.wake)
+		t.wake = nil
+	}
+}
+
+// waitChan returns a channel that is closed on the next input, or nil if there was an input within timeout
+func (t *idleTracker) waitChan(now time.Time, timeout time.Duration) chan struct{} {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if now.Sub(t.lastInput) < timeout {
+		return nil
+	}
+	if t.wake == nil {
+		t.wake = make(chan struct{})
+	}
+	return t.wake
+}
+
+// captureInput records key presses as activity before they are handled
+func (session *viewerSession) captureInput(event *tcell.EventKey) *tcell.EventKey {
+	session.idle.markActive(time.Now())
+	return session.confirmQuit(event)
+}
+
+func (session *viewerSession) captureMouse(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+	if action != tview.MouseMove {
+		session.idle.markActive(time.Now())
+	}
+	return event, action
+}
+
+// waitUntilActive blocks while there was no input for idle_timeout minutes.
+// It returns false right away if f1viewer isn't idle or idle_timeout is disabled.
+func (session *viewerSession) waitUntilActive(task string) bool {
+	if session.cfg.IdleTimeout <= 0 {
+		return false
+	}
+	wake := session.idle.waitChan(time.Now(), time.Duration(session.cfg.IdleTimeout)*time.Minute)
+	if wake == nil {
+		return false
+	}
+	session.logInfo("no input for ", session.cfg.IdleTimeout, " minutes, pausing ", task)
+	<-wake
+	session.logInfo("resuming ", task)
+	return true
+}
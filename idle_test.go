@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdleTracker(t *testing.T) {
+	t.Parallel()
+	var tracker idleTracker
+	now := time.Now()
+	tracker.markActive(now)
+	assert.Nil(t, tracker.waitChan(now.Add(time.Minute), 5*time.Minute))
+
+	wake := tracker.waitChan(now.Add(10*time.Minute), 5*time.Minute)
+	assert.NotNil(t, wake)
+	// everything that waits gets the same channel
+	assert.Equal(t, wake, tracker.waitChan(now.Add(11*time.Minute), 5*time.Minute))
+
+	tracker.markActive(now.Add(12 * time.Minute))
+	select {
+	case <-wake:
+	default:
+		t.Error("input didn't wake up the waiting tasks")
+	}
+	assert.Nil(t, tracker.waitChan(now.Add(13*time.Minute), 5*time.Minute))
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// used if info_resolve_timeout isn't set
+const defaultInfoResolveTimeout = 500 * time.Millisecond
+
+// the value column isn't wrapped narrower than this, so labels that take up the pane don't wrap values into single characters
+const minInfoValueWidth = 10
+
+type infoRow struct {
+	key   string
+	value string
+}
+
+// nameRow is an info row listing IDs that have to be resolved to names with API requests
+type nameRow struct {
+	key string
+	// driver or team, used in logs and as the placeholder for names that can't be resolved
+	kind    string
+	ids     []string
+	resolve func(id string) (string, error)
+}
+
+// lookupName requests the name of the ID and caches it. Failed lookups aren't cached,
+// so they are tried again the next time the name is needed.
+func (session *viewerSession) lookupName(id, kind string, resolve func(id string) (string, error)) (string, bool) {
+	name, err := resolve(id)
+	if err == nil && strings.TrimSpace(name) == "" {
+		err = errors.New("empty name")
+	}
+	if err != nil {
+		session.logWarn(fmt.Sprintf("could not look up %s %s: %v", kind, pathToUID(id), err))
+		return "", false
+	}
+	session.nameLock.Lock()
+	if session.nameCache == nil {
+		session.nameCache = make(map[string]string)
+	}
+	session.nameCache[id] = name
+	session.nameLock.Unlock()
+	session.saveName(id, name, time.Now())
+	return name, true
+}
+
+// fallbackName is shown instead of a name that couldn't be resolved, the ID or a placeholder depending on name_fallback
+func (session *viewerSession) fallbackName(id, kind string) string {
+	if session.cfg.NameFallback == "placeholder" && kind != "" {
+		return "unknown " + kind
+	}
+	return pathToUID(id)
+}
+
+// updateInfo shows the metadata of the selected node in the info table, unless the table is pinned to another node
+func (session *viewerSession) updateInfo(node *tview.TreeNode) {
+	if session.infoTable == nil || node == nil {
+		return
+	}
+	if session.pinnedNode != nil && session.pinnedNode != node {
+		return
+	}
+	session.infoTable.Clear()
+	session.infoTable.SetOffset(0, 0)
+	session.infoRows = nil
+	session.infoNode = node
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok {
+		return
+	}
+	rows := nodeInfo(ref.metadata)
+	for i, row := range rows {
+		session.setInfoRow(i, row)
+	}
+	if ep, ok := ref.metadata.(episode); ok {
+		names := []nameRow{
+			{key: "Drivers", kind: "driver", ids: ep.DriverUrls, resolve: session.getDriverName},
+			{key: "Teams", kind: "team", ids: ep.TeamUrls, resolve: session.getTeamName},
+		}
+		for _, nr := range names {
+			if len(nr.ids) > 0 {
+				session.fillNames(node, len(rows), nr)
+				rows = append(rows, infoRow{})
+			}
+		}
+	}
+	session.infoTable.ScrollToBeginning()
+}
+
+func (session *viewerSession) setInfoRow(i int, row infoRow) {
+	for len(session.infoRows) <= i {
+		session.infoRows = append(session.infoRows, infoRow{})
+	}
+	session.infoRows[i] = row
+	session.layoutInfo()
+}
+
+// infoLabelWidth returns the width of the label column, info_label_width or the width of the longest label
+func (session *viewerSession) infoLabelWidth() int {
+	if session.cfg.InfoLabelWidth > 0 {
+		return session.cfg.InfoLabelWidth
+	}
+	var width int
+	for _, row := range session.infoRows {
+		if w := tview.TaggedStringWidth(row.key); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// infoValueWidth returns how wide values can be in the current size of the info table, or 0 if it wasn't drawn yet
+func (session *viewerSession) infoValueWidth() int {
+	_, _, width, _ := session.infoTable.GetInnerRect()
+	if width <= 0 {
+		return 0
+	}
+	// the columns are separated by a space
+	width -= session.infoLabelWidth() + 1
+	if width < minInfoValueWidth {
+		return minInfoValueWidth
+	}
+	return width
+}
+
+// layoutInfo fills the info table with the info rows. Values are wrapped to the width of the table,
+// the lines after the first one are shown in rows without a label.
+func (session *viewerSession) layoutInfo() {
+	labelWidth := session.infoLabelWidth()
+	valueWidth := session.infoValueWidth()
+	session.infoWidth = valueWidth
+	session.infoTable.Clear()
+	var i int
+	for _, row := range session.infoRows {
+		lines := []string{strings.Join(strings.Fields(row.value), " ")}
+		if valueWidth > 0 {
+			lines = tview.WordWrap(lines[0], valueWidth)
+		}
+		for j, line := range lines {
+			var key string
+			if j == 0 {
+				key = row.key
+			}
+			session.infoTable.SetCell(i, 0, tview.NewTableCell(key).
+				SetMaxWidth(labelWidth).
+				SetTextColor(session.theme.CategoryNodeColor))
+			session.infoTable.SetCell(i, 1, tview.NewTableCell(strings.TrimSpace(line)).
+				SetTextColor(session.theme.TerminalTextColor).
+				SetExpansion(1))
+			i++
+		}
+	}
+}
+
+// relayoutInfo wraps the values again when the width of the info table changed.
+// It's the draw func of the info table, so it runs right before the table is drawn.
+func (session *viewerSession) relayoutInfo(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+	// the table has a border
+	x, y, width, height = x+1, y+1, width-2, height-2
+	if session.infoValueWidth() != session.infoWidth {
+		session.layoutInfo()
+	}
+	return x, y, width, height
+}
+
+// fillNames shows the names for the IDs in the row. Names that aren't cached are requested in the background,
+// until the configured timeout runs out a placeholder is shown, after that the raw IDs are shown and replaced as the names arrive.
+func (session *viewerSession) fillNames(node *tview.TreeNode, i int, nr nameRow) {
+	names := make([]string, len(nr.ids))
+	var missing []int
+	session.nameLock.Lock()
+	for j, id := range nr.ids {
+		name, ok := session.nameCache[id]
+		session.metrics.recordLookup("name", ok)
+		if ok {
+			names[j] = name
+		} else {
+			missing = append(missing, j)
+		}
+	}
+	session.nameLock.Unlock()
+	if len(missing) == 0 {
+		session.setInfoRow(i, infoRow{nr.key, strings.Join(names, ", ")})
+		return
+	}
+	session.setInfoRow(i, infoRow{nr.key, "resolving…"})
+
+	timeout := time.Duration(session.cfg.InfoResolveTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultInfoResolveTimeout
+	}
+
+	type result struct {
+		index int
+		name  string
+	}
+	results := make(chan result)
+	for _, j := range missing {
+		go func(j int) {
+			name, _ := session.lookupName(nr.ids[j], nr.kind, nr.resolve)
+			results <- result{index: j, name: name}
+		}(j)
+	}
+
+	go func() {
+		show := func() {
+			value := make([]string, len(names))
+			for j := range names {
+				value[j] = names[j]
+				if value[j] == "" {
+					value[j] = session.fallbackName(nr.ids[j], nr.kind)
+				}
+			}
+			row := infoRow{nr.key, strings.Join(value, ", ")}
+			session.app.QueueUpdateDraw(func() {
+				// the selection might have changed in the meantime
+				if session.infoNode == node {
+					session.setInfoRow(i, row)
+				}
+			})
+		}
+		deadline := time.After(timeout)
+		timedOut := false
+		for pending := len(missing); pending > 0; {
+			select {
+			case r := <-results:
+				pending--
+				names[r.index] = r.name
+				if timedOut {
+					show()
+				}
+			case <-deadline:
+				timedOut = true
+				show()
+			}
+		}
+		if !timedOut {
+			show()
+		}
+	}()
+}
+
+// truncate replaces line breaks with spaces and cuts off the text after max characters with an ellipsis
+func truncate(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// showFullInfo shows the info table's rows without cutting off long values
+func (session *viewerSession) showFullInfo() {
+	text := session.infoText()
+	if text == "" {
+		return
+	}
+	session.showModal(strings.TrimSpace(text), []string{"Close"}, nil)
+}
+
+// togglePin pins the info table to the current node, or unpins it if it's already pinned
+func (session *viewerSession) togglePin() {
+	if session.pinnedNode != nil {
+		session.pinnedNode = nil
+		session.infoTable.SetTitle(" info ")
+		session.updateInfo(session.tree.GetCurrentNode())
+		return
+	}
+	node := session.tree.GetCurrentNode()
+	if node == nil {
+		return
+	}
+	session.updateInfo(node)
+	session.pinnedNode = node
+	session.infoTable.SetTitle(" info (pinned: " + nodeLabel(node) + ") ")
+}
+
+// nodeInfo returns the rows shown in the info table for a node's metadata, empty values are left out
+func nodeInfo(metadata interface{}) []infoRow {
+	var rows []infoRow
+	switch m := metadata.(type) {
+	case seasonStruct:
+		rows = []infoRow{
+			{"Season", m.Name},
+			{"Year", strconv.Itoa(m.Year)},
+			{"Events", strconv.Itoa(len(m.EventoccurrenceUrls))},
+		}
+	case eventStruct:
+		rows = []infoRow{
+			{"Event", m.Name},
+			{"Official name", m.OfficialName},
+			{"Circuit", m.Circuit.Name},
+			{"Country", m.Nation.Name},
+			{"Start", m.StartDate},
+			{"End", m.EndDate},
+		}
+	case sessionStruct:
+		rows = []infoRow{
+			{"Session", m.Name},
+			{"Status", m.Status},
+		}
+	case episode:
+		rows = []infoRow{
+			{"Title", m.Title},
+			{"Subtitle", m.Subtitle},
+			{"Synopsis", m.Synopsis},
+			{"UID", m.UID},
+		}
+	}
+
+	var info []infoRow
+	for _, row := range rows {
+		if strings.TrimSpace(row.value) != "" {
+			info = append(info, row)
+		}
+	}
+	return info
+}
+
+// outputLayout arranges the info table and the output window opposite to the tree
+func (session *viewerSession) outputLayout() *tview.Flex {
+	flex := tview.NewFlex().
+		AddItem(session.infoTable, 0, 1, false).
+		AddItem(session.textWindow, 0, 1, false)
+	if session.debugShown && session.debugWindow != nil {
+		flex.AddItem(session.debugWindow, 0, 1, false)
+	}
+	if !session.cfg.HorizontalLayout {
+		flex.SetDirection(tview.FlexRow)
+	}
+	session.outputFlex = flex
+	return flex
+}
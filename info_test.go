@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeInfo(t *testing.T) {
+	t.Parallel()
+	event := eventStruct{Name: "Monaco Grand Prix", StartDate: "2019-05-23"}
+	event.Circuit.Name = "Circuit de Monaco"
+	event.Nation.Name = "Monaco"
+
+	assert.Equal(t, []infoRow{
+		{"Event", "Monaco Grand Prix"},
+		{"Circuit", "Circuit de Monaco"},
+		{"Country", "Monaco"},
+		{"Start", "2019-05-23"},
+	}, nodeInfo(event))
+	assert.Empty(t, nodeInfo(nil))
+}
+
+func TestFillNames(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 40, 5)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+	s.infoTable = tview.NewTable()
+	// wide enough that the names aren't wrapped
+	s.infoTable.SetRect(0, 0, 80, 10)
+	s.cfg.InfoResolveTimeout = 50
+	node := tview.NewTreeNode("episode")
+	s.infoNode = node
+
+	// the slow name only arrives once the test releases it
+	release := make(chan struct{})
+	resolve := func(id string) (string, error) {
+		if id == "/api/driver/slow/" {
+			<-release
+			return "Slow Driver", nil
+		}
+		return "Fast Driver", nil
+	}
+	value := func() string {
+		var text string
+		s.app.QueueUpdate(func() { text = s.infoTable.GetCell(0, 1).Text })
+		return text
+	}
+
+	s.app.QueueUpdate(func() {
+		s.fillNames(node, 0, nameRow{key: "Drivers", ids: []string{"/api/driver/fast/", "/api/driver/slow/"}, resolve: resolve})
+	})
+	assert.Equal(t, "resolving…", value())
+
+	// after the timeout the IDs of missing names are shown
+	assert.Eventually(t, func() bool { return value() == "Fast Driver, slow" }, time.Second, 10*time.Millisecond)
+
+	close(release)
+	assert.Eventually(t, func() bool { return value() == "Fast Driver, Slow Driver" }, time.Second, 10*time.Millisecond)
+
+	// cached names are shown immediately
+	s.app.QueueUpdate(func() {
+		s.fillNames(node, 0, nameRow{key: "Drivers", ids: []string{"/api/driver/slow/"}, resolve: resolve})
+	})
+	assert.Equal(t, "Slow Driver", value())
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "two lines", truncate("two\nlines ", 10))
+	assert.Equal(t, "a long…", truncate("a long synopsis", 8))
+	assert.Equal(t, "Räikkö…", truncate("Räikkönen", 7))
+
+	ep := episode{Title: "Highlights", Subtitle: " ", Synopsis: "The best moments"}
+	assert.Equal(t, []infoRow{{"Title", "Highlights"}, {"Synopsis", "The best moments"}}, nodeInfo(ep))
+}
+
+func TestInfoWrap(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 40, 5)
+	s.infoTable = tview.NewTable()
+	s.infoTable.SetBorder(true)
+	// 20 columns inside the border, 8 for the label and a space leave 11 for the value
+	s.infoTable.SetRect(0, 0, 22, 10)
+	s.setInfoRow(0, infoRow{"Title", "Highlights"})
+	s.setInfoRow(1, infoRow{"Synopsis", "The best moments\nof the race"})
+
+	cell := func(row, column int) string { return s.infoTable.GetCell(row, column).Text }
+	assert.Equal(t, 4, s.infoTable.GetRowCount())
+	assert.Equal(t, "Synopsis", cell(1, 0))
+	assert.Equal(t, "The best", cell(1, 1))
+	assert.Equal(t, "", cell(2, 0))
+	assert.Equal(t, "moments of", cell(2, 1))
+	assert.Equal(t, "the race", cell(3, 1))
+
+	s.cfg.InfoLabelWidth = 4
+	s.infoTable.SetRect(0, 0, 40, 10)
+	s.relayoutInfo(nil, 0, 0, 40, 10)
+	assert.Equal(t, 2, s.infoTable.GetRowCount())
+	assert.Equal(t, "The best moments of the race", cell(1, 1))
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ipcPath returns the path of MPV's IPC server, a unix socket in the temporary directory
+func ipcPath(name string) string {
+	return filepath.Join(os.TempDir(), name+".sock")
+}
+
+func dialIPC(path string) (io.ReadWriteCloser, error) {
+	return net.Dial("unix", path)
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// ipcPath returns the path of MPV's IPC server, a named pipe
+func ipcPath(name string) string {
+	return `\\.\pipe\` + name
+}
+
+// dialIPC opens the named pipe, it can be used like a file
+func dialIPC(path string) (io.ReadWriteCloser, error) {
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+)
+
+// actions that can be bound to other keys with key_bindings
+const (
+	scrollInfoUp    = "scroll_info_up"
+	scrollInfoDown  = "scroll_info_down"
+	toggleDebugPane = "toggle_debug_pane"
+)
+
+var defaultKeyBindings = map[string]string{
+	scrollInfoUp:    "Shift+Up",
+	scrollInfoDown:  "Shift+Down",
+	toggleDebugPane: "D",
+}
+
+// keyActions returns the names of all actions that can be bound, sorted
+func keyActions() []string {
+	actions := make([]string, 0, len(defaultKeyBindings))
+	for action := range defaultKeyBindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// keyBinding is a key with modifiers, eg. Shift+Up, Ctrl+U or K
+type keyBinding struct {
+	key tcell.Key
+	ch  rune
+	mod tcell.ModMask
+}
+
+// parseKey parses a key like Shift+Up. Special keys use the names tcell uses, eg. PgDn, single characters are runes.
+func parseKey(name string) (keyBinding, error) {
+	var b keyBinding
+	if name == "" {
+		return b, fmt.Errorf("no key")
+	}
+	key := name
+	var mods []string
+	// the key itself can be a +, eg. Ctrl++
+	if i := strings.LastIndex(name[:len(name)-1], "+"); i >= 0 {
+		mods = strings.Split(name[:i], "+")
+		key = name[i+1:]
+	}
+	for _, mod := range mods {
+		switch strings.ToLower(mod) {
+		case "shift":
+			b.mod |= tcell.ModShift
+		case "ctrl":
+			b.mod |= tcell.ModCtrl
+		case "alt":
+			b.mod |= tcell.ModAlt
+		default:
+			return b, fmt.Errorf("unknown modifier '%s' in '%s', must be Shift, Ctrl or Alt", mod, name)
+		}
+	}
+	if utf8.RuneCountInString(key) == 1 {
+		r, _ := utf8.DecodeRuneInString(key)
+		if lower := unicode.ToLower(r); b.mod&tcell.ModCtrl != 0 && lower >= 'a' && lower <= 'z' {
+			// terminals send Ctrl and a letter as a control key
+			b.key = tcell.KeyCtrlA + tcell.Key(lower-'a')
+			return b, nil
+		}
+		b.key, b.ch = tcell.KeyRune, r
+		return b, nil
+	}
+	for k, n := range tcell.KeyNames {
+		if strings.EqualFold(n, key) {
+			b.key = k
+			return b, nil
+		}
+	}
+	return b, fmt.Errorf("unknown key '%s' in '%s'", key, name)
+}
+
+// matches checks if the event is the key. Runes already include Shift, control keys already include Ctrl.
+func (b keyBinding) matches(event *tcell.EventKey) bool {
+	if event.Key() != b.key {
+		return false
+	}
+	switch {
+	case b.key == tcell.KeyRune:
+		return event.Rune() == b.ch && event.Modifiers()&tcell.ModAlt == b.mod&tcell.ModAlt
+	case b.key >= tcell.KeyCtrlA && b.key <= tcell.KeyCtrlZ:
+		return event.Modifiers()&tcell.ModAlt == b.mod&tcell.ModAlt
+	default:
+		mask := tcell.ModShift | tcell.ModCtrl | tcell.ModAlt
+		return event.Modifiers()&mask == b.mod
+	}
+}
+
+// keyBindings returns the keys of all actions, key_bindings replaces the default keys.
+// Invalid keys are reported by validateConfig, here they fall back to the default.
+func keyBindings(custom map[string]string) map[string]keyBinding {
+	bindings := make(map[string]keyBinding, len(defaultKeyBindings))
+	for action, key := range defaultKeyBindings {
+		if k, ok := custom[action]; ok {
+			if b, err := parseKey(k); err == nil {
+				bindings[action] = b
+				continue
+			}
+		}
+		bindings[action], _ = parseKey(key)
+	}
+	return bindings
+}
+
+// keyAction returns the action the key is bound to, or "" if it isn't bound
+func (session *viewerSession) keyAction(event *tcell.EventKey) string {
+	if session.keys == nil {
+		session.keys = keyBindings(session.cfg.KeyBindings)
+	}
+	for action, b := range session.keys {
+		if b.matches(event) {
+			return action
+		}
+	}
+	return ""
+}
+
+// scrollInfo scrolls the info table by delta rows, the tree keeps the focus
+func (session *viewerSession) scrollInfo(delta int) {
+	if session.infoTable == nil {
+		return
+	}
+	row, column := session.infoTable.GetOffset()
+	row += delta
+	if max := session.infoTable.GetRowCount() - 1; row > max {
+		row = max
+	}
+	if row < 0 {
+		row = 0
+	}
+	session.infoTable.SetOffset(row, column)
+}
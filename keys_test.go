@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		matches *tcell.EventKey
+		other   *tcell.EventKey
+	}{
+		{"Shift+Up", tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModShift), tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)},
+		{"pgdn", tcell.NewEventKey(tcell.KeyPgDn, 0, tcell.ModNone), tcell.NewEventKey(tcell.KeyPgUp, 0, tcell.ModNone)},
+		{"Ctrl+U", tcell.NewEventKey(tcell.KeyCtrlU, 0, tcell.ModCtrl), tcell.NewEventKey(tcell.KeyRune, 'u', tcell.ModNone)},
+		{"K", tcell.NewEventKey(tcell.KeyRune, 'K', tcell.ModNone), tcell.NewEventKey(tcell.KeyRune, 'k', tcell.ModNone)},
+		{"Alt+j", tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModAlt), tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone)},
+		{"Alt++", tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModAlt), tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModNone)},
+	}
+	for _, tt := range tests {
+		b, err := parseKey(tt.name)
+		if assert.NoError(t, err, tt.name) {
+			assert.True(t, b.matches(tt.matches), tt.name)
+			assert.False(t, b.matches(tt.other), tt.name)
+		}
+	}
+
+	for _, name := range []string{"", "Hyper+Up", "Upp"} {
+		_, err := parseKey(name)
+		assert.Error(t, err, name)
+	}
+
+	errs := validateConfig([]byte(`{"key_bindings": {"scroll_up": "K"}}`))
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), strings.Join(keyActions(), ", "))
+	}
+	assert.Equal(t, []string{scrollInfoDown, scrollInfoUp, toggleDebugPane}, keyActions())
+}
+
+func TestScrollInfo(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	s.cfg.KeyBindings = map[string]string{scrollInfoDown: "J"}
+	s.infoTable = tview.NewTable()
+	for i := 0; i < 5; i++ {
+		s.infoTable.SetCellSimple(i, 0, fmt.Sprint(i))
+	}
+
+	assert.Nil(t, s.treeInputCapture(tcell.NewEventKey(tcell.KeyRune, 'J', tcell.ModNone)))
+	assert.Nil(t, s.treeInputCapture(tcell.NewEventKey(tcell.KeyRune, 'J', tcell.ModNone)))
+	row, _ := s.infoTable.GetOffset()
+	assert.Equal(t, 2, row)
+
+	assert.Nil(t, s.treeInputCapture(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModShift)))
+	row, _ = s.infoTable.GetOffset()
+	assert.Equal(t, 1, row)
+
+	s.scrollInfo(10)
+	row, _ = s.infoTable.GetOffset()
+	assert.Equal(t, 4, row)
+	s.scrollInfo(-10)
+	row, _ = s.infoTable.GetOffset()
+	assert.Equal(t, 0, row)
+}
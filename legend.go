@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// legendEntry explains what a node color means
+type legendEntry struct {
+	color   tcell.Color
+	meaning string
+}
+
+// themeLegend returns what each node color of the theme means, so the legend always matches the active colors
+func themeLegend(colors themeColors) []legendEntry {
+	return []legendEntry{
+		{colors.CategoryNodeColor, "category"},
+		{colors.FolderNodeColor, "folder, eg. a season or session"},
+		{colors.ItemNodeColor, "perspective or episode that can be played"},
+		{colors.ActionNodeColor, "action, eg. a player or download"},
+		{colors.MultiCommandColor, "multi command"},
+		{colors.LiveColor, "live session"},
+		{colors.UpdateColor, "recently added event"},
+		{colors.NoContentColor, "no content"},
+		{colors.LoadingColor, "loading"},
+		{colors.RateLimitColor, "rate limited, retrying soon"},
+		{colors.GeoBlockColor, "geo-blocked, not available in your region"},
+		{colors.VerifiedColor, "download that was verified"},
+	}
+}
+
+// formatLegend formats the entries as colored lines for a modal
+func formatLegend(entries []legendEntry) string {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("[%s]■ %s[-]", colortoHexString(e.color), e.meaning)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showLegend shows what the colors of the tree mean
+func (session *viewerSession) showLegend() {
+	session.showModal(formatLegend(themeLegend(session.theme)), []string{"Close"}, func(string) {
+		session.app.SetFocus(session.tree)
+	})
+}
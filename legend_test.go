@@ -0,0 +1,21 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThemeLegend(t *testing.T) {
+	t.Parallel()
+	colors := defaultTheme()
+	colors.LiveColor = tcell.NewHexColor(0x123456)
+
+	legend := formatLegend(themeLegend(colors))
+	lines := strings.Split(legend, "\n")
+	assert.Len(t, lines, 12)
+	assert.Contains(t, legend, "[#123456]■ live session[-]")
+	assert.Contains(t, legend, "[#ffa500]■ category[-]")
+}
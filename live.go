@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// addLiveNode adds the live session at the top of the tree, unless one was added already.
+// It returns the live node that is in the tree.
+func (session *viewerSession) addLiveNode(node *tview.TreeNode) *tview.TreeNode {
+	session.liveLock.Lock()
+	defer session.liveLock.Unlock()
+	if session.liveNode != nil {
+		return session.liveNode
+	}
+	session.liveNode = node
+	insertNodeAtTop(session.tree.GetRoot(), node)
+	session.styleCategory(node)
+	return node
+}
+
+// jumpToLive selects the live session and expands it. If it wasn't found yet it is looked up,
+// if nothing is live the next session of the weekend is shown with the time until it starts.
+func (session *viewerSession) jumpToLive() {
+	session.liveLock.Lock()
+	node := session.liveNode
+	session.liveLock.Unlock()
+	if node != nil {
+		session.focusLive(node)
+		return
+	}
+	go func() {
+		isLive, liveNode, err := session.getLiveNode()
+		if err != nil {
+			session.logError("error looking for live session: ", err)
+			return
+		}
+		if isLive {
+			node := session.addLiveNode(liveNode)
+			session.app.QueueUpdateDraw(func() {
+				session.focusLive(node)
+			})
+			return
+		}
+		event, found, err := session.getLiveWeekendEvent()
+		if err != nil {
+			session.logError("error looking for the next session: ", err)
+			return
+		}
+		if !found {
+			session.logInfo("nothing is live and there is no race weekend right now")
+			return
+		}
+		sessions, err := session.getSessions(event.SessionoccurrenceUrls)
+		if err != nil {
+			session.logError("error looking for the next session: ", err)
+			return
+		}
+		next, ok := nextSession(sessions, time.Now())
+		if !ok {
+			session.logInfo("nothing is live and ", event.label(), " has no upcoming sessions")
+			return
+		}
+		session.logInfo(fmt.Sprintf("nothing is live, %s - %s starts in %s", event.label(), next.Name, formatCountdown(time.Until(next.StartTime))))
+	}()
+}
+
+// focusLive selects and expands the live node, and plays its first perspective if live_jump_play is enabled
+func (session *viewerSession) focusLive(node *tview.TreeNode) {
+	node.SetExpanded(true)
+	session.jumpTo(node)
+	if !session.cfg.LiveJumpPlay {
+		return
+	}
+	for _, child := range node.GetChildren() {
+		if ref, ok := child.GetReference().(*NodeMetadata); ok && ref.nodeType == StreamNode {
+			session.tree.SetCurrentNode(child)
+			session.playDefault(child)
+			return
+		}
+	}
+}
+
+// nextSession returns the session that starts next after now
+func nextSession(sessions []sessionStruct, now time.Time) (sessionStruct, bool) {
+	var next sessionStruct
+	found := false
+	for _, s := range sessions {
+		if s.StartTime.IsZero() || !s.StartTime.After(now) {
+			continue
+		}
+		if !found || s.StartTime.Before(next.StartTime) {
+			next = s
+			found = true
+		}
+	}
+	return next, found
+}
+
+// formatCountdown formats the time until a session starts, eg. 1d 2h 5m
+func formatCountdown(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextSession(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, 7, 4, 12, 0, 0, 0, time.UTC)
+	sessions := []sessionStruct{
+		{Name: "Practice 3", StartTime: now.Add(-2 * time.Hour)},
+		{Name: "Race", StartTime: now.Add(26 * time.Hour)},
+		{Name: "Qualifying", StartTime: now.Add(2*time.Hour + 10*time.Minute)},
+		{Name: "Unknown"},
+	}
+	next, ok := nextSession(sessions, now)
+	assert.True(t, ok)
+	assert.Equal(t, "Qualifying", next.Name)
+
+	_, ok = nextSession(sessions[:1], now)
+	assert.False(t, ok)
+
+	assert.Equal(t, "2h 10m", formatCountdown(2*time.Hour+10*time.Minute))
+	assert.Equal(t, "1d 2h 0m", formatCountdown(26*time.Hour))
+	assert.Equal(t, "5m", formatCountdown(5*time.Minute))
+}
+
+func TestAddLiveNode(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 80, 20)
+	s.tree.GetRoot().AddChild(tview.NewTreeNode("Full Seasons"))
+
+	first := tview.NewTreeNode("Race - LIVE").AddChild(tview.NewTreeNode("Main Feed").SetReference(&NodeMetadata{nodeType: StreamNode}))
+	assert.Equal(t, first, s.addLiveNode(first))
+	assert.Equal(t, first, s.addLiveNode(tview.NewTreeNode("Race - LIVE")))
+	assert.Len(t, s.tree.GetRoot().GetChildren(), 2)
+
+	first.SetExpanded(false)
+	s.jumpToLive()
+	assert.True(t, first.IsExpanded())
+	assert.Equal(t, first, s.tree.GetCurrentNode())
+}
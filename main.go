@@ -4,8 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,7 +17,7 @@ import (
 	"github.com/rivo/tview"
 )
 
-var activeTheme = struct {
+type themeColors struct {
 	CategoryNodeColor   tcell.Color
 	FolderNodeColor     tcell.Color
 	ItemNodeColor       tcell.Color
@@ -24,41 +27,165 @@ var activeTheme = struct {
 	MultiCommandColor   tcell.Color
 	UpdateColor         tcell.Color
 	NoContentColor      tcell.Color
+	RateLimitColor      tcell.Color
+	GeoBlockColor       tcell.Color
+	VerifiedColor       tcell.Color
+	WarnColor           tcell.Color
 	InfoColor           tcell.Color
 	ErrorColor          tcell.Color
 	TerminalAccentColor tcell.Color
 	TerminalTextColor   tcell.Color
-}{
-	CategoryNodeColor:   tcell.ColorOrange,
-	FolderNodeColor:     tcell.ColorWhite,
-	ItemNodeColor:       tcell.ColorLightGreen,
-	ActionNodeColor:     tcell.ColorDarkCyan,
-	LoadingColor:        tcell.ColorDarkCyan,
-	LiveColor:           tcell.ColorRed,
-	MultiCommandColor:   tcell.ColorAquaMarine,
-	UpdateColor:         tcell.ColorDarkRed,
-	NoContentColor:      tcell.ColorOrangeRed,
-	InfoColor:           tcell.ColorGreen,
-	ErrorColor:          tcell.ColorRed,
-	TerminalAccentColor: tcell.ColorGreen,
-	TerminalTextColor:   tview.Styles.PrimaryTextColor,
+}
+
+func defaultTheme() themeColors {
+	return themeColors{
+		CategoryNodeColor:   tcell.ColorOrange,
+		FolderNodeColor:     tcell.ColorWhite,
+		ItemNodeColor:       tcell.ColorLightGreen,
+		ActionNodeColor:     tcell.ColorDarkCyan,
+		LoadingColor:        tcell.ColorDarkCyan,
+		LiveColor:           tcell.ColorRed,
+		MultiCommandColor:   tcell.ColorAquaMarine,
+		UpdateColor:         tcell.ColorDarkRed,
+		NoContentColor:      tcell.ColorOrangeRed,
+		RateLimitColor:      tcell.ColorYellow,
+		GeoBlockColor:       tcell.ColorFuchsia,
+		VerifiedColor:       tcell.ColorLime,
+		WarnColor:           tcell.ColorOrange,
+		InfoColor:           tcell.ColorGreen,
+		ErrorColor:          tcell.ColorRed,
+		TerminalAccentColor: tcell.ColorGreen,
+		TerminalTextColor:   tview.Styles.PrimaryTextColor,
+	}
 }
 
 type viewerSession struct {
-	cfg config
+	cfg   config
+	theme themeColors
+	// index of the last bundled theme that was switched to
+	themeIndex int
 
 	ring      keyring.Keyring
 	username  string
 	password  string
 	authtoken string
+	// the API requests are sent to, api_base_url replaces the F1TV API
+	apiEndpoint string
+	apiClient   *http.Client
+	// fetches playlists and segments, through proxy if it is set
+	streamClient *http.Client
 	// tview
 	app        *tview.Application
+	pages      *tview.Pages
 	textWindow *tview.TextView
-	tree       *tview.TreeView
+	infoTable  *tview.Table
+	// every message including debug ones, shown next to the output with D
+	debugWindow *tview.TextView
+	debugShown  bool
+	// the flex with the info table and the output, the debug pane is added to it
+	outputFlex *tview.Flex
+	// the rows shown in the info table, with the full values
+	infoRows []infoRow
+	// width the info values were last wrapped to
+	infoWidth int
+	// node the info table is pinned to, nil if it follows the selection
+	pinnedNode *tview.TreeNode
+	// node the info table currently shows
+	infoNode *tview.TreeNode
+	tree     *tview.TreeView
+
+	// set with "Always load" to load everything without asking, see confirm_requests
+	skipConfirm bool
+	confirmLock sync.Mutex
+
+	// the live session at the top of the tree, nil until one was found
+	liveNode *tview.TreeNode
+	liveLock sync.Mutex
+
+	// content that was played last, used to play the next or previous one
+	playing      *playback
+	playbackLock sync.Mutex
+
+	redraw redrawState
+
+	history jumpHistory
+
+	// the player now_playing_file shows
+	nowPlayingProc *runningProcess
+	nowPlayingLock sync.Mutex
+
+	// the keys of the actions that can be bound with key_bindings
+	keys map[string]keyBinding
+
+	// limits the bandwidth of downloads, ffmpeg downloads through a local proxy that also forwards to proxy
+	limiter      *rateLimiter
+	throttleOnce sync.Once
+	proxyURL     string
+	proxyLock    sync.Mutex
+
+	// all children of loaded events, the session filter shows some of them
+	eventSessions   map[*tview.TreeNode][]*tview.TreeNode
+	showAllSessions bool
+	filterLock      sync.Mutex
+
+	// stream URLs from session bundles, keyed by content ID
+	savedURLs map[string]savedURL
+	urlLock   sync.Mutex
+
+	// driver and team names, keyed by their API path
+	nameCache map[string]string
+	nameLock  sync.Mutex
+
+	// content of loaded episodes and onboard perspectives by driver URL
+	driverIndex map[string][]driverContent
+	driverLock  sync.Mutex
 
 	commands map[string]bool
+	// locks of the cache files, keyed by path
+	cacheFiles     map[string]*sync.Mutex
+	cacheFilesLock sync.Mutex
+	// API requests and cache lookups since f1viewer was started, shown with m
+	metrics fetchMetrics
+	// streams of past sessions, keyed by session UID
+	streamCache map[string][]channel
+	cacheLock   sync.Mutex
+	// processes started by custom commands and players
+	processes   []*runningProcess
+	processLock sync.Mutex
+	// makes sure invalid episode labels are only reported once
+	labelWarning sync.Once
+	// makes sure invalid driver labels are only reported once
+	driverLabelWarning sync.Once
+	// print commands instead of running them
+	dryRun bool
+	// minimum level of messages shown in the output window
+	logLevel logLevel
+	// results of the last event search
+	searchNode *tview.TreeNode
+	// perspectives and episodes selected with x
+	selection []selectedNode
+	// shown instead of the VOD type categories if they couldn't be loaded
+	vodTypesErrorNode *tview.TreeNode
+	// loads that can be cancelled with Ctrl+C
+	activeLoads map[chan struct{}]bool
+	loadLock    sync.Mutex
+	// last time Ctrl+C cancelled loads instead of quitting
+	lastCancel time.Time
+	// the state node_icons shows
+	icons iconState
+	// pauses live session polling after idle_timeout minutes without input
+	idle idleTracker
+	// multi commands and multi-select actions that can be cancelled with X
+	batches   []*batch
+	batchLock sync.Mutex
+	// nodes marked because a player started from them failed to start
+	playerFailures map[*tview.TreeNode]playerFailure
+	failureLock    sync.Mutex
 }
 
+// Ctrl+C quits if it's pressed again within this time after it cancelled loads
+const quitConfirmWindow = 2 * time.Second
+
 var (
 	version = "dev"
 	commit  = ""
@@ -67,6 +194,28 @@ var (
 
 func main() {
 	var showVersion bool
+	profile := os.Getenv("F1VIEWER_PROFILE")
+	var dryRun bool
+	var list, id string
+	var asJSON bool
+	var check bool
+	var serveStreams bool
+	var debug bool
+	var audioDevices bool
+	var play, perspective string
+	var runChecks bool
+	flag.StringVar(&profile, "profile", profile, "name of the config profile to use")
+	flag.BoolVar(&dryRun, "dry-run", dryRun, "print commands instead of running them")
+	flag.StringVar(&list, "list", list, "print seasons, events, sessions or streams instead of starting the UI")
+	flag.StringVar(&id, "id", id, "UID of the season, event or session to list the content of")
+	flag.BoolVar(&asJSON, "json", asJSON, "print the output of -list as JSON")
+	flag.BoolVar(&debug, "d", debug, "enable debug messages and debug actions, same as the debug option")
+	flag.BoolVar(&check, "check-config", check, "validate the config and exit")
+	flag.BoolVar(&runChecks, "doctor", runChecks, "check the config, players, API connection and directories and exit")
+	flag.BoolVar(&serveStreams, "serve", serveStreams, "serve streams over HTTP instead of starting the UI")
+	flag.StringVar(&play, "play", play, "UID of a session to play with the default player instead of starting the UI")
+	flag.StringVar(&perspective, "perspective", perspective, "name of the perspective -play plays, the main feed by default")
+	flag.BoolVar(&audioDevices, "audio-devices", audioDevices, "list the audio devices MPV can use for audio_device")
 	flag.BoolVar(&showVersion, "v", showVersion, "show version information")
 	flag.BoolVar(&showVersion, "version", showVersion, "show version information")
 	flag.Parse()
@@ -74,13 +223,63 @@ func main() {
 		fmt.Println(buildVersion())
 		return
 	}
+	if audioDevices {
+		if err := listAudioDevices(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "[ERROR]", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if runChecks {
+		if err := doctor(os.Stdout, profile); err != nil {
+			fmt.Fprintln(os.Stderr, "[ERROR]", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if check {
+		if err := checkConfig(os.Stdout, profile); err != nil {
+			fmt.Fprintln(os.Stderr, "[ERROR]", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if serveStreams {
+		if err := serve(profile); err != nil {
+			fmt.Fprintln(os.Stderr, "[ERROR]", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if play != "" {
+		if err := playFromCommandLine(os.Stdout, profile, play, perspective, dryRun); err != nil {
+			fmt.Fprintln(os.Stderr, "[ERROR]", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if list != "" {
+		if err := listContent(os.Stdout, profile, list, id, asJSON); err != nil {
+			fmt.Fprintln(os.Stderr, "[ERROR]", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	session, logfile, err := newSession()
+	session, logfile, err := newSession(profile, debug)
 	defer logfile.Close()
 	if err != nil {
 		fmt.Println("[ERROR]", err)
 		log.Fatal(err)
 	}
+	session.dryRun = dryRun
+	screen, err := newScreen()
+	if err != nil {
+		session.logError(err)
+		fmt.Fprintln(os.Stderr, "[ERROR]", err)
+		os.Exit(1)
+	}
+	session.app.SetScreen(screen)
 	go func() {
 		if err := session.app.Run(); err != nil {
 			log.Fatal(err)
@@ -88,28 +287,35 @@ func main() {
 		os.Exit(0)
 	}()
 
-	go session.checkCommands("vlc", "mpv")
+	go session.checkCommands("vlc", "mpv", "ffmpeg", "ffprobe")
 	go session.checkLive()
 	go session.CheckUpdate()
+	go session.checkNewEvents()
+	go session.checkRetention(true)
 
 	// set vod types nodes
 	session.tree.GetRoot().AddChild(session.getCollectionsNode())
-	nodes, err := session.getVodTypeNodes()
-	if err != nil {
-		session.logError(err)
-	} else {
-		appendNodes(session.tree.GetRoot(), nodes...)
-		session.app.Draw()
-	}
+	session.addVodTypeNodes()
+	session.draw()
+
+	session.tree.GetRoot().AddChild(session.getMostWatchedNode())
+	session.tree.GetRoot().AddChild(session.getPlaylistNode())
+	session.tree.GetRoot().AddChild(session.getDriversNode())
+	session.tree.GetRoot().AddChild(session.getBundlesNode())
 
 	logOutNode := tview.NewTreeNode("Log Out").
 		SetReference(&NodeMetadata{nodeType: ActionNode}).
-		SetColor(activeTheme.ActionNodeColor)
+		SetColor(session.theme.ActionNodeColor)
 	logOutNode.SetSelectedFunc(func() {
 		session.logout()
 		session.initUIWithForm()
 	})
 	session.tree.GetRoot().AddChild(logOutNode)
+	root := session.tree.GetRoot()
+	root.SetChildren(orderNodes(root.GetChildren(), session.cfg.CategoryOrder, session.cfg.HiddenCategories))
+	session.styleNodes(root)
+	session.expandCategories(root.GetChildren())
+	session.draw()
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -117,21 +323,45 @@ func main() {
 	<-c
 }
 
-func newSession() (*viewerSession, *os.File, error) {
+func newSession(profile string, debug bool) (*viewerSession, *os.File, error) {
 	var err error
 	session := &viewerSession{}
 
 	session.commands = make(map[string]bool)
 
-	session.cfg, err = loadConfig()
+	session.cfg, err = loadConfig(profile)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Could not open config: %w", err)
 	}
+	session.cfg.applyFlags(debug)
+	// debug is a shortcut for showing debug messages and debug actions
+	if session.cfg.Debug {
+		session.cfg.LogLevel = levelDebug.String()
+		session.cfg.DebugActions = true
+		session.cfg.DebugPane = true
+	}
+	session.configureAPI()
+	configureColorMode(session.cfg.ColorMode)
+	session.theme = session.loadTheme(session.cfg.Theme)
+	if i := findTheme(session.cfg.Theme.Preset); i >= 0 {
+		// t continues with the theme after the configured one
+		session.themeIndex = i
+	}
 
 	logFile, err := configureLogging(session.cfg)
 	if err != nil {
 		return nil, nil, err
 	}
+	session.logLevel, err = parseLogLevel(session.cfg.LogLevel)
+	if err != nil {
+		session.logError(err)
+	}
+	if !session.cfg.knownPlayer() {
+		session.logWarn("unknown default player '", session.cfg.DefaultPlayer, "', must be mpv, vlc or the title of a custom playback option")
+	}
+	if session.cfg.Theme.Preset != "" && findTheme(session.cfg.Theme.Preset) < 0 {
+		session.logWarn("unknown theme '", session.cfg.Theme.Preset, "', must be one of ", strings.Join(themeNames(), ", "))
+	}
 
 	err = session.openRing()
 	if err != nil {
@@ -142,9 +372,15 @@ func newSession() (*viewerSession, *os.File, error) {
 	if err != nil {
 		session.logError(err)
 	}
+	session.loadNames(time.Now())
 
 	session.app = tview.NewApplication()
 	session.app.EnableMouse(true)
+	session.app.SetInputCapture(session.captureInput)
+	session.app.SetMouseCapture(session.captureMouse)
+	session.idle.markActive(time.Now())
+	session.pages = tview.NewPages()
+	session.app.SetRoot(session.pages, true)
 
 	root := tview.NewTreeNode("Categories").SetSelectable(false)
 	root.AddChild(session.getFullSessionsNode())
@@ -153,15 +389,35 @@ func newSession() (*viewerSession, *os.File, error) {
 		SetCurrentNode(root).
 		SetTopLevel(1)
 
-	// refresh supported nodes on 'r' key press
-	session.tree.SetInputCapture(session.nodeRefresh)
+	session.tree.SetInputCapture(session.treeInputCapture)
+	session.styleTree()
 
 	session.textWindow = tview.NewTextView().
-		SetWordWrap(false).
-		SetWrap(false).
+		SetWordWrap(session.cfg.WrapOutput).
+		SetWrap(session.cfg.WrapOutput).
 		SetDynamicColors(true).
-		SetChangedFunc(func() { session.app.Draw() })
+		SetChangedFunc(func() { session.draw() })
 	session.textWindow.SetBorder(true)
+	if session.cfg.profile != "" {
+		session.textWindow.SetTitle(" profile: " + session.cfg.profile + " ")
+	}
+
+	session.debugWindow = tview.NewTextView().
+		SetWordWrap(session.cfg.WrapOutput).
+		SetWrap(session.cfg.WrapOutput).
+		SetDynamicColors(true).
+		SetChangedFunc(func() {
+			if session.debugShown {
+				session.draw()
+			}
+		})
+	session.debugWindow.SetBorder(true).SetTitle(" debug ")
+	session.debugShown = session.cfg.DebugPane
+
+	session.infoTable = tview.NewTable()
+	session.infoTable.SetBorder(true).SetTitle(" info ")
+	session.infoTable.SetDrawFunc(session.relayoutInfo)
+	session.tree.SetChangedFunc(session.updateInfo)
 
 	session.tree.SetSelectedFunc(session.toggleVisibility)
 
@@ -207,21 +463,107 @@ func (session *viewerSession) initUIWithForm() {
 
 	masterFlex.
 		AddItem(formTreeFlex, 0, session.cfg.TreeRatio, true).
-		AddItem(session.textWindow, 0, session.cfg.OutputRatio, false)
+		AddItem(session.outputLayout(), 0, session.cfg.OutputRatio, false)
 
-	session.app.SetRoot(masterFlex, true)
+	session.setLayout(masterFlex)
 }
 
 func (session *viewerSession) initUI() {
 	flex := tview.NewFlex().
 		AddItem(session.tree, 0, session.cfg.TreeRatio, true).
-		AddItem(session.textWindow, 0, session.cfg.OutputRatio, false)
+		AddItem(session.outputLayout(), 0, session.cfg.OutputRatio, false)
 
 	if session.cfg.HorizontalLayout {
 		flex.SetDirection(tview.FlexRow)
 	}
 
-	session.app.SetRoot(flex, true)
+	session.setLayout(flex)
+}
+
+// setLayout replaces the main page of the UI
+func (session *viewerSession) setLayout(layout tview.Primitive) {
+	session.pages.AddPage("main", layout, true, true)
+	session.app.SetFocus(layout)
+}
+
+// showModal shows a dialog on top of the UI.
+// Selecting a button closes the dialog and calls done with the button's label.
+func (session *viewerSession) showModal(text string, buttons []string, done func(label string)) {
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons(buttons).
+		SetDoneFunc(func(_ int, label string) {
+			session.pages.RemovePage("modal")
+			if done != nil {
+				done(label)
+			}
+		})
+	session.pages.AddPage("modal", modal, true, true)
+	session.app.SetFocus(modal)
+}
+
+// showInput shows a single line input in the center of the screen, done is only called if the input was confirmed with enter
+func (session *viewerSession) showInput(label string, done func(text string)) {
+	input := tview.NewInputField().SetLabel(label)
+	input.SetBorder(true)
+	input.SetDoneFunc(func(key tcell.Key) {
+		session.pages.RemovePage("input")
+		if key == tcell.KeyEnter && strings.TrimSpace(input.GetText()) != "" {
+			done(input.GetText())
+		}
+	})
+	layout := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(input, 3, 0, true).
+			AddItem(nil, 0, 1, false), 60, 0, true).
+		AddItem(nil, 0, 1, false)
+	session.pages.AddPage("input", layout, true, true)
+	session.app.SetFocus(input)
+}
+
+// showText shows scrollable text with color tags in a bordered window on top of the UI, Esc or q closes it
+func (session *viewerSession) showText(title, text string) {
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(false).
+		SetText(text)
+	view.SetBorder(true).SetTitle(title)
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyRune && event.Rune() == 'q' {
+			session.pages.RemovePage("text")
+			session.app.SetFocus(session.tree)
+			return nil
+		}
+		return event
+	})
+	session.pages.AddPage("text", view, true, true)
+	session.app.SetFocus(view)
+}
+
+// confirmQuit asks for confirmation before quitting with Ctrl-C while downloads are running
+func (session *viewerSession) confirmQuit(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() != tcell.KeyCtrlC {
+		return event
+	}
+	// the first Ctrl+C cancels running loads, a second one quits
+	if time.Since(session.lastCancel) > quitConfirmWindow && session.cancelLoads() {
+		session.lastCancel = time.Now()
+		session.logInfo("cancelled loading, press Ctrl+C again to quit")
+		return nil
+	}
+	downloads := session.runningDownloads()
+	if len(downloads) == 0 {
+		return event
+	}
+	text := "These downloads are still running and will be aborted:\n\n" + strings.Join(downloads, "\n") + "\n\nQuit anyway?"
+	session.showModal(text, []string{"Wait", "Quit"}, func(label string) {
+		if label == "Quit" {
+			session.app.Stop()
+		}
+	})
+	return nil
 }
 
 func (session *viewerSession) closeForm() {
@@ -243,9 +585,12 @@ func (session *viewerSession) checkLive() {
 				return
 			}
 		} else if isLive {
-			insertNodeAtTop(session.tree.GetRoot(), liveNode)
+			// the live node may have been added already by jumping to it
+			if session.addLiveNode(liveNode) == liveNode {
+				session.notify(notifyLive, nodeLabel(liveNode)+" is live")
+			}
 			if session.app != nil {
-				session.app.Draw()
+				session.draw()
 			}
 			return
 		} else if session.cfg.LiveRetryTimeout <= 0 {
@@ -255,6 +600,7 @@ func (session *viewerSession) checkLive() {
 			session.logInfo("no live session found")
 		}
 		time.Sleep(time.Second * time.Duration(session.cfg.LiveRetryTimeout))
+		session.waitUntilActive("checking for live sessions")
 	}
 }
 
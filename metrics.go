@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fetchMetrics counts the API requests and cache lookups since f1viewer was started
+type fetchMetrics struct {
+	lock        sync.Mutex
+	requests    int
+	failed      int
+	rateLimited int
+	latency     time.Duration
+	slowest     time.Duration
+	caches      map[string]*cacheMetrics
+}
+
+type cacheMetrics struct {
+	hits   int
+	misses int
+}
+
+// recordRequest adds a finished API request
+func (m *fetchMetrics) recordRequest(latency time.Duration, failed, rateLimited bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests++
+	m.latency += latency
+	if latency > m.slowest {
+		m.slowest = latency
+	}
+	if failed {
+		m.failed++
+	}
+	if rateLimited {
+		m.rateLimited++
+	}
+}
+
+// recordLookup adds a lookup in the named cache
+func (m *fetchMetrics) recordLookup(cache string, hit bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.caches == nil {
+		m.caches = make(map[string]*cacheMetrics)
+	}
+	c, ok := m.caches[cache]
+	if !ok {
+		c = &cacheMetrics{}
+		m.caches[cache] = c
+	}
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+// summary returns one line per metric
+func (m *fetchMetrics) summary() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var average time.Duration
+	if m.requests > 0 {
+		average = m.latency / time.Duration(m.requests)
+	}
+	lines := []string{
+		fmt.Sprintf("API requests: %d (%d failed, %d rate limited)", m.requests, m.failed, m.rateLimited),
+		fmt.Sprintf("request latency: %s average, %s slowest, %s total",
+			average.Round(time.Millisecond), m.slowest.Round(time.Millisecond), m.latency.Round(time.Millisecond)),
+	}
+	var names []string
+	for name := range m.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := m.caches[name]
+		lines = append(lines, fmt.Sprintf("%s cache: %d hits, %d misses", name, c.hits, c.misses))
+	}
+	return lines
+}
+
+// printMetrics shows the metrics in the output window
+func (session *viewerSession) printMetrics() {
+	for _, line := range session.metrics.summary() {
+		session.logInfo(line)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchMetrics(t *testing.T) {
+	t.Parallel()
+	m := &fetchMetrics{}
+	m.recordRequest(100*time.Millisecond, false, false)
+	m.recordRequest(300*time.Millisecond, true, true)
+	m.recordLookup("stream", true)
+	m.recordLookup("stream", false)
+	m.recordLookup("name", false)
+
+	assert.Equal(t, []string{
+		"API requests: 2 (1 failed, 1 rate limited)",
+		"request latency: 200ms average, 300ms slowest, 400ms total",
+		"name cache: 0 hits, 1 misses",
+		"stream cache: 1 hits, 1 misses",
+	}, m.summary())
+}
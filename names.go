@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// cachedName is a driver or team name saved to disk, so the info table doesn't have to look it up again after a restart
+type cachedName struct {
+	Name     string    `json:"name"`
+	Resolved time.Time `json:"resolved"`
+}
+
+func getNamesPath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return path + "names.json", nil
+}
+
+// nameCacheTTL returns how long saved names are used, or 0 if they aren't saved
+func (session *viewerSession) nameCacheTTL() time.Duration {
+	return time.Duration(session.cfg.NameCacheDays) * 24 * time.Hour
+}
+
+// loadNames fills the name cache with the saved names that aren't older than name_cache_days
+func (session *viewerSession) loadNames(now time.Time) {
+	ttl := session.nameCacheTTL()
+	if ttl <= 0 {
+		return
+	}
+	path, err := getNamesPath()
+	if err != nil {
+		session.logWarn("could not load saved names: ", err)
+		return
+	}
+	names := make(map[string]cachedName)
+	if err := session.readCache(path, &names); err != nil {
+		if !os.IsNotExist(err) {
+			session.logWarn("could not load saved names: ", err)
+		}
+		return
+	}
+	session.nameLock.Lock()
+	defer session.nameLock.Unlock()
+	if session.nameCache == nil {
+		session.nameCache = make(map[string]string)
+	}
+	for id, name := range freshNames(names, now, ttl) {
+		session.nameCache[id] = name
+	}
+}
+
+// freshNames returns the names that were resolved within the TTL
+func freshNames(names map[string]cachedName, now time.Time, ttl time.Duration) map[string]string {
+	fresh := make(map[string]string, len(names))
+	for id, n := range names {
+		if n.Name != "" && now.Sub(n.Resolved) < ttl {
+			fresh[id] = n.Name
+		}
+	}
+	return fresh
+}
+
+// saveName adds a resolved name to the saved names and drops expired ones
+func (session *viewerSession) saveName(id, name string, now time.Time) {
+	ttl := session.nameCacheTTL()
+	if ttl <= 0 {
+		return
+	}
+	path, err := getNamesPath()
+	if err != nil {
+		session.logDebug("could not save name: ", err)
+		return
+	}
+	names := make(map[string]cachedName)
+	err = session.updateCache(path, &names, func(readErr error) error {
+		if errors.Is(readErr, errInvalidCache) {
+			names = make(map[string]cachedName)
+		} else if readErr != nil {
+			return readErr
+		}
+		for id, n := range names {
+			if now.Sub(n.Resolved) >= ttl {
+				delete(names, id)
+			}
+		}
+		names[id] = cachedName{Name: name, Resolved: now}
+		return nil
+	})
+	if err != nil {
+		session.logDebug("could not save name: ", err)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreshNames(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, 7, 5, 12, 0, 0, 0, time.UTC)
+	names := map[string]cachedName{
+		"/api/driver/1/": {Name: "Lewis Hamilton", Resolved: now.Add(-24 * time.Hour)},
+		"/api/driver/2/": {Name: "Max Verstappen", Resolved: now.Add(-40 * 24 * time.Hour)},
+		"/api/team/1/":   {Name: "", Resolved: now},
+	}
+	assert.Equal(t, map[string]string{"/api/driver/1/": "Lewis Hamilton"}, freshNames(names, now, 30*24*time.Hour))
+	assert.Empty(t, freshNames(names, now, time.Hour))
+}
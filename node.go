@@ -3,8 +3,14 @@ package main
 import (
 	"errors"
 	"fmt"
+	"os"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/gdamore/tcell"
@@ -13,11 +19,26 @@ import (
 
 var errNoSessions = errors.New("event has not past or live events")
 
+// events without a name, circuit or country can't be labeled, they are skipped
+var errNoEventLabel = errors.New("event has no name, circuit or country")
+
+var errLoadCancelled = errors.New("loading cancelled")
+
+// added to the label of nodes that aren't available in the user's region
+const geoBlockedLabel = " (geo-blocked)"
+
+// used if driver_label isn't set
+const defaultDriverLabel = "{Number} {Name}"
+
 // NodeMetadata is used for treenode references and holds metadata about a node
 type NodeMetadata struct {
 	nodeType NodeType
 	id       string
 	titles   Titles
+	// API data shown in the info table
+	metadata interface{}
+	// the tree_prefixes prefix, node_icons icons and selection mark shown in front of the label
+	prefix, icons, mark string
 	sync.Mutex
 }
 
@@ -34,6 +55,140 @@ const (
 	MiscNode
 )
 
+func (session *viewerSession) treeInputCapture(keyEvent *tcell.EventKey) *tcell.EventKey {
+	switch session.keyAction(keyEvent) {
+	case scrollInfoUp:
+		session.scrollInfo(-1)
+		return nil
+	case scrollInfoDown:
+		session.scrollInfo(1)
+		return nil
+	case toggleDebugPane:
+		session.toggleDebugPane()
+		return nil
+	}
+	if keyEvent.Key() == tcell.KeyEscape && len(session.selection) > 0 {
+		session.clearSelection()
+		return nil
+	}
+	// with enter_action play, enter plays perspectives and episodes and alt+enter expands them
+	if keyEvent.Key() == tcell.KeyEnter && keyEvent.Modifiers()&tcell.ModAlt == 0 && session.cfg.EnterAction == "play" {
+		if session.playDefault(session.tree.GetCurrentNode()) {
+			return nil
+		}
+	}
+	if keyEvent.Key() != tcell.KeyRune {
+		return keyEvent
+	}
+	switch keyEvent.Rune() {
+	case 'r':
+		// refresh supported nodes
+		return session.nodeRefresh(keyEvent)
+	case 't':
+		session.cycleTheme()
+		return nil
+	case 'v':
+		session.cycleLogLevel()
+		return nil
+	case 'm':
+		session.printMetrics()
+		return nil
+	case 'e':
+		// expand the node like enter does by default
+		return tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModAlt)
+	case 'x':
+		session.toggleSelection(session.tree.GetCurrentNode())
+		return nil
+	case 'a':
+		session.selectionAction()
+		return nil
+	case 'X':
+		session.cancelBatches()
+		return nil
+	case 's':
+		session.toggleSeasonOrder()
+		return nil
+	case 'E':
+		session.exportTreeDialog()
+		return nil
+	case 'L':
+		session.jumpToLive()
+		return nil
+	case 'B':
+		session.bundleSession(session.tree.GetCurrentNode())
+		return nil
+	case '[':
+		session.historyStep(-1)
+		return nil
+	case ']':
+		session.historyStep(1)
+		return nil
+	case 'C':
+		go session.checkRetention(false)
+		return nil
+	case 'F':
+		session.toggleSessionFilter()
+		return nil
+	case '?':
+		session.showLegend()
+		return nil
+	case 'p':
+		session.togglePin()
+		return nil
+	case 'i':
+		session.showFullInfo()
+		return nil
+	case 'I':
+		session.openInfoInPager()
+		return nil
+	case 'o':
+		session.showPlayerOutput(session.tree.GetCurrentNode())
+		return nil
+	case 'J':
+		session.showRawJSON(session.tree.GetCurrentNode())
+		return nil
+	case 'c':
+		session.copyNodeID(session.tree.GetCurrentNode())
+		return nil
+	case 'n':
+		session.playSibling(1)
+		return nil
+	case 'N':
+		session.playSibling(-1)
+		return nil
+	case '/':
+		session.showInput("Search events: ", func(query string) {
+			go session.searchEvents(query)
+		})
+		return nil
+	default:
+		return keyEvent
+	}
+}
+
+// copyNodeID copies the API ID of the node to the clipboard.
+// Streams are copied as their API path, everything else as the UID the command line flags take.
+func (session *viewerSession) copyNodeID(node *tview.TreeNode) {
+	if node == nil {
+		return
+	}
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok || ref.id == "" {
+		session.logInfo(nodeLabel(node), " has no ID")
+		return
+	}
+	id := ref.id
+	if ref.nodeType != StreamNode {
+		id = pathToUID(id)
+	}
+	err := clipboard.WriteAll(id)
+	if err != nil {
+		session.logError(err)
+		return
+	}
+	session.logInfo("copied ", id, " to clipboard")
+}
+
 func (session *viewerSession) nodeRefresh(keyEvent *tcell.EventKey) *tcell.EventKey {
 	// only listen for 'r' key
 	if keyEvent.Key() != tcell.KeyRune || keyEvent.Rune() != 'r' {
@@ -41,6 +196,10 @@ func (session *viewerSession) nodeRefresh(keyEvent *tcell.EventKey) *tcell.Event
 	}
 
 	node := session.tree.GetCurrentNode()
+	if node != nil && node == session.vodTypesErrorNode {
+		go session.retryVodTypes(node)
+		return nil
+	}
 	metadata, err := getMetadata(node)
 	if err != nil {
 		session.logError(err)
@@ -62,7 +221,7 @@ func (session *viewerSession) nodeRefresh(keyEvent *tcell.EventKey) *tcell.Event
 
 func (session *viewerSession) updateEvent(node *tview.TreeNode, metadata *NodeMetadata) {
 	node.ClearChildren().SetSelectedFunc(nil)
-	event, err := getEvent(metadata.id)
+	event, err := session.getEvent(metadata.id)
 	if err != nil {
 		session.logError("Could not refresh event: ", err)
 		return
@@ -77,8 +236,9 @@ func (session *viewerSession) updateEvent(node *tview.TreeNode, metadata *NodeMe
 	appendNodes(node, sessions...)
 
 	if len(node.GetChildren()) == 0 {
-		node.AddChild(nocontentNode())
+		node.AddChild(session.nocontentNode())
 	}
+	session.filterEventSessions(node, node.GetChildren())
 	node.SetExpanded(true)
 }
 
@@ -96,54 +256,184 @@ func getMetadata(node *tview.TreeNode) (*NodeMetadata, error) {
 
 func (session *viewerSession) getFullSessionsNode() *tview.TreeNode {
 	fullSessions := tview.NewTreeNode("Full Seasons").
-		SetColor(activeTheme.CategoryNodeColor).
+		SetColor(session.theme.CategoryNodeColor).
 		SetReference(&NodeMetadata{nodeType: CategoryNode, titles: Titles{CategoryTitle: "Full Seasons"}})
 
-	fullSessions.SetSelectedFunc(session.withBlink(fullSessions, func() {
-		fullSessions.SetSelectedFunc(nil)
-		seasons, err := session.getSeasonNodes()
-		if err != nil {
-			session.logError(err)
-		} else {
-			appendNodes(fullSessions, seasons...)
-		}
-	}, nil))
+	session.lazyLoad(fullSessions, session.getSeasonNodes)
 	return fullSessions
 }
 
-func (session *viewerSession) getPlaybackNodes(sessionTitles Titles, epID string) []*tview.TreeNode {
-	nodes := make([]*tview.TreeNode, 0)
+// playerCommands returns the custom playback options followed by the available default players.
+// The first command is used as the default player, default_player moves the player it selects to the front.
+func (session *viewerSession) playerCommands(t Titles) []command {
+	return preferPlayer(session.availablePlayers(t), session.cfg.DefaultPlayer)
+}
 
-	// add custom options
-	if session.cfg.CustomPlaybackOptions != nil {
-		for i := range session.cfg.CustomPlaybackOptions {
-			com := session.cfg.CustomPlaybackOptions[i]
-			if len(com.Command) > 0 {
-				nodes = append(nodes, session.createCommandNode(sessionTitles, epID, com))
-			}
+// preferPlayer moves the command with the title to the front, mpv and vlc select the first command that runs them
+func preferPlayer(commands []command, player string) []command {
+	if player == "" {
+		return commands
+	}
+	for i, com := range commands {
+		builtIn := (strings.EqualFold(player, "mpv") || strings.EqualFold(player, "vlc")) && strings.EqualFold(com.Command[0], player)
+		if strings.EqualFold(com.Title, player) || builtIn {
+			return append(append([]command{com}, commands[:i]...), commands[i+1:]...)
 		}
 	}
+	return commands
+}
 
+// availablePlayers returns the custom playback options followed by MPV, VLC and the system opener if they are available
+func (session *viewerSession) availablePlayers(t Titles) []command {
+	var commands []command
+	for _, com := range session.cfg.CustomPlaybackOptions {
+		if len(com.Command) > 0 {
+			commands = append(commands, com)
+		}
+	}
 	if session.commandAvailable("mpv") {
 		mpvCommand := command{
 			Title:   "Play with MPV",
 			Command: []string{"mpv", "$url", "--alang=" + session.cfg.Lang, "--start=0", "--quiet", "--title=$title"},
 		}
-		nodes = append(nodes, session.createCommandNode(sessionTitles, epID, mpvCommand))
+		if profile := mpvProfile(session.cfg.MPVProfiles, t); profile != "" {
+			mpvCommand.Command = append(mpvCommand.Command, "--profile="+profile)
+		}
+		if session.quality(t) != "" {
+			mpvCommand.Command = append(mpvCommand.Command, "--hls-bitrate=$hls_bitrate")
+		}
+		if session.cfg.AudioDevice != "" {
+			mpvCommand.Command = append(mpvCommand.Command, "--audio-device="+session.cfg.AudioDevice)
+		}
+		commands = append(commands, mpvCommand)
+		if t.Live {
+			// MPV joins live streams at the live edge, live_start_index=0 starts at the oldest segment the stream still has
+			fromStart := command{
+				Title:   "Play from beginning with MPV",
+				Command: append(append([]string(nil), mpvCommand.Command...), "--demuxer-lavf-o=live_start_index=0"),
+			}
+			commands = append(commands, fromStart)
+		}
 	}
 	if session.commandAvailable("vlc") {
 		vlcCommand := command{
 			Title:   "Play with VLC",
 			Command: []string{"vlc", "$url", "--meta-title=$title"},
 		}
-		nodes = append(nodes, session.createCommandNode(sessionTitles, epID, vlcCommand))
+		vlcCommand.Command = append(vlcCommand.Command, vlcQualityArgs(session.quality(t))...)
+		vlcCommand.Command = append(vlcCommand.Command, session.cfg.VLCAudioOptions...)
+		commands = append(commands, vlcCommand)
+	}
+	if session.openerAvailable() {
+		commands = append(commands, command{
+			Title:   "Open in system default",
+			Command: openerCommand(runtime.GOOS),
+		})
+	}
+	return commands
+}
+
+// mpvProfile returns the MPV profile for the content.
+// Profiles for perspective names take precedence over the ones for content types.
+func mpvProfile(profiles map[string]string, t Titles) string {
+	if profile, ok := profiles[t.PerspectiveTitle]; ok && t.PerspectiveTitle != "" {
+		return profile
+	}
+	switch {
+	case t.Live:
+		return profiles["live"]
+	case t.EpisodeTitle != "":
+		return profiles["episode"]
+	default:
+		return profiles["replay"]
+	}
+}
+
+func (session *viewerSession) getPlaybackNodes(sessionTitles Titles, epID string) []*tview.TreeNode {
+	nodes := make([]*tview.TreeNode, 0)
+
+	players := session.playerCommands(sessionTitles)
+	for _, com := range players {
+		nodes = append(nodes, session.createCommandNode(sessionTitles, epID, com))
+	}
+
+	if partyNode := session.partyNode(sessionTitles, epID); partyNode != nil {
+		nodes = append(nodes, partyNode)
+	}
+
+	if len(players) > 0 {
+		recordNode := tview.NewTreeNode("Watch and Record").
+			SetColor(session.theme.ActionNodeColor).
+			SetReference(&NodeMetadata{nodeType: ActionNode, titles: sessionTitles})
+		recordNode.SetSelectedFunc(func() {
+			go session.watchAndRecord(recordNode, epID, sessionTitles)
+		})
+		nodes = append(nodes, recordNode)
+	}
+
+	downloadNode := tview.NewTreeNode("Download").
+		SetColor(session.theme.ActionNodeColor).
+		SetReference(&NodeMetadata{nodeType: ActionNode, titles: sessionTitles})
+	downloadNode.SetSelectedFunc(func() {
+		go func() {
+			err := session.downloadAsset(epID, sessionTitles, downloadNode)
+			if err != nil {
+				session.logError("download failed: ", err)
+			}
+		}()
+	})
+	nodes = append(nodes, downloadNode)
+
+	downloadCommandNode := tview.NewTreeNode("Copy download command").
+		SetColor(session.theme.ActionNodeColor).
+		SetReference(&NodeMetadata{nodeType: ActionNode, titles: sessionTitles})
+	downloadCommandNode.SetSelectedFunc(func() {
+		go func() {
+			err := session.copyDownloadCommand(epID, sessionTitles)
+			if err != nil {
+				session.logError(err)
+			}
+		}()
+	})
+	nodes = append(nodes, downloadCommandNode)
+
+	playlistNode := tview.NewTreeNode("Add to playlist").
+		SetColor(session.theme.ActionNodeColor).
+		SetReference(&NodeMetadata{nodeType: ActionNode, titles: sessionTitles})
+	playlistNode.SetSelectedFunc(func() {
+		go func() {
+			err := session.addToPlaylist(epID, sessionTitles)
+			if err != nil {
+				session.logError("could not add to playlist: ", err)
+				return
+			}
+			session.logInfo("added ", sessionTitles.String(), " to the playlist")
+		}()
+	})
+	nodes = append(nodes, playlistNode)
+
+	if session.cfg.DebugActions {
+		printNode := tview.NewTreeNode("Print URL").
+			SetColor(session.theme.ActionNodeColor).
+			SetReference(&NodeMetadata{nodeType: ActionNode, titles: sessionTitles})
+		printNode.SetSelectedFunc(func() {
+			go func() {
+				url, err := session.playableURL(epID)
+				if err != nil {
+					session.logError(err)
+					return
+				}
+				session.logInfo(epID, " ", url)
+			}()
+		})
+		nodes = append(nodes, printNode)
 	}
 
 	streamNode := tview.NewTreeNode("Copy URL to clipboard").
-		SetColor(activeTheme.ActionNodeColor).
+		SetColor(session.theme.ActionNodeColor).
 		SetReference(&NodeMetadata{nodeType: ActionNode, titles: sessionTitles})
 	streamNode.SetSelectedFunc(func() {
-		url, err := getPlayableURL(epID, session.authtoken)
+		url, err := session.playableURL(epID)
 		if err != nil {
 			session.logError(err)
 			return
@@ -160,15 +450,17 @@ func (session *viewerSession) getPlaybackNodes(sessionTitles Titles, epID string
 }
 
 func (session *viewerSession) createCommandNode(t Titles, epID string, c command) *tview.TreeNode {
+	node := tview.NewTreeNode(c.Title).
+		SetColor(session.theme.ActionNodeColor).
+		SetReference(&NodeMetadata{nodeType: ActionNode, titles: t})
 	context := commandContext{
 		Titles:        t,
 		EpID:          epID,
 		CustomOptions: c,
+		Node:          node,
 	}
-	node := tview.NewTreeNode(c.Title).
-		SetColor(activeTheme.ActionNodeColor).
-		SetReference(&NodeMetadata{nodeType: ActionNode, titles: t})
 	node.SetSelectedFunc(func() {
+		session.setPlaying(node, c)
 		go func() {
 			err := session.runCustomCommand(context)
 			if err != nil {
@@ -183,31 +475,35 @@ func (session *viewerSession) createCommandNode(t Titles, epID string, c command
 func (session *viewerSession) getLiveNode() (bool, *tview.TreeNode, error) {
 	var sessionNode *tview.TreeNode
 
-	event, eventFound, err := getLiveWeekendEvent()
+	event, eventFound, err := session.getLiveWeekendEvent()
 	if err != nil || !eventFound {
 		return false, sessionNode, err
 	}
 
 	var t Titles
-	t.EventTitle = event.Name
+	t.EventTitle = event.label()
 	for _, sessionID := range event.SessionoccurrenceUrls {
-		s, err := getSession(sessionID)
+		s, err := session.getSession(sessionID)
 		if err != nil {
 			return false, sessionNode, err
 		}
 		st := t
 		st.SessionTitle = s.Name
-		if s.Status == "live" {
-			streams, err := getSessionStreams(s.UID)
+		if s.Status == "live" && !(session.cfg.LiveReplay == "replay" && s.replayAvailable(time.Now())) {
+			st.Live = true
+			streams, err := session.getSessionStreams(s.UID)
 			if err != nil {
 				return false, sessionNode, err
 			}
 			sessionNode = tview.NewTreeNode(s.SessionName + " - LIVE").
-				SetColor(activeTheme.LiveColor).
+				SetColor(session.theme.LiveColor).
 				SetExpanded(false).
 				SetReference(&NodeMetadata{nodeType: PlayableNode, id: event.UID, titles: t})
 			channels := session.getPerspectiveNodes(st, streams)
 			appendNodes(sessionNode, channels...)
+			if len(channels) == 0 {
+				sessionNode.AddChild(session.nocontentNode())
+			}
 			return true, sessionNode, nil
 		}
 	}
@@ -226,7 +522,11 @@ func (session *viewerSession) getEventNodes(season seasonStruct) ([]*tview.TreeN
 				events[m] = node
 			}
 
-			if err == errNoSessions {
+			switch err {
+			case errNoSessions:
+				err = nil
+			case errNoEventLabel:
+				session.logDebug("skipping event ", eventID, ": ", err)
 				err = nil
 			}
 
@@ -243,7 +543,7 @@ func (session *viewerSession) getEventNodes(season seasonStruct) ([]*tview.TreeN
 }
 
 func (session *viewerSession) getEventNode(eventID string, seasonName string) (*tview.TreeNode, error) {
-	event, err := getEvent(eventID)
+	event, err := session.getEvent(eventID)
 	if err != nil {
 		return nil, err
 	}
@@ -251,60 +551,45 @@ func (session *viewerSession) getEventNode(eventID string, seasonName string) (*
 	if len(event.SessionoccurrenceUrls) == 0 {
 		return nil, errNoSessions
 	}
+	if event.label() == "" {
+		return nil, errNoEventLabel
+	}
+	return session.newEventNode(eventID, event, seasonName), nil
+}
 
+func (session *viewerSession) newEventNode(eventID string, event eventStruct, seasonName string) *tview.TreeNode {
 	titles := Titles{SeasonTitle: seasonName, CategoryTitle: "Full Seasons"}
 
-	eventNode := tview.NewTreeNode(event.Name).
+	label := event.label()
+	if session.cfg.ShowEventCountry && event.Nation.CountryCode != "" {
+		label += " (" + event.Nation.CountryCode + ")"
+	}
+	eventNode := tview.NewTreeNode(label).
 		SetSelectable(true).
-		SetReference(&NodeMetadata{nodeType: EventNode, id: eventID, titles: titles})
-	eventNode.SetSelectedFunc(session.withBlink(eventNode, func() {
-		eventNode.SetSelectedFunc(nil)
-		sessions, err := session.getSessionNodes(titles, event)
-		if err != nil {
-			session.logError(err)
-		} else {
-			appendNodes(eventNode, sessions...)
-		}
-		if len(eventNode.GetChildren()) == 0 {
-			eventNode.AddChild(nocontentNode())
-		}
-	}, nil))
-	return eventNode, nil
-
+		SetReference(&NodeMetadata{nodeType: EventNode, id: eventID, titles: titles, metadata: event})
+	session.lazyLoadThen(eventNode, func() ([]*tview.TreeNode, error) {
+		return session.getSessionNodes(titles, event)
+	}, func(children []*tview.TreeNode) {
+		session.filterEventSessions(eventNode, eventNode.GetChildren())
+	})
+	return eventNode
 }
 
 func (session *viewerSession) getSessionNodes(t Titles, event eventStruct) ([]*tview.TreeNode, error) {
 	sessions := make([]*tview.TreeNode, 0)
 	bonusIDs := make([]string, 0)
-	sessionsData, err := getSessions(event.SessionoccurrenceUrls)
+	sessionsData, err := session.getSessions(event.SessionoccurrenceUrls)
 	if err != nil {
 		return nil, err
 	}
-	t.EventTitle = event.Name
+	t.EventTitle = event.label()
 	for _, s := range sessionsData {
 		st := t
 		st.SessionTitle = s.Name
+		st.Live = s.Status == "live"
 		bonusIDs = append(bonusIDs, s.ContentUrls...)
 		if s.Status != "upcoming" && s.Status != "expired" {
-			s := s
-			sessionNode := tview.NewTreeNode(s.Name).
-				SetSelectable(true).
-				SetReference(&NodeMetadata{nodeType: PlayableNode, id: s.UID, titles: t})
-			sessionNode.SetSelectedFunc(session.withBlink(sessionNode, func() {
-				sessionNode.SetSelectedFunc(nil)
-				streams, err := getSessionStreams(s.UID)
-				if err != nil {
-					session.logError(err)
-					return
-				}
-				channels := session.getPerspectiveNodes(st, streams)
-				appendNodes(sessionNode, channels...)
-			}, nil))
-			if s.Status == "live" {
-				sessionNode.SetText(s.Name + " - LIVE").
-					SetColor(activeTheme.LiveColor)
-			}
-			sessions = append(sessions, sessionNode)
+			sessions = append(sessions, session.newSessionNode(st, s))
 		}
 	}
 	if len(bonusIDs) > 0 {
@@ -320,6 +605,59 @@ func (session *viewerSession) getSessionNodes(t Titles, event eventStruct) ([]*t
 	return sessions, nil
 }
 
+// newSessionNode creates the node for a session that can be played.
+// Sessions that are live but already have a replay are handled according to live_replay.
+func (session *viewerSession) newSessionNode(t Titles, s sessionStruct) *tview.TreeNode {
+	sessionNode := tview.NewTreeNode(s.Name).
+		SetSelectable(true).
+		SetReference(&NodeMetadata{nodeType: PlayableNode, id: s.UID, titles: t, metadata: s})
+	live := s.Status == "live"
+	if live && s.replayAvailable(time.Now()) {
+		switch session.cfg.LiveReplay {
+		case "replay":
+			live = false
+		case "both":
+			sessionNode.SetText(s.Name + " - LIVE / Replay").
+				SetColor(session.theme.LiveColor).
+				SetExpanded(false)
+			for _, asLive := range []bool{true, false} {
+				st := t
+				st.Live = asLive
+				node := tview.NewTreeNode("Replay").
+					SetSelectable(true).
+					SetReference(&NodeMetadata{nodeType: PlayableNode, id: s.UID, titles: st, metadata: s})
+				if asLive {
+					node.SetText("LIVE").SetColor(session.theme.LiveColor)
+				}
+				session.lazyLoadThen(node, session.perspectiveLoader(st, s.UID), session.openMainFeed)
+				sessionNode.AddChild(node)
+			}
+			return sessionNode
+		}
+	}
+	t.Live = live
+	session.lazyLoadThen(sessionNode, session.perspectiveLoader(t, s.UID), session.openMainFeed)
+	switch {
+	case live:
+		sessionNode.SetText(s.Name + " - LIVE").
+			SetColor(session.theme.LiveColor)
+	case s.Status == "live":
+		sessionNode.SetText(s.Name + " - Replay")
+	}
+	return sessionNode
+}
+
+// perspectiveLoader loads the perspectives of a session, the streams of live sessions aren't cached
+func (session *viewerSession) perspectiveLoader(t Titles, sessionID string) func() ([]*tview.TreeNode, error) {
+	return func() ([]*tview.TreeNode, error) {
+		streams, err := session.loadSessionStreams(sessionID, t.Live)
+		if err != nil {
+			return nil, err
+		}
+		return session.getPerspectiveNodes(t, streams), nil
+	}
+}
+
 func (session *viewerSession) getMultiCommandNodes(perspectives []channel) []*tview.TreeNode {
 	if len(session.cfg.MultiCommand) == 0 {
 		return nil
@@ -336,7 +674,7 @@ func (session *viewerSession) getMultiCommandNodes(perspectives []channel) []*tv
 				continue
 			}
 
-			perspective, err := findPerspectiveByName(target.MatchTitle, perspectives)
+			perspective, err := findPerspectiveByName(target.MatchTitle, perspectives, session.cfg.PerspectiveLabels)
 			if err != nil {
 				continue
 			}
@@ -346,6 +684,10 @@ func (session *viewerSession) getMultiCommandNodes(perspectives []channel) []*tv
 				Titles:        Titles{PerspectiveTitle: multi.Title},
 				EpID:          perspective.Self,
 				CustomOptions: cmd,
+				Quality: session.quality(Titles{
+					PerspectiveTitle: perspective.PrettyName(session.cfg.PerspectiveLabels),
+					Onboard:          perspective.racingNumber() > 0,
+				}),
 			}
 			commands = append(commands, context)
 		}
@@ -355,11 +697,18 @@ func (session *viewerSession) getMultiCommandNodes(perspectives []channel) []*tv
 		}
 
 		multiNode := tview.NewTreeNode(multi.Title).
-			SetColor(activeTheme.MultiCommandColor).
+			SetColor(session.theme.MultiCommandColor).
 			SetReference(&NodeMetadata{nodeType: ActionNode})
 		multiNode.SetSelectedFunc(session.withBlink(multiNode, func() {
 			multiNode.SetSelectedFunc(nil)
-			for _, context := range commands {
+			b := session.startBatch()
+			defer session.endBatch(b)
+			for i, context := range commands {
+				if b.cancelled(session, i, len(commands)) {
+					return
+				}
+				b.addProcess(playerKey(context))
+				context.Node = multiNode
 				err := session.runCustomCommand(context)
 				if err != nil {
 					session.logError(err)
@@ -372,9 +721,9 @@ func (session *viewerSession) getMultiCommandNodes(perspectives []channel) []*tv
 	return nodes
 }
 
-func findPerspectiveByName(name string, perspectives []channel) (channel, error) {
+func findPerspectiveByName(name string, perspectives []channel, labels map[string]string) (channel, error) {
 	for _, perspective := range perspectives {
-		if perspective.PrettyName() == name {
+		if perspective.PrettyName(labels) == name {
 			return perspective, nil
 		}
 		// if the string doesn't match try regex
@@ -382,7 +731,7 @@ func findPerspectiveByName(name string, perspectives []channel) (channel, error)
 		if err != nil {
 			continue
 		}
-		if r.MatchString(perspective.PrettyName()) {
+		if r.MatchString(perspective.PrettyName(labels)) {
 			return perspective, nil
 		}
 	}
@@ -413,32 +762,75 @@ func (session *viewerSession) getPerspectiveNodes(title Titles, perspectives []c
 	multiCommands := session.getMultiCommandNodes(perspectives)
 	channels = append(channels, multiCommands...)
 
+	perspectives = sortPerspectives(perspectives, session.cfg.PerspectiveOrder, session.cfg.PerspectiveLabels)
+
+	var numbers []int
+	for _, p := range perspectives {
+		numbers = append(numbers, p.racingNumber())
+	}
+	width := racingNumberWidth(numbers)
+
+	// with primary_perspectives the other perspectives are grouped in a node below the primary ones
+	var more []*tview.TreeNode
 	for _, streamPerspective := range perspectives {
 		streamPerspective := streamPerspective
-		name := streamPerspective.PrettyName()
+		name := streamPerspective.PrettyName(session.cfg.PerspectiveLabels)
 
 		newTitle := title
 		newTitle.PerspectiveTitle = name
+		newTitle.Onboard = streamPerspective.racingNumber() > 0
 
-		streamNode := tview.NewTreeNode(name).
-			SetColor(activeTheme.ItemNodeColor).
-			SetReference(&NodeMetadata{nodeType: StreamNode, id: streamPerspective.Self, titles: newTitle})
+		label := name
+		if number := streamPerspective.racingNumber(); number > 0 {
+			label = session.driverLabel(number, width, name)
+		}
 
-		streamNode.SetSelectedFunc(func() {
-			streamNode.SetSelectedFunc(nil)
-			nodes := session.getPlaybackNodes(newTitle, streamPerspective.Self)
-			appendNodes(streamNode, nodes...)
-		})
-		channels = append(channels, streamNode)
+		node := session.newPerspectiveNode(newTitle, streamPerspective, label)
+		if session.isPrimaryPerspective(streamPerspective) {
+			channels = append(channels, node)
+		} else {
+			more = append(more, node)
+		}
+		if driver := streamPerspective.driverURL(); driver != "" {
+			session.indexDriverContent([]string{driver}, streamPerspective.Self, newTitle.String(), func() *tview.TreeNode {
+				return session.newPerspectiveNode(newTitle, streamPerspective, newTitle.String())
+			})
+		}
+	}
+	if len(channels) == len(multiCommands) {
+		// nothing is primary, so nothing is hidden
+		channels, more = append(channels, more...), nil
+	}
+	if len(more) > 0 {
+		moreNode := tview.NewTreeNode(fmt.Sprintf("Show all onboards… (%d)", len(more))).
+			SetColor(session.theme.FolderNodeColor).
+			SetExpanded(false).
+			SetReference(&NodeMetadata{nodeType: MiscNode, titles: title})
+		moreNode.SetChildren(more)
+		channels = append(channels, moreNode)
 	}
 	if teamsContasiner != nil {
 		channels = append(channels, teamsContasiner)
 	}
+	if len(perspectives) <= session.cfg.ExpandPerspectives {
+		// sessions with few perspectives show their options right away
+		for _, node := range channels {
+			if ref, ok := node.GetReference().(*NodeMetadata); ok && ref.nodeType == StreamNode {
+				session.loadPlaybackNodes(node, ref.titles, ref.id)
+			}
+		}
+	}
 	return channels
 }
 
+// isPrimaryPerspective checks if a perspective is always shown, all perspectives are if primary_perspectives isn't set
+func (session *viewerSession) isPrimaryPerspective(p channel) bool {
+	primary := session.cfg.PrimaryPerspectives
+	return len(primary) == 0 || containsFold(primary, p.Name) || containsFold(primary, p.PrettyName(session.cfg.PerspectiveLabels))
+}
+
 func (session *viewerSession) getSeasonNodes() ([]*tview.TreeNode, error) {
-	seasons, err := getSeasons()
+	seasons, err := session.getSeasons()
 	if err != nil {
 		return nil, err
 	}
@@ -446,30 +838,110 @@ func (session *viewerSession) getSeasonNodes() ([]*tview.TreeNode, error) {
 	for _, s := range seasons.Seasons {
 		if s.HasContent {
 			s := s
-			seasonNode := tview.NewTreeNode(s.Name).SetReference(&NodeMetadata{nodeType: CategoryNode, id: s.UID})
-			seasonNode.SetSelectedFunc(session.withBlink(seasonNode, func() {
-				seasonNode.SetSelectedFunc(nil)
-				events, err := session.getEventNodes(s)
-				if err != nil {
-					session.logError(err)
-				}
-				appendNodes(seasonNode, events...)
-			}, nil))
+			seasonNode := tview.NewTreeNode(s.Name).SetReference(&NodeMetadata{nodeType: CategoryNode, id: s.UID, metadata: s})
+			session.lazyLoadCounted(seasonNode, len(s.EventoccurrenceUrls), func() ([]*tview.TreeNode, error) {
+				return session.getEventNodes(s)
+			})
 			nodes = append(nodes, seasonNode)
 		}
 	}
+	sortSeasonNodes(nodes, session.cfg.SeasonOrder == "descending")
 	return nodes, nil
 }
 
+// seasonYear returns the year of a season, from the name if the API didn't return it
+func seasonYear(s seasonStruct) int {
+	if s.Year > 0 {
+		return s.Year
+	}
+	year, _ := strconv.Atoi(yearRegex.FindString(s.Name))
+	return year
+}
+
+var yearRegex = regexp.MustCompile(`\b\d{4}\b`)
+
+// sortSeasonNodes sorts season nodes by year, other nodes keep their position at the end
+func sortSeasonNodes(nodes []*tview.TreeNode, descending bool) {
+	year := func(node *tview.TreeNode) (int, bool) {
+		ref, ok := node.GetReference().(*NodeMetadata)
+		if !ok {
+			return 0, false
+		}
+		s, ok := ref.metadata.(seasonStruct)
+		return seasonYear(s), ok
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, okA := year(nodes[i])
+		b, okB := year(nodes[j])
+		if !okA || !okB {
+			return okA && !okB
+		}
+		if descending {
+			return a > b
+		}
+		return a < b
+	})
+}
+
+// toggleSeasonOrder switches between the oldest and the newest season first for all loaded seasons
+func (session *viewerSession) toggleSeasonOrder() {
+	descending := session.cfg.SeasonOrder != "descending"
+	session.cfg.SeasonOrder = "ascending"
+	if descending {
+		session.cfg.SeasonOrder = "descending"
+	}
+	session.tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		children := node.GetChildren()
+		for _, child := range children {
+			if ref, ok := child.GetReference().(*NodeMetadata); ok {
+				if _, ok := ref.metadata.(seasonStruct); ok {
+					sortSeasonNodes(children, descending)
+					node.SetChildren(children)
+					return false
+				}
+			}
+		}
+		return true
+	})
+	if descending {
+		session.logInfo("showing the newest seasons first")
+	} else {
+		session.logInfo("showing the oldest seasons first")
+	}
+}
+
+// newEpisodeNode returns a node that shows the playback options for the episode's first item when it's selected
+func (session *viewerSession) newEpisodeNode(title Titles, ep episode) *tview.TreeNode {
+	node := tview.NewTreeNode(session.episodeLabel(ep)).
+		SetColor(session.theme.ItemNodeColor).
+		SetReference(&NodeMetadata{nodeType: PlayableNode, id: ep.UID, titles: title, metadata: ep})
+	node.SetSelectedFunc(func() {
+		node.SetSelectedFunc(nil)
+		nodes := session.getPlaybackNodes(title, ep.Items[0])
+		appendNodes(node, nodes...)
+		session.styleNodes(node)
+	})
+	return node
+}
+
 func (session *viewerSession) getEpisodeNodes(title Titles, IDs []string) ([]*tview.TreeNode, error) {
-	var nodes []*tview.TreeNode
-	var yearNodes []*tview.TreeNode
-	yearNodesMap := make(map[string]*tview.TreeNode)
 	eps, err := session.loadEpisodes(IDs)
 	if err != nil {
 		return nil, err
 	}
-	episodes := sortEpisodes(eps)
+	nodes := session.episodeNodesByYear(title, sortEpisodes(eps))
+	if session.cfg.FlattenFolders {
+		nodes = flattenFolders(nodes)
+	}
+	return nodes, nil
+}
+
+// episodeNodesByYear returns a node for each year the episodes are from, followed by the episodes without a year.
+// The year nodes are looked up in a map, so large categories don't have to scan them for every episode.
+func (session *viewerSession) episodeNodesByYear(title Titles, episodes []episode) []*tview.TreeNode {
+	var nodes []*tview.TreeNode
+	var yearNodes []*tview.TreeNode
+	yearNodesMap := make(map[string]*tview.TreeNode)
 	for _, ep := range episodes {
 		ep := ep
 		if len(ep.Items) < 1 {
@@ -477,13 +949,9 @@ func (session *viewerSession) getEpisodeNodes(title Titles, IDs []string) ([]*tv
 		}
 		tempTitle := title
 		tempTitle.EpisodeTitle = ep.Title
-		node := tview.NewTreeNode(ep.Title).
-			SetColor(activeTheme.ItemNodeColor).
-			SetReference(&NodeMetadata{nodeType: PlayableNode, id: ep.UID, titles: tempTitle})
-		node.SetSelectedFunc(func() {
-			node.SetSelectedFunc(nil)
-			nodes := session.getPlaybackNodes(tempTitle, ep.Items[0])
-			appendNodes(node, nodes...)
+		node := session.newEpisodeNode(tempTitle, ep)
+		session.indexDriverContent(ep.DriverUrls, ep.UID, session.episodeLabel(ep), func() *tview.TreeNode {
+			return session.newEpisodeNode(tempTitle, ep)
 		})
 		if year, _, err := getYearAndRace(ep.DataSourceID); err == nil {
 			yearNode, ok := yearNodesMap[year]
@@ -499,12 +967,252 @@ func (session *viewerSession) getEpisodeNodes(title Titles, IDs []string) ([]*tv
 			nodes = append(nodes, node)
 		}
 	}
-	return append(yearNodes, nodes...), nil
+	return append(yearNodes, nodes...)
+}
+
+// flattenFolders replaces folders that contain a single node with that node, the labels are merged.
+// Only folders that are already loaded are flattened.
+func flattenFolders(nodes []*tview.TreeNode) []*tview.TreeNode {
+	flat := make([]*tview.TreeNode, len(nodes))
+	for i, node := range nodes {
+		for isFolder(node) && len(node.GetChildren()) == 1 {
+			child := node.GetChildren()[0]
+			child.SetText(node.GetText() + " - " + child.GetText())
+			node = child
+		}
+		if isFolder(node) {
+			node.SetChildren(flattenFolders(node.GetChildren()))
+		}
+		flat[i] = node
+	}
+	return flat
+}
+
+func isFolder(node *tview.TreeNode) bool {
+	ref, ok := node.GetReference().(*NodeMetadata)
+	return ok && ref.nodeType == MiscNode && len(node.GetChildren()) > 0
+}
+
+// episodeLabel returns the text of an episode's node based on the configured template
+func (session *viewerSession) episodeLabel(ep episode) string {
+	if session.cfg.EpisodeLabel == "" {
+		return ep.Title
+	}
+	label, unknown := fillEpisodeTemplate(session.cfg.EpisodeLabel, ep)
+	if len(unknown) > 0 {
+		session.labelWarning.Do(func() {
+			session.logWarn("unknown placeholders in episode_label: ", strings.Join(unknown, ", "))
+		})
+	}
+	if strings.TrimSpace(label) == "" {
+		return ep.Title
+	}
+	return label
+}
+
+var placeholderRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// fillEpisodeTemplate replaces placeholders like {Title} with the episode's fields.
+// Unknown placeholders are left as they are and returned.
+func fillEpisodeTemplate(template string, ep episode) (string, []string) {
+	return fillTemplate(template, map[string]string{
+		"Title":        ep.Title,
+		"Subtitle":     ep.Subtitle,
+		"UID":          ep.UID,
+		"DataSourceID": ep.DataSourceID,
+	})
+}
+
+// driverLabel returns the label of an onboard perspective, formatted with driver_label.
+// The racing number is right-justified to width digits, so the names of all drivers line up.
+func (session *viewerSession) driverLabel(number, width int, name string) string {
+	template := session.cfg.DriverLabel
+	if strings.TrimSpace(template) == "" {
+		template = defaultDriverLabel
+	}
+	label, unknown := fillDriverTemplate(template, formatRacingNumber(number, width), name)
+	if len(unknown) > 0 {
+		session.driverLabelWarning.Do(func() {
+			session.logWarn("unknown placeholders in driver_label: ", strings.Join(unknown, ", "))
+		})
+	}
+	return label
+}
+
+func fillDriverTemplate(template, number, name string) (string, []string) {
+	return fillTemplate(template, map[string]string{
+		"Number": number,
+		"Name":   name,
+	})
+}
+
+// formatRacingNumber right-justifies the number to width digits
+func formatRacingNumber(number, width int) string {
+	return fmt.Sprintf("%*d", width, number)
+}
+
+// newPerspectiveNode returns a node that shows the playback options for the perspective when it's selected
+func (session *viewerSession) newPerspectiveNode(title Titles, perspective channel, label string) *tview.TreeNode {
+	streamNode := tview.NewTreeNode(label).
+		SetColor(session.perspectiveColor(perspective)).
+		SetReference(&NodeMetadata{nodeType: StreamNode, id: perspective.Self, titles: title, metadata: perspective})
+
+	streamNode.SetSelectedFunc(func() {
+		session.loadPlaybackNodes(streamNode, title, perspective.Self)
+	})
+	return streamNode
+}
+
+// loadPlaybackNodes adds the player and download options to a perspective node
+func (session *viewerSession) loadPlaybackNodes(node *tview.TreeNode, title Titles, id string) {
+	node.SetSelectedFunc(nil)
+	appendNodes(node, session.getPlaybackNodes(title, id)...)
+	session.styleNodes(node)
+}
+
+// perspectiveColor returns the team color for onboard perspectives and the item color for everything else
+func (session *viewerSession) perspectiveColor(c channel) tcell.Color {
+	hex := strings.TrimPrefix(c.teamColour(), "#")
+	if len(hex) != 6 {
+		return session.theme.ItemNodeColor
+	}
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		return session.theme.ItemNodeColor
+	}
+	color := hexStringToColor(hex)
+	if n := paletteSize(session.cfg.ColorMode); n > 0 {
+		color = tcell.FindColor(color, palette(n))
+	}
+	return color
+}
+
+// racingNumberWidth returns the number of digits of the largest number
+func racingNumberWidth(numbers []int) int {
+	width := 1
+	for _, n := range numbers {
+		if w := len(strconv.Itoa(n)); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// fillTemplate replaces placeholders like {Title} with the values of the fields.
+// Unknown placeholders are left as they are and returned.
+func fillTemplate(template string, fields map[string]string) (string, []string) {
+	var unknown []string
+	label := placeholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := fields[name]
+		if !ok {
+			unknown = append(unknown, name)
+			return placeholder
+		}
+		return value
+	})
+	return label, unknown
+}
+
+// addVodTypeNodes adds a category for every VOD type to the root node.
+// If they can't be loaded an error node is added instead, that loads them again when it's selected or refreshed.
+func (session *viewerSession) addVodTypeNodes() {
+	root := session.tree.GetRoot()
+	nodes, err := session.getVodTypeNodes()
+	if err == nil {
+		appendNodes(root, nodes...)
+		for _, node := range nodes {
+			session.styleCategory(node)
+		}
+		return
+	}
+	session.logError("could not load categories: ", err)
+	errorNode := tview.NewTreeNode("Could not load categories, select to retry").
+		SetColor(session.theme.ErrorColor).
+		SetReference(&NodeMetadata{nodeType: ActionNode})
+	errorNode.SetSelectedFunc(func() {
+		go session.retryVodTypes(errorNode)
+	})
+	session.vodTypesErrorNode = errorNode
+	root.AddChild(errorNode)
+}
+
+// retryVodTypes loads the VOD types again and replaces the error node with them
+func (session *viewerSession) retryVodTypes(errorNode *tview.TreeNode) {
+	metadata, err := getMetadata(errorNode)
+	if err != nil {
+		session.logError(err)
+		return
+	}
+	metadata.Lock()
+	if session.vodTypesErrorNode != errorNode {
+		// another retry already succeeded
+		metadata.Unlock()
+		return
+	}
+	var nodes []*tview.TreeNode
+	session.withBlink(errorNode, func() {
+		nodes, err = session.getVodTypeNodes()
+	}, func() {
+		defer metadata.Unlock()
+		if err != nil {
+			session.logError("could not load categories: ", err)
+			return
+		}
+		root := session.tree.GetRoot()
+		replaceNode(root, errorNode, nodes...)
+		for _, node := range nodes {
+			session.styleCategory(node)
+		}
+		root.SetChildren(orderNodes(root.GetChildren(), session.cfg.CategoryOrder, session.cfg.HiddenCategories))
+		session.vodTypesErrorNode = nil
+		if session.tree.GetCurrentNode() == errorNode && len(nodes) > 0 {
+			session.tree.SetCurrentNode(nodes[0])
+		}
+		session.expandCategories(nodes)
+		session.logInfo("loaded categories")
+		session.draw()
+	})()
+}
+
+// loadVodTypes returns the VOD types and caches them.
+// If they can't be loaded from the API the cached ones are returned instead.
+func (session *viewerSession) loadVodTypes() (vodTypes, error) {
+	path, pathErr := getVodTypesPath()
+	types, err := session.getVodTypes()
+	if err == nil {
+		if pathErr == nil {
+			pathErr = session.writeCache(path, types)
+		}
+		if pathErr != nil {
+			session.logWarn("could not cache categories: ", pathErr)
+		}
+		return types, nil
+	}
+	if pathErr != nil {
+		return types, err
+	}
+	var cached vodTypes
+	if cacheErr := session.readCache(path, &cached); cacheErr != nil {
+		if !os.IsNotExist(cacheErr) {
+			session.logWarn(cacheErr)
+		}
+		return types, err
+	}
+	session.logWarn("could not load categories, showing saved ones: ", err)
+	return cached, nil
+}
+
+func getVodTypesPath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return path + "vod_types.json", nil
 }
 
 func (session *viewerSession) getVodTypeNodes() ([]*tview.TreeNode, error) {
 	var nodes []*tview.TreeNode
-	vodTypes, err := getVodTypes()
+	vodTypes, err := session.loadVodTypes()
 	if err != nil {
 		return nil, err
 	}
@@ -513,17 +1221,11 @@ func (session *viewerSession) getVodTypeNodes() ([]*tview.TreeNode, error) {
 		if len(vType.ContentUrls) > 0 {
 			titles := Titles{CategoryTitle: vType.Name}
 			node := tview.NewTreeNode(vType.Name).
-				SetColor(activeTheme.CategoryNodeColor).
+				SetColor(session.theme.CategoryNodeColor).
 				SetReference(&NodeMetadata{nodeType: CategoryNode, id: vType.UID, titles: titles})
-			node.SetSelectedFunc(session.withBlink(node, func() {
-				node.SetSelectedFunc(nil)
-				episodes, err := session.getEpisodeNodes(titles, vType.ContentUrls)
-				if err != nil {
-					session.logError(err)
-				} else {
-					appendNodes(node, episodes...)
-				}
-			}, nil))
+			session.lazyLoadCounted(node, episodeRequests(len(vType.ContentUrls)), func() ([]*tview.TreeNode, error) {
+				return session.getEpisodeNodes(titles, vType.ContentUrls)
+			})
 			nodes = append(nodes, node)
 		}
 	}
@@ -532,37 +1234,29 @@ func (session *viewerSession) getVodTypeNodes() ([]*tview.TreeNode, error) {
 
 func (session *viewerSession) getCollectionsNode() *tview.TreeNode {
 	node := tview.NewTreeNode("Collections").
-		SetColor(activeTheme.CategoryNodeColor).
+		SetColor(session.theme.CategoryNodeColor).
 		SetReference(&NodeMetadata{nodeType: CategoryNode})
-	node.SetSelectedFunc(session.withBlink(node, func() {
-		node.SetSelectedFunc(nil)
-		list, err := getCollectionList()
+	session.lazyLoad(node, func() ([]*tview.TreeNode, error) {
+		list, err := session.getCollectionList()
 		if err != nil {
-			session.logError("could not load collections: ", err)
+			return nil, fmt.Errorf("could not load collections: %w", err)
 		}
+		var children []*tview.TreeNode
 		for _, coll := range list.Objects {
 			collID := coll.UID
 			child := tview.NewTreeNode(coll.Title).SetReference(&NodeMetadata{nodeType: MiscNode, id: collID})
-			child.SetSelectedFunc(session.withBlink(child, func() {
-				child.SetSelectedFunc(nil)
-				var nodes []*tview.TreeNode
-				nodes, err = session.getCollectionContent(collID)
-				if err != nil {
-					session.logError(err)
-				} else if len(nodes) > 0 {
-					appendNodes(child, nodes...)
-				} else {
-					child.AddChild(nocontentNode())
-				}
-			}, nil))
-			node.AddChild(child)
+			session.lazyLoad(child, func() ([]*tview.TreeNode, error) {
+				return session.getCollectionContent(collID)
+			})
+			children = append(children, child)
 		}
-	}, nil))
+		return children, nil
+	})
 	return node
 }
 
 func (session *viewerSession) getCollectionContent(id string) ([]*tview.TreeNode, error) {
-	coll, err := getCollection(id)
+	coll, err := session.getCollection(id)
 	if err != nil {
 		return nil, err
 	}
@@ -573,12 +1267,278 @@ func (session *viewerSession) getCollectionContent(id string) ([]*tview.TreeNode
 	return session.getEpisodeNodes(Titles{CategoryTitle: coll.Title}, epIDs)
 }
 
-func nocontentNode() *tview.TreeNode {
+// lazyLoad loads the node's children the first time it is selected.
+// If the API rate limits the request the node is marked and loading is retried once the server allows it.
+func (session *viewerSession) lazyLoad(node *tview.TreeNode, load func() ([]*tview.TreeNode, error)) {
+	session.lazyLoadThen(node, load, nil)
+}
+
+// lazyLoadCounted works like lazyLoad, but asks first if loading needs more requests than confirm_requests
+func (session *viewerSession) lazyLoadCounted(node *tview.TreeNode, requests int, load func() ([]*tview.TreeNode, error)) {
+	loader := session.lazyLoadThen(node, load, nil)
+	if session.cfg.ConfirmRequests <= 0 || requests <= session.cfg.ConfirmRequests {
+		return
+	}
+	node.SetSelectedFunc(func() {
+		session.confirmRequests(nodeLabel(node), requests, loader)
+	})
+}
+
+// confirmRequests asks if something that needs many requests should be loaded.
+// "Always load" skips the question until f1viewer is restarted.
+func (session *viewerSession) confirmRequests(name string, requests int, load func()) {
+	session.confirmLock.Lock()
+	skip := session.skipConfirm
+	session.confirmLock.Unlock()
+	if skip {
+		load()
+		return
+	}
+	text := fmt.Sprintf("Loading %s needs about %d requests. Load it anyway?", name, requests)
+	session.showModal(text, []string{"Load", "Always load", "Cancel"}, func(label string) {
+		session.app.SetFocus(session.tree)
+		switch label {
+		case "Always load":
+			session.confirmLock.Lock()
+			session.skipConfirm = true
+			session.confirmLock.Unlock()
+			load()
+		case "Load":
+			load()
+		}
+	})
+}
+
+// markGeoBlocked labels and colors a node whose content isn't available in the user's region
+func (session *viewerSession) markGeoBlocked(node *tview.TreeNode) {
+	if node == nil {
+		return
+	}
+	if label := nodeLabel(node); !strings.HasSuffix(label, geoBlockedLabel) {
+		setLabel(node, label+geoBlockedLabel)
+	}
+	node.SetColor(session.theme.GeoBlockColor)
+}
+
+// lazyLoadThen works like lazyLoad, then is called with the loaded children once they are shown.
+// It returns the function that loads the children, it is also the node's selected function until they are loaded.
+func (session *viewerSession) lazyLoadThen(node *tview.TreeNode, load func() ([]*tview.TreeNode, error), then func(children []*tview.TreeNode)) func() {
+	var rateErr rateLimitError
+	var elapsed time.Duration
+	var loaded, cancelled, geoBlocked bool
+	var children []*tview.TreeNode
+	var loader func()
+	loader = session.withBlink(node, func() {
+		node.SetSelectedFunc(nil)
+		rateErr = rateLimitError{}
+		loaded = false
+		cancelled = false
+		start := time.Now()
+		var err error
+		children, err = session.cancellableLoad(load)
+		elapsed = time.Since(start)
+		if errors.As(err, &rateErr) {
+			return
+		} else if errors.As(err, &geoBlockError{}) {
+			session.logError(nodeLabel(node), ": ", err)
+			geoBlocked = true
+			return
+		} else if errors.Is(err, errLoadCancelled) {
+			cancelled = true
+			return
+		} else if err != nil {
+			session.logError(err)
+			return
+		}
+		loaded = true
+		// the tree is drawn on the UI goroutine, the children are added there so it doesn't change while it's drawn
+		session.app.QueueUpdate(func() {
+			appendNodes(node, children...)
+			if len(node.GetChildren()) == 0 {
+				node.AddChild(session.nocontentNode())
+			}
+			session.styleNodes(node)
+		})
+	}, func() {
+		if cancelled {
+			session.logInfo("cancelled loading ", nodeLabel(node))
+			node.SetSelectedFunc(loader)
+			return
+		}
+		if geoBlocked {
+			session.markGeoBlocked(node)
+			session.draw()
+			return
+		}
+		session.logDebug(fmt.Sprintf("loaded %s in %s", nodeLabel(node), elapsed.Round(time.Millisecond)))
+		if loaded && then != nil {
+			then(children)
+		}
+		if rateErr.retryAfter == 0 {
+			return
+		}
+		label, color := nodeLabel(node), node.GetColor()
+		session.logWarn(fmt.Sprintf("rate limited while loading %s, retrying in %s", label, rateErr.retryAfter.Round(time.Second)))
+		setLabel(node, fmt.Sprintf("%s (rate limited, retrying in %s)", label, rateErr.retryAfter.Round(time.Second)))
+		node.SetColor(session.theme.RateLimitColor)
+		session.draw()
+		time.AfterFunc(rateErr.retryAfter, func() {
+			setLabel(node, label)
+			node.SetColor(color)
+			loader()
+		})
+	})
+	node.SetSelectedFunc(loader)
+	return loader
+}
+
+// cancellableLoad runs load until it returns or cancelLoads is called.
+// The requests of a cancelled load keep running in the background, but their result is discarded.
+func (session *viewerSession) cancellableLoad(load func() ([]*tview.TreeNode, error)) ([]*tview.TreeNode, error) {
+	cancel := make(chan struct{})
+	session.loadLock.Lock()
+	if session.activeLoads == nil {
+		session.activeLoads = make(map[chan struct{}]bool)
+	}
+	session.activeLoads[cancel] = true
+	session.loadLock.Unlock()
+	defer func() {
+		session.loadLock.Lock()
+		delete(session.activeLoads, cancel)
+		session.loadLock.Unlock()
+	}()
+
+	type result struct {
+		children []*tview.TreeNode
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		children, err := load()
+		done <- result{children, err}
+	}()
+	select {
+	case r := <-done:
+		return r.children, r.err
+	case <-cancel:
+		return nil, errLoadCancelled
+	}
+}
+
+// cancelLoads cancels all running loads, it returns false if there were none
+func (session *viewerSession) cancelLoads() bool {
+	session.loadLock.Lock()
+	defer session.loadLock.Unlock()
+	cancelled := len(session.activeLoads) > 0
+	for cancel := range session.activeLoads {
+		close(cancel)
+		delete(session.activeLoads, cancel)
+	}
+	return cancelled
+}
+
+// openMainFeed selects or plays the main feed of a loaded session, depending on main_feed_action
+func (session *viewerSession) openMainFeed(perspectives []*tview.TreeNode) {
+	if session.cfg.MainFeedAction == "" {
+		return
+	}
+	for _, node := range perspectives {
+		ref, ok := node.GetReference().(*NodeMetadata)
+		if !ok || ref.nodeType != StreamNode {
+			continue
+		}
+		if c, ok := ref.metadata.(channel); !ok || c.Name != mainFeedName {
+			continue
+		}
+		switch session.cfg.MainFeedAction {
+		case "select":
+			session.tree.SetCurrentNode(node)
+			session.draw()
+		case "play":
+			commands := session.playerCommands(ref.titles)
+			if len(commands) == 0 {
+				session.logWarn("can't play the main feed, no player is available")
+				return
+			}
+			go func() {
+				err := session.runCustomCommand(commandContext{Titles: ref.titles, EpID: ref.id, CustomOptions: commands[0], Node: node})
+				if err != nil {
+					session.logError(err)
+				}
+			}()
+		}
+		return
+	}
+}
+
+func (session *viewerSession) nocontentNode() *tview.TreeNode {
 	return tview.NewTreeNode("no content").
-		SetColor(activeTheme.NoContentColor).
+		SetColor(session.theme.NoContentColor).
 		SetReference(&NodeMetadata{nodeType: MiscNode})
 }
 
+// used if perspective_order isn't set
+var defaultPerspectiveOrder = []string{"Main Feed", "Pit Lane", "Data Channel", "Driver Tracker", "onboard"}
+
+// sortPerspectives sorts the perspectives by the position of their labels in order, names are not case sensitive.
+// "onboard" is the position of all onboard cameras that aren't listed by name, they are sorted by racing number.
+// Perspectives that aren't listed keep their order after the listed ones.
+func sortPerspectives(perspectives []channel, order []string, labels map[string]string) []channel {
+	if len(order) == 0 {
+		order = defaultPerspectiveOrder
+	}
+	rank := func(c channel) int {
+		name := c.PrettyName(labels)
+		onboard := len(order)
+		for i, o := range order {
+			if strings.EqualFold(o, name) {
+				return i
+			}
+			if strings.EqualFold(o, "onboard") {
+				onboard = i
+			}
+		}
+		if c.racingNumber() > 0 {
+			return onboard
+		}
+		return len(order)
+	}
+	sorted := make([]channel, len(perspectives))
+	copy(sorted, perspectives)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := rank(sorted[i]), rank(sorted[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return sorted[i].racingNumber() < sorted[j].racingNumber()
+	})
+	return sorted
+}
+
+// orderNodes moves the nodes named in order to the front and removes hidden nodes.
+// Nodes that aren't mentioned keep their position after the ordered ones, names are not case sensitive.
+func orderNodes(nodes []*tview.TreeNode, order, hidden []string) []*tview.TreeNode {
+	isHidden := func(node *tview.TreeNode) bool {
+		return containsFold(hidden, nodeLabel(node))
+	}
+	var ordered []*tview.TreeNode
+	used := make(map[*tview.TreeNode]bool)
+	for _, name := range order {
+		for _, node := range nodes {
+			if !used[node] && !isHidden(node) && strings.EqualFold(name, nodeLabel(node)) {
+				ordered = append(ordered, node)
+				used[node] = true
+			}
+		}
+	}
+	for _, node := range nodes {
+		if !used[node] && !isHidden(node) {
+			ordered = append(ordered, node)
+		}
+	}
+	return ordered
+}
+
 func appendNodes(parent *tview.TreeNode, children ...*tview.TreeNode) {
 	for _, node := range children {
 		if node != nil {
@@ -587,12 +1547,67 @@ func appendNodes(parent *tview.TreeNode, children ...*tview.TreeNode) {
 	}
 }
 
+func removeNode(parentNode *tview.TreeNode, childNode *tview.TreeNode) {
+	var children []*tview.TreeNode
+	for _, node := range parentNode.GetChildren() {
+		if node != childNode {
+			children = append(children, node)
+		}
+	}
+	parentNode.SetChildren(children)
+}
+
+// replaceNode replaces the child with the new nodes at the same position
+func replaceNode(parentNode *tview.TreeNode, childNode *tview.TreeNode, newNodes ...*tview.TreeNode) {
+	var children []*tview.TreeNode
+	for _, node := range parentNode.GetChildren() {
+		if node == childNode {
+			children = append(children, newNodes...)
+		} else {
+			children = append(children, node)
+		}
+	}
+	parentNode.SetChildren(children)
+}
+
 func insertNodeAtTop(parentNode *tview.TreeNode, childNode *tview.TreeNode) {
 	children := parentNode.GetChildren()
 	children = append([]*tview.TreeNode{childNode}, children...)
 	parentNode.SetChildren(children)
 }
 
+// expandCategories expands the nodes named in expanded_categories, nodes that weren't loaded yet are loaded first
+func (session *viewerSession) expandCategories(nodes []*tview.TreeNode) {
+	for _, node := range nodes {
+		if !containsFold(session.cfg.ExpandedCategories, nodeLabel(node)) {
+			continue
+		}
+		if len(node.GetChildren()) > 0 {
+			node.SetExpanded(true)
+			continue
+		}
+		session.activateNode(node)
+	}
+}
+
+// activateNode selects the node like enter does, without moving the cursor
+func (session *viewerSession) activateNode(node *tview.TreeNode) {
+	current := session.tree.GetCurrentNode()
+	session.tree.SetCurrentNode(node)
+	session.tree.InputHandler()(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModAlt), func(tview.Primitive) {})
+	session.tree.SetCurrentNode(current)
+}
+
+// containsFold reports whether the list contains the name, ignoring case
+func containsFold(list []string, name string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func (session *viewerSession) toggleVisibility(node *tview.TreeNode) {
 	if len(node.GetChildren()) > 0 {
 		node.SetExpanded(!node.IsExpanded())
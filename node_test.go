@@ -1,7 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rivo/tview"
 	"github.com/stretchr/testify/assert"
@@ -22,3 +28,449 @@ func TestMetadata(t *testing.T) {
 	_, err = getMetadata(tview.NewTreeNode("Testing").SetReference(123))
 	assert.EqualError(t, err, "Node has reference of unexpected type int")
 }
+
+func TestFillEpisodeTemplate(t *testing.T) {
+	ep := episode{Title: "Race Highlights", Subtitle: "Monaco", UID: "epi_1", DataSourceID: "1906_MON"}
+
+	label, unknown := fillEpisodeTemplate("{Title} - {Subtitle}", ep)
+	assert.Equal(t, "Race Highlights - Monaco", label)
+	assert.Empty(t, unknown)
+
+	label, unknown = fillEpisodeTemplate("{Title} ({Date})", ep)
+	assert.Equal(t, "Race Highlights ({Date})", label)
+	assert.Equal(t, []string{"Date"}, unknown)
+}
+
+func TestFormatRacingNumber(t *testing.T) {
+	t.Parallel()
+	numbers := []int{1, 9, 10, 44, 100}
+	width := racingNumberWidth(numbers)
+	assert.Equal(t, 3, width)
+
+	var formatted []string
+	for _, n := range numbers {
+		formatted = append(formatted, formatRacingNumber(n, width))
+	}
+	assert.Equal(t, []string{"  1", "  9", " 10", " 44", "100"}, formatted)
+
+	assert.Equal(t, 2, racingNumberWidth([]int{0, 5, 44}))
+	assert.Equal(t, " 5", formatRacingNumber(5, 2))
+	assert.Equal(t, "100", formatRacingNumber(100, 2))
+
+	label, unknown := fillDriverTemplate("#{Number} {Name}", formatRacingNumber(44, 2), "Lewis Hamilton")
+	assert.Equal(t, "#44 Lewis Hamilton", label)
+	assert.Empty(t, unknown)
+}
+
+func TestPerspectiveColor(t *testing.T) {
+	t.Parallel()
+	var channels []channel
+	data := `[
+		{"name": "HAM", "driveroccurrence_urls": [{"driver_racingnumber": 44, "team_url": {"colour": "#00D2BE"}}]},
+		{"name": "VER", "driveroccurrence_urls": [{"driver_racingnumber": 33, "team_url": "/api/team-occurrence/team_1/"}]},
+		{"name": "WIF", "driveroccurrence_urls": []}
+	]`
+	assert.NoError(t, json.Unmarshal([]byte(data), &channels))
+
+	session := &viewerSession{theme: defaultTheme()}
+	assert.Equal(t, hexStringToColor("00D2BE"), session.perspectiveColor(channels[0]))
+	assert.Equal(t, session.theme.ItemNodeColor, session.perspectiveColor(channels[1]))
+	assert.Equal(t, session.theme.ItemNodeColor, session.perspectiveColor(channels[2]))
+
+	channels[0].Drivers[0].Team.Colour = "teal"
+	assert.Equal(t, session.theme.ItemNodeColor, session.perspectiveColor(channels[0]))
+}
+
+func TestMPVProfile(t *testing.T) {
+	t.Parallel()
+	profiles := map[string]string{"live": "low-latency", "replay": "high-quality", "Pit Lane": "pit"}
+
+	assert.Equal(t, "low-latency", mpvProfile(profiles, Titles{PerspectiveTitle: "Main Feed", Live: true}))
+	assert.Equal(t, "high-quality", mpvProfile(profiles, Titles{PerspectiveTitle: "Main Feed"}))
+	assert.Equal(t, "pit", mpvProfile(profiles, Titles{PerspectiveTitle: "Pit Lane", Live: true}))
+	assert.Equal(t, "", mpvProfile(profiles, Titles{EpisodeTitle: "Highlights"}))
+	assert.Equal(t, "", mpvProfile(nil, Titles{}))
+}
+
+func TestOrderNodes(t *testing.T) {
+	t.Parallel()
+	var nodes []*tview.TreeNode
+	for _, name := range []string{"Collections", "Documentaries", "Full Seasons", "Playlist", "Log Out"} {
+		nodes = append(nodes, tview.NewTreeNode(name))
+	}
+	texts := func(nodes []*tview.TreeNode) []string {
+		var s []string
+		for _, n := range nodes {
+			s = append(s, n.GetText())
+		}
+		return s
+	}
+
+	ordered := orderNodes(nodes, []string{"full seasons", "Playlist", "Unknown"}, []string{"documentaries"})
+	assert.Equal(t, []string{"Full Seasons", "Playlist", "Collections", "Log Out"}, texts(ordered))
+	assert.Equal(t, texts(nodes), texts(orderNodes(nodes, nil, nil)))
+}
+
+func TestReplaceNode(t *testing.T) {
+	t.Parallel()
+	root := tview.NewTreeNode("root")
+	first := tview.NewTreeNode("first")
+	failed := tview.NewTreeNode("failed")
+	last := tview.NewTreeNode("last")
+	root.SetChildren([]*tview.TreeNode{first, failed, last})
+
+	a := tview.NewTreeNode("a")
+	b := tview.NewTreeNode("b")
+	replaceNode(root, failed, a, b)
+	assert.Equal(t, []*tview.TreeNode{first, a, b, last}, root.GetChildren())
+
+	replaceNode(root, a)
+	assert.Equal(t, []*tview.TreeNode{first, b, last}, root.GetChildren())
+}
+
+func TestCancelLoads(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	assert.False(t, s.cancelLoads())
+
+	children, err := s.cancellableLoad(func() ([]*tview.TreeNode, error) {
+		return []*tview.TreeNode{tview.NewTreeNode("child")}, nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, children, 1)
+
+	block := make(chan struct{})
+	defer close(block)
+	result := make(chan error)
+	go func() {
+		_, err := s.cancellableLoad(func() ([]*tview.TreeNode, error) {
+			<-block
+			return nil, nil
+		})
+		result <- err
+	}()
+	assert.Eventually(t, s.cancelLoads, time.Second, 10*time.Millisecond)
+	assert.Equal(t, errLoadCancelled, <-result)
+	assert.False(t, s.cancelLoads())
+}
+
+func TestFlattenFolders(t *testing.T) {
+	t.Parallel()
+	folder := func(name string, children ...*tview.TreeNode) *tview.TreeNode {
+		return tview.NewTreeNode(name).SetReference(&NodeMetadata{nodeType: MiscNode}).SetChildren(children)
+	}
+	item := func(name string) *tview.TreeNode {
+		return tview.NewTreeNode(name).SetReference(&NodeMetadata{nodeType: PlayableNode})
+	}
+
+	single := item("Highlights")
+	nested := item("Race")
+	first, second := item("first"), item("second")
+	unloaded := folder("unloaded")
+	nodes := flattenFolders([]*tview.TreeNode{
+		folder("2019", single),
+		folder("2020", folder("Round 1", nested)),
+		folder("2021", first, second),
+		unloaded,
+	})
+
+	assert.Len(t, nodes, 4)
+	assert.Equal(t, single, nodes[0])
+	assert.Equal(t, "2019 - Highlights", single.GetText())
+	assert.Equal(t, nested, nodes[1])
+	assert.Equal(t, "2020 - Round 1 - Race", nested.GetText())
+	assert.Equal(t, "2021", nodes[2].GetText())
+	assert.Equal(t, []*tview.TreeNode{first, second}, nodes[2].GetChildren())
+	assert.Equal(t, unloaded, nodes[3])
+}
+
+func TestSortPerspectives(t *testing.T) {
+	t.Parallel()
+	onboard := func(name string, number int) channel {
+		return channel{Name: name, Drivers: []channelDriver{{RacingNumber: number}}}
+	}
+	perspectives := []channel{
+		onboard("Lewis Hamilton", 44),
+		{Name: "data"},
+		onboard("Kimi Räikkönen", 7),
+		{Name: "driver"},
+		{Name: "WIF"},
+		{Name: "Unknown"},
+		onboard("Max Verstappen", 33),
+		{Name: "pit lane"},
+	}
+	names := func(perspectives []channel) []string {
+		var names []string
+		for _, p := range perspectives {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{
+		"WIF", "pit lane", "data", "driver", "Kimi Räikkönen", "Max Verstappen", "Lewis Hamilton", "Unknown",
+	}, names(sortPerspectives(perspectives, nil, nil)))
+
+	assert.Equal(t, []string{
+		"Lewis Hamilton", "Kimi Räikkönen", "Max Verstappen", "WIF", "data", "driver", "Unknown", "pit lane",
+	}, names(sortPerspectives(perspectives, []string{"lewis hamilton", "onboard", "main feed"}, nil)))
+
+	// the input must not be modified
+	assert.Equal(t, "Lewis Hamilton", perspectives[0].Name)
+}
+
+func TestNewSessionNode(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	ended := sessionStruct{UID: "s1", Name: "Race", Status: "live", EndTime: time.Now().Add(-time.Minute)}
+	running := sessionStruct{UID: "s2", Name: "Race", Status: "live", EndTime: time.Now().Add(time.Hour)}
+	assert.True(t, ended.replayAvailable(time.Now()))
+	assert.False(t, running.replayAvailable(time.Now()))
+	assert.True(t, sessionStruct{Status: "replay"}.replayAvailable(time.Now()))
+
+	assert.Equal(t, "Race - LIVE", s.newSessionNode(Titles{}, ended).GetText())
+	assert.Equal(t, "Race - LIVE", s.newSessionNode(Titles{}, running).GetText())
+
+	s.cfg.LiveReplay = "replay"
+	assert.Equal(t, "Race - Replay", s.newSessionNode(Titles{}, ended).GetText())
+	assert.Equal(t, "Race - LIVE", s.newSessionNode(Titles{}, running).GetText())
+
+	s.cfg.LiveReplay = "both"
+	node := s.newSessionNode(Titles{}, ended)
+	assert.Equal(t, "Race - LIVE / Replay", node.GetText())
+	children := node.GetChildren()
+	assert.Len(t, children, 2)
+	assert.Equal(t, "LIVE", children[0].GetText())
+	assert.True(t, children[0].GetReference().(*NodeMetadata).titles.Live)
+	assert.Equal(t, "Replay", children[1].GetText())
+	assert.False(t, children[1].GetReference().(*NodeMetadata).titles.Live)
+}
+
+func TestExpandCategories(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 80, 20)
+	s.cfg.ExpandedCategories = []string{"full seasons", "Collections"}
+	s.tree.SetSelectedFunc(s.toggleVisibility)
+	root := s.tree.GetRoot()
+
+	seasons := tview.NewTreeNode("Full Seasons")
+	seasons.SetSelectedFunc(func() {
+		seasons.SetSelectedFunc(nil)
+		seasons.AddChild(tview.NewTreeNode("2020"))
+	})
+	collections := tview.NewTreeNode("Collections").SetExpanded(false).AddChild(tview.NewTreeNode("Docs"))
+	archive := tview.NewTreeNode("Archive").SetExpanded(false).AddChild(tview.NewTreeNode("1990"))
+	root.SetChildren([]*tview.TreeNode{archive, seasons, collections})
+	s.tree.SetCurrentNode(archive)
+
+	s.expandCategories(root.GetChildren())
+	assert.Len(t, seasons.GetChildren(), 1)
+	assert.True(t, seasons.IsExpanded())
+	assert.True(t, collections.IsExpanded())
+	assert.False(t, archive.IsExpanded())
+	assert.Equal(t, archive, s.tree.GetCurrentNode())
+
+	// expanding again doesn't collapse them
+	s.expandCategories(root.GetChildren())
+	assert.True(t, seasons.IsExpanded())
+	assert.True(t, collections.IsExpanded())
+}
+
+func TestSortSeasonNodes(t *testing.T) {
+	t.Parallel()
+	season := func(name string, year int) *tview.TreeNode {
+		return tview.NewTreeNode(name).SetReference(&NodeMetadata{nodeType: CategoryNode, metadata: seasonStruct{Name: name, Year: year}})
+	}
+	other := tview.NewTreeNode("other")
+	nodes := []*tview.TreeNode{season("2019 Formula 1 World Championship", 0), other, season("2020", 2020), season("2018", 2018)}
+
+	names := func() []string {
+		var names []string
+		for _, n := range nodes {
+			names = append(names, n.GetText())
+		}
+		return names
+	}
+	sortSeasonNodes(nodes, true)
+	assert.Equal(t, []string{"2020", "2019 Formula 1 World Championship", "2018", "other"}, names())
+	sortSeasonNodes(nodes, false)
+	assert.Equal(t, []string{"2018", "2019 Formula 1 World Championship", "2020", "other"}, names())
+}
+
+func TestEpisodeNodesByYear(t *testing.T) {
+	t.Parallel()
+	session := &viewerSession{theme: defaultTheme()}
+	var episodes []episode
+	for i := 0; i < 600; i++ {
+		// 20 seasons from 2000 to 2019, 30 races each
+		id := fmt.Sprintf("%02d%02d_RACE", i%20, i/20)
+		episodes = append(episodes, episode{Title: fmt.Sprintf("Episode %d", i), UID: fmt.Sprint(i), DataSourceID: id, Items: []string{"item"}})
+	}
+	episodes = append(episodes,
+		episode{Title: "no year", UID: "a", DataSourceID: "x", Items: []string{"item"}},
+		episode{Title: "no items", UID: "b", DataSourceID: "0101_RACE"})
+
+	nodes := session.episodeNodesByYear(Titles{CategoryTitle: "Archive"}, episodes)
+	if !assert.Len(t, nodes, 21) {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, node := range nodes[:20] {
+		assert.False(t, seen[node.GetText()], "duplicate year node %s", node.GetText())
+		seen[node.GetText()] = true
+		assert.Len(t, node.GetChildren(), 30)
+	}
+	assert.Equal(t, "2000", nodes[0].GetText())
+	assert.Equal(t, "no year", nodes[20].GetText())
+}
+
+func TestConfirmRequests(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 80, 20)
+	s.pages = tview.NewPages()
+	s.tree.SetSelectedFunc(s.toggleVisibility)
+	s.cfg.ConfirmRequests = 10
+	assert.Equal(t, 3, episodeRequests(11))
+	assert.Equal(t, 2, episodeRequests(10))
+
+	load := func() ([]*tview.TreeNode, error) {
+		return []*tview.TreeNode{tview.NewTreeNode("child")}, nil
+	}
+	small := tview.NewTreeNode("small")
+	big := tview.NewTreeNode("big")
+	// the tree is only changed before the app draws it
+	s.tree.GetRoot().AddChild(small).AddChild(big)
+	s.lazyLoadCounted(small, 10, load)
+	s.lazyLoadCounted(big, 11, load)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+
+	// the children are only read on the UI goroutine, where the loader adds them
+	children := func(node *tview.TreeNode) int {
+		var n int
+		s.app.QueueUpdate(func() { n = len(node.GetChildren()) })
+		return n
+	}
+
+	s.app.QueueUpdate(func() { s.activateNode(small) })
+	assert.Eventually(t, func() bool { return children(small) == 1 }, time.Second, 10*time.Millisecond)
+
+	s.app.QueueUpdate(func() { s.activateNode(big) })
+	assert.True(t, s.pages.HasPage("modal"))
+	assert.Equal(t, 0, children(big))
+
+	s.confirmLock.Lock()
+	s.skipConfirm = true
+	s.confirmLock.Unlock()
+	s.app.QueueUpdate(func() { s.activateNode(big) })
+	assert.Eventually(t, func() bool { return children(big) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestMarkGeoBlocked(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	node := tview.NewTreeNode("Monaco")
+	s.markGeoBlocked(node)
+	s.markGeoBlocked(node)
+	assert.Equal(t, "Monaco (geo-blocked)", node.GetText())
+	assert.Equal(t, s.theme.GeoBlockColor, node.GetColor())
+}
+
+func TestExpandPerspectives(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	s.commands["mpv"] = true
+	streams := []channel{
+		{Name: mainFeedName, Self: "/api/channels/1/"},
+		{Name: "pit lane", Self: "/api/channels/2/"},
+		{Name: "data", Self: "/api/channels/3/"},
+		{Name: "tracker", Self: "/api/channels/4/"},
+	}
+
+	tests := []struct {
+		limit    int
+		streams  int
+		expanded bool
+	}{
+		{limit: 3, streams: 2, expanded: true},
+		{limit: 3, streams: 3, expanded: true},
+		{limit: 3, streams: 4, expanded: false},
+		{limit: 0, streams: 1, expanded: false},
+		{limit: 0, streams: 4, expanded: false},
+	}
+	for _, tt := range tests {
+		s.cfg.ExpandPerspectives = tt.limit
+		nodes := s.getPerspectiveNodes(Titles{SessionTitle: "Race"}, streams[:tt.streams])
+		assert.Len(t, nodes, tt.streams)
+		for _, node := range nodes {
+			if tt.expanded {
+				// the player and download options are shown right away
+				assert.NotEmpty(t, node.GetChildren(), "%d of %d: %s", tt.streams, tt.limit, node.GetText())
+				assert.True(t, node.IsExpanded(), "%d of %d: %s", tt.streams, tt.limit, node.GetText())
+			} else {
+				// the options are only loaded when the perspective is selected
+				assert.Empty(t, node.GetChildren(), "%d of %d: %s", tt.streams, tt.limit, node.GetText())
+			}
+		}
+	}
+}
+
+func TestPrimaryPerspectives(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	s.cfg.PrimaryPerspectives = []string{"main feed", "Pit Lane"}
+	streams := []channel{
+		{Name: mainFeedName, Self: "/api/channels/1/"},
+		{Name: "pit lane", Self: "/api/channels/2/"},
+		{Name: "Lewis Hamilton", Self: "/api/channels/3/"},
+		{Name: "Max Verstappen", Self: "/api/channels/4/"},
+	}
+
+	nodes := s.getPerspectiveNodes(Titles{SessionTitle: "Race"}, streams)
+	if assert.Len(t, nodes, 3) {
+		assert.Equal(t, "Show all onboards… (2)", nodes[2].GetText())
+		assert.Len(t, nodes[2].GetChildren(), 2)
+		assert.False(t, nodes[2].IsExpanded())
+	}
+
+	s.cfg.PrimaryPerspectives = []string{"Data"}
+	assert.Len(t, s.getPerspectiveNodes(Titles{SessionTitle: "Race"}, streams), 4)
+}
+
+func TestEventWithoutName(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "event_circuit"):
+			fmt.Fprint(w, `{"uid": "event_circuit", "name": "", "official_name": "", "start_date": "2020-07-05",
+				"circuit_url": {"name": "Red Bull Ring"}, "sessionoccurrence_urls": ["/api/session-occurrence/s1/"]}`)
+		case strings.Contains(r.URL.Path, "event_official"):
+			fmt.Fprint(w, `{"uid": "event_official", "name": "", "official_name": "Formula 1 Rolex Grosser Preis von Österreich 2020",
+				"sessionoccurrence_urls": ["/api/session-occurrence/s2/"]}`)
+		default:
+			fmt.Fprint(w, `{"uid": "event_empty", "name": "", "sessionoccurrence_urls": ["/api/session-occurrence/s3/"]}`)
+		}
+	}))
+	defer server.Close()
+
+	_, s := newTestApp(t, 20, 5)
+	s.cfg.APIBaseURL = server.URL
+	s.configureAPI()
+	season := seasonStruct{Name: "2020", EventoccurrenceUrls: []string{
+		"/api/event-occurrence/event_circuit/",
+		"/api/event-occurrence/event_official/",
+		"/api/event-occurrence/event_empty/",
+	}}
+	nodes, err := s.getEventNodes(season)
+	assert.NoError(t, err)
+	if assert.Len(t, nodes, 3) {
+		assert.Equal(t, "Red Bull Ring 2020-07-05", nodes[0].GetText())
+		assert.Equal(t, "Formula 1 Rolex Grosser Preis von Österreich 2020", nodes[1].GetText())
+		// events without any name are skipped
+		assert.Nil(t, nodes[2])
+	}
+	_, err = s.getEventNode("/api/event-occurrence/event_empty/", "2020")
+	assert.Equal(t, errNoEventLabel, err)
+}
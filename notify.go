@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// events that can send desktop notifications, they are enabled in the notifications option
+const (
+	notifyLive     = "live"
+	notifyDownload = "download"
+	notifyBatch    = "batch"
+	notifyPlayback = "playback"
+)
+
+var notifyEvents = []string{notifyLive, notifyDownload, notifyBatch, notifyPlayback}
+
+// processExited stops tracking a process that was started and sends a notification if it's enabled
+func (session *viewerSession) processExited(proc *runningProcess, err error) {
+	session.untrackProcess(proc)
+	session.notifyExited(proc, err)
+}
+
+// notifyExited sends the notification for a finished process if it's enabled
+func (session *viewerSession) notifyExited(proc *runningProcess, err error) {
+	event, action := notifyPlayback, "playing"
+	if proc.download {
+		event, action = notifyDownload, "downloading"
+	}
+	if err != nil {
+		session.notify(event, fmt.Sprintf("%s %s failed: %v", action, proc.title, err))
+		return
+	}
+	session.notify(event, "finished "+action+" "+proc.title)
+}
+
+// notify shows a desktop notification if the event is enabled
+func (session *viewerSession) notify(event, message string) {
+	if !session.cfg.Notifications[event] {
+		return
+	}
+	args := notificationCommand(runtime.GOOS, "f1viewer", message)
+	if len(args) == 0 {
+		session.logDebug("notifications are not supported on ", runtime.GOOS)
+		return
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		session.logWarn("can't show notifications, ", args[0], " is not installed")
+		return
+	}
+	go func() {
+		// the output of the notification tools isn't interesting, only failures are logged
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			session.logWarn("could not show notification: ", err, " ", strings.TrimSpace(string(out)))
+		}
+	}()
+}
+
+// notificationCommand returns the command that shows a notification on the OS, or nil if there is none
+func notificationCommand(goos, title, message string) []string {
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"notify-send", "--app-name=f1viewer", title, message}
+	case "darwin":
+		quote := func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+		return []string{"osascript", "-e", "display notification " + quote(message) + " with title " + quote(title)}
+	case "windows":
+		quote := func(s string) string {
+			return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+		script := "Add-Type -AssemblyName System.Windows.Forms; " +
+			"$n = New-Object System.Windows.Forms.NotifyIcon; " +
+			"$n.Icon = [System.Drawing.SystemIcons]::Information; " +
+			"$n.Visible = $true; " +
+			"$n.ShowBalloonTip(10000, " + quote(title) + ", " + quote(message) + ", 'Info'); " +
+			"Start-Sleep -Seconds 10; $n.Dispose()"
+		return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", script}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationCommand(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"notify-send", "--app-name=f1viewer", "f1viewer", "finished downloading Race"},
+		notificationCommand("linux", "f1viewer", "finished downloading Race"))
+	assert.Equal(t, []string{"osascript", "-e", `display notification "say \"hi\"" with title "f1viewer"`},
+		notificationCommand("darwin", "f1viewer", `say "hi"`))
+	assert.Contains(t, notificationCommand("windows", "f1viewer", "Monaco's race")[4], "'Monaco''s race'")
+	assert.Nil(t, notificationCommand("plan9", "f1viewer", "hi"))
+}
+
+func TestValidateNotifications(t *testing.T) {
+	t.Parallel()
+	assert.Empty(t, validateConfig([]byte(`{"notifications": {"download": true, "live": false}}`)))
+	assert.Len(t, validateConfig([]byte(`{"notifications": {"downloads": true}}`)), 1)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nowPlaying is what now_playing_file contains while something is playing
+type nowPlaying struct {
+	Title       string    `json:"title"`
+	Perspective string    `json:"perspective,omitempty"`
+	Position    string    `json:"position,omitempty"`
+	Seconds     float64   `json:"seconds"`
+	State       string    `json:"state"`
+	Player      string    `json:"player"`
+	Updated     time.Time `json:"updated"`
+}
+
+func newNowPlaying(cc commandContext, pos time.Duration, paused bool, now time.Time) nowPlaying {
+	t := cc.Titles
+	n := nowPlaying{
+		Title:       strings.Join(nonEmpty(t.EventTitle, t.SessionTitle, t.EpisodeTitle), " - "),
+		Perspective: t.PerspectiveTitle,
+		State:       "playing",
+		Player:      cc.CustomOptions.Title,
+		Updated:     now,
+	}
+	if n.Title == "" {
+		n.Title = t.CategoryTitle
+	}
+	if t.Live {
+		n.State = "live"
+		return n
+	}
+	if paused {
+		n.State = "paused"
+	}
+	n.Position = formatPosition(pos)
+	n.Seconds = pos.Truncate(time.Second).Seconds()
+	return n
+}
+
+// format returns the file content, JSON for .json files and a single line for everything else, eg. for OBS text sources
+func (n nowPlaying) format(file string) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		return json.MarshalIndent(n, "", "\t")
+	}
+	return []byte(strings.Join(nonEmpty(n.Title, n.Perspective, n.Position, n.State), " | ") + "\n"), nil
+}
+
+// startNowPlaying writes the playback of a player that was just started to now_playing_file, it replaces what was shown before
+func (session *viewerSession) startNowPlaying(proc *runningProcess, cc commandContext, pos time.Duration) {
+	if session.cfg.NowPlayingFile == "" {
+		return
+	}
+	session.nowPlayingLock.Lock()
+	defer session.nowPlayingLock.Unlock()
+	session.nowPlayingProc = proc
+	session.writeNowPlaying(cc, pos, false)
+}
+
+// updateNowPlaying writes the position MPV reported to now_playing_file, unless another player was started since
+func (session *viewerSession) updateNowPlaying(proc *runningProcess, cc commandContext, pos time.Duration, paused bool) {
+	if session.cfg.NowPlayingFile == "" {
+		return
+	}
+	session.nowPlayingLock.Lock()
+	defer session.nowPlayingLock.Unlock()
+	if session.nowPlayingProc != proc {
+		return
+	}
+	session.writeNowPlaying(cc, pos, paused)
+}
+
+// writeNowPlaying writes the file, nowPlayingLock has to be held
+func (session *viewerSession) writeNowPlaying(cc commandContext, pos time.Duration, paused bool) {
+	file := session.cfg.NowPlayingFile
+	data, err := newNowPlaying(cc, pos, paused, time.Now()).format(file)
+	if err == nil {
+		err = writeFileAtomic(file, data, 0644)
+	}
+	if err != nil {
+		session.logDebug("could not update the now playing file: ", err)
+	}
+}
+
+// clearNowPlaying empties now_playing_file when the player stops, unless something else was started since
+func (session *viewerSession) clearNowPlaying(proc *runningProcess) {
+	file := session.cfg.NowPlayingFile
+	if file == "" {
+		return
+	}
+	session.nowPlayingLock.Lock()
+	defer session.nowPlayingLock.Unlock()
+	if session.nowPlayingProc != proc {
+		return
+	}
+	session.nowPlayingProc = nil
+	if err := writeFileAtomic(file, nil, 0644); err != nil {
+		session.logDebug("could not clear the now playing file: ", err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNowPlayingFormat(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, 7, 5, 12, 0, 0, 0, time.UTC)
+	cc := commandContext{Titles: Titles{EventTitle: "Austrian Grand Prix", SessionTitle: "Race", PerspectiveTitle: "Main Feed"}, CustomOptions: command{Title: "MPV"}}
+
+	data, err := newNowPlaying(cc, 754*time.Second, false, now).format("now.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "Austrian Grand Prix - Race | Main Feed | 0:12:34 | playing\n", string(data))
+
+	data, err = newNowPlaying(cc, 754*time.Second, true, now).format("now.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "Austrian Grand Prix - Race | Main Feed | 0:12:34 | paused\n", string(data))
+
+	data, err = newNowPlaying(cc, 754*time.Second, false, now).format("now.JSON")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"title": "Austrian Grand Prix - Race", "perspective": "Main Feed", "position": "0:12:34", "seconds": 754,
+		"state": "playing", "player": "MPV", "updated": "2020-07-05T12:00:00Z"}`, string(data))
+
+	cc.Titles.Live = true
+	data, err = newNowPlaying(cc, 0, false, now).format("now.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "Austrian Grand Prix - Race | Main Feed | live\n", string(data))
+}
+
+func TestNowPlayingFile(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer-nowplaying")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	_, s := newTestApp(t, 20, 5)
+	s.cfg.NowPlayingFile = filepath.Join(dir, "now.txt")
+
+	first, second := &runningProcess{}, &runningProcess{}
+	monaco, silverstone := commandContext{Titles: Titles{EventTitle: "Monaco"}}, commandContext{Titles: Titles{EventTitle: "Silverstone"}}
+	s.startNowPlaying(first, monaco, time.Minute)
+	s.startNowPlaying(second, silverstone, 0)
+	// positions of the first player don't replace the one that was started later
+	s.updateNowPlaying(first, monaco, 2*time.Minute, false)
+	data, err := ioutil.ReadFile(s.cfg.NowPlayingFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "Silverstone | 0:00:00 | playing\n", string(data))
+	s.updateNowPlaying(second, silverstone, time.Second, true)
+	data, err = ioutil.ReadFile(s.cfg.NowPlayingFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "Silverstone | 0:00:01 | paused\n", string(data))
+
+	// the first player stopping doesn't clear the second one
+	s.clearNowPlaying(first)
+	data, err = ioutil.ReadFile(s.cfg.NowPlayingFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "Silverstone | 0:00:01 | paused\n", string(data))
+
+	s.clearNowPlaying(second)
+	data, err = ioutil.ReadFile(s.cfg.NowPlayingFile)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
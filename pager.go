@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// pagerArgs returns the command that opens the file: pager from the config, $PAGER or a default pager.
+// $file is replaced with the file, it's added as the last argument if $file isn't used.
+func pagerArgs(pager []string, env, goos, file string) []string {
+	command := pager
+	if len(command) == 0 {
+		command = strings.Fields(env)
+	}
+	if len(command) == 0 {
+		command = []string{"less"}
+		if goos == "windows" {
+			command = []string{"more"}
+		}
+	}
+	args := make([]string, len(command))
+	hasFile := false
+	for i, arg := range command {
+		if strings.Contains(arg, "$file") {
+			hasFile = true
+		}
+		args[i] = strings.ReplaceAll(arg, "$file", file)
+	}
+	if !hasFile {
+		args = append(args, file)
+	}
+	return args
+}
+
+// infoText returns the info table's rows without cutting off long values, after the label of the node
+func (session *viewerSession) infoText() string {
+	var lines []string
+	for _, row := range session.infoRows {
+		if row.key != "" {
+			lines = append(lines, row.key+": "+row.value)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	if session.infoNode != nil {
+		lines = append([]string{nodeLabel(session.infoNode)}, lines...)
+	}
+	return strings.Join(lines, "\n\n") + "\n"
+}
+
+// openInfoInPager saves the info table to a temporary file and opens it with the pager.
+// f1viewer is suspended until the pager exits, so terminal pagers like less can be used.
+func (session *viewerSession) openInfoInPager() {
+	text := session.infoText()
+	if text == "" {
+		session.logInfo("there is no info to open")
+		return
+	}
+	file, err := ioutil.TempFile("", "f1viewer-info-*.txt")
+	if err != nil {
+		session.logError("could not save the info: ", err)
+		return
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(text)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		session.logError("could not save the info: ", err)
+		return
+	}
+
+	args := pagerArgs(session.cfg.Pager, os.Getenv("PAGER"), runtime.GOOS, file.Name())
+	session.app.Suspend(func() {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+	})
+	if err != nil {
+		session.logError("could not open the info with ", args[0], ": ", err)
+	}
+}
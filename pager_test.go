@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerArgs(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"less", "info.txt"}, pagerArgs(nil, "", "linux", "info.txt"))
+	assert.Equal(t, []string{"more", "info.txt"}, pagerArgs(nil, "", "windows", "info.txt"))
+	assert.Equal(t, []string{"most", "-s", "info.txt"}, pagerArgs(nil, "most -s", "linux", "info.txt"))
+	assert.Equal(t, []string{"code", "--wait", "info.txt"}, pagerArgs([]string{"code", "--wait"}, "most", "linux", "info.txt"))
+	assert.Equal(t, []string{"vim", "-R", "info.txt", "+1"}, pagerArgs([]string{"vim", "-R", "$file", "+1"}, "", "linux", "info.txt"))
+}
+
+func TestInfoText(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	assert.Empty(t, s.infoText())
+
+	s.infoNode = tview.NewTreeNode("Race")
+	s.infoRows = []infoRow{{key: "Title", value: "Race"}, {key: "Synopsis", value: "A long synopsis"}}
+	assert.Equal(t, "Race\n\nTitle: Race\n\nSynopsis: A long synopsis\n", s.infoText())
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// timeout for reading and writing a watch party URL
+const partyTimeout = 5 * time.Second
+
+var partyClient = &http.Client{Timeout: partyTimeout}
+
+// watchParty shares the playback position of the host through a file or URL, so others can start at the same position
+type watchParty struct {
+	// a file path or an http(s) URL that accepts PUT requests
+	Location string `json:"location"`
+	// the host writes its position, everybody else reads it
+	Host bool `json:"host"`
+}
+
+// partyState is what the host writes to the watch party location
+type partyState struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Position float64   `json:"position"`
+	Updated  time.Time `json:"updated"`
+	// the host paused, the position doesn't move until it's updated again
+	Paused bool `json:"paused"`
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+func writePartyState(location string, state partyState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if !isURL(location) {
+		return writeFileAtomic(location, data, 0644)
+	}
+	req, err := http.NewRequest(http.MethodPut, location, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := partyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", location, resp.Status)
+	}
+	return nil
+}
+
+func readPartyState(location string) (partyState, error) {
+	var state partyState
+	var data []byte
+	var err error
+	if isURL(location) {
+		var resp *http.Response
+		resp, err = partyClient.Get(location)
+		if err != nil {
+			return state, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return state, fmt.Errorf("%s returned %s", location, resp.Status)
+		}
+		data, err = ioutil.ReadAll(resp.Body)
+	} else {
+		data, err = ioutil.ReadFile(location)
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// position returns where the host is now, assuming it kept playing since the state was written unless it was paused
+func (state partyState) position(now time.Time) time.Duration {
+	pos := time.Duration(state.Position * float64(time.Second))
+	if elapsed := now.Sub(state.Updated); elapsed > 0 && !state.Paused {
+		pos += elapsed
+	}
+	return pos
+}
+
+// publishPosition writes the host's position, failures are only logged in debug mode so they don't flood the output
+func (session *viewerSession) publishPosition(cc commandContext, pos time.Duration, paused bool) {
+	state := partyState{ID: cc.EpID, Title: cc.Titles.String(), Position: pos.Seconds(), Paused: paused, Updated: time.Now()}
+	if err := writePartyState(session.cfg.WatchParty.Location, state); err != nil {
+		session.logDebug("could not update the watch party: ", err)
+	}
+}
+
+// partyNode returns a node that plays the content with MPV at the host's position,
+// or nil if there is no watch party or MPV isn't installed
+func (session *viewerSession) partyNode(t Titles, epID string) *tview.TreeNode {
+	if session.cfg.WatchParty.Location == "" || session.cfg.WatchParty.Host || t.Live {
+		return nil
+	}
+	var mpv *command
+	for _, c := range session.playerCommands(t) {
+		if c.Title == "Play with MPV" {
+			c := c
+			mpv = &c
+			break
+		}
+	}
+	if mpv == nil {
+		return nil
+	}
+	node := tview.NewTreeNode("Join watch party").
+		SetColor(session.theme.ActionNodeColor).
+		SetReference(&NodeMetadata{nodeType: ActionNode, titles: t})
+	node.SetSelectedFunc(func() {
+		go func() {
+			state, err := readPartyState(session.cfg.WatchParty.Location)
+			if err != nil {
+				session.logError("could not read the watch party: ", err)
+				return
+			}
+			if state.ID != epID {
+				session.logWarn("the watch party is watching ", state.Title)
+				return
+			}
+			cc := commandContext{Titles: t, EpID: epID, CustomOptions: *mpv, Node: node, Start: state.position(time.Now())}
+			session.logInfo("joining the watch party at ", formatPosition(cc.Start))
+			if err := session.runCustomCommand(cc); err != nil {
+				session.logError(err)
+			}
+		}()
+	})
+	return node
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartyState(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	updated := time.Date(2020, 7, 5, 14, 0, 0, 0, time.UTC)
+	state := partyState{ID: "ep_1", Title: "Austrian Grand Prix - Race", Position: 90, Updated: updated}
+
+	path := filepath.Join(dir, "party.json")
+	assert.NoError(t, writePartyState(path, state))
+	read, err := readPartyState(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "ep_1", read.ID)
+	assert.Equal(t, 100*time.Second, read.position(updated.Add(10*time.Second)))
+
+	// a paused host stays at the same position
+	state.Paused = true
+	assert.NoError(t, writePartyState(path, state))
+	read, err = readPartyState(path)
+	assert.NoError(t, err)
+	assert.True(t, read.Paused)
+	assert.Equal(t, 90*time.Second, read.position(updated.Add(10*time.Second)))
+	state.Paused = false
+
+	var stored []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			stored, _ = ioutil.ReadAll(r.Body)
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(stored)
+		}
+	}))
+	defer server.Close()
+
+	_, err = readPartyState(server.URL)
+	assert.Error(t, err)
+	assert.NoError(t, writePartyState(server.URL, state))
+	read, err = readPartyState(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, state.Title, read.Title)
+	assert.Equal(t, 90*time.Second, read.position(updated))
+}
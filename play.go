@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// findSessionStream returns the perspective with the name, or the main feed if perspective is empty.
+// If nothing matches the error lists the available perspectives.
+func findSessionStream(sessionID string, streams []channel, perspective string, labels map[string]string) (channel, error) {
+	if len(streams) == 0 {
+		return channel{}, fmt.Errorf("session '%s' has no streams", sessionID)
+	}
+	if perspective == "" {
+		for _, s := range streams {
+			if s.Name == mainFeedName {
+				return s, nil
+			}
+		}
+		return streams[0], nil
+	}
+	stream, err := findPerspectiveByName(perspective, streams, labels)
+	if err != nil {
+		names := make([]string, len(streams))
+		for i, s := range streams {
+			names[i] = s.PrettyName(labels)
+		}
+		return channel{}, fmt.Errorf("%w, available perspectives: %s", err, strings.Join(names, ", "))
+	}
+	return stream, nil
+}
+
+// playFromCommandLine plays a perspective of a session with the default player without starting the UI.
+// It uses the saved credentials and waits until the player exits.
+func playFromCommandLine(w io.Writer, profile, sessionID, perspective string, dryRun bool) error {
+	cfg, err := loadConfig(profile)
+	if err != nil {
+		return fmt.Errorf("Could not open config: %w", err)
+	}
+
+	session := &viewerSession{cfg: cfg, commands: make(map[string]bool)}
+	session.configureAPI()
+	for _, program := range []string{"mpv", "vlc"} {
+		_, err := exec.LookPath(program)
+		session.commands[program] = err == nil
+	}
+
+	s, err := session.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	streams, err := session.getSessionStreams(sessionID)
+	if err != nil {
+		return err
+	}
+	stream, err := findSessionStream(sessionID, streams, perspective, cfg.PerspectiveLabels)
+	if err != nil {
+		return err
+	}
+	t := Titles{SessionTitle: s.Name, PerspectiveTitle: stream.PrettyName(cfg.PerspectiveLabels), Live: s.Status == "live"}
+	commands := session.playerCommands(t)
+	if len(commands) == 0 {
+		return fmt.Errorf("found no player, install MPV or VLC or add custom_playback_options")
+	}
+
+	if err := session.openRing(); err != nil {
+		return fmt.Errorf("Could not access credential store: %w", err)
+	}
+	if err := session.loadCredentials(); err != nil {
+		return fmt.Errorf("%w, log in once without -play to save your credentials", err)
+	}
+	if session.authtoken, err = session.login(); err != nil {
+		return err
+	}
+	url, err := session.playableURL(stream.Self)
+	if err != nil {
+		return err
+	}
+	cc := commandContext{Titles: t, EpID: stream.Self, CustomOptions: commands[0]}
+	cc.Quality = session.quality(t)
+	args := fillCommand(cc, url)
+	if dryRun {
+		fmt.Fprintln(w, strings.Join(args, " "))
+		return nil
+	}
+	fmt.Fprintf(w, "playing %s with %s\n", t.String(), commands[0].Title)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSessionStream(t *testing.T) {
+	t.Parallel()
+	streams := []channel{
+		{Name: "pit lane", Self: "/api/channels/pit/"},
+		{Name: mainFeedName, Self: "/api/channels/main/"},
+	}
+
+	stream, err := findSessionStream("race", streams, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/channels/main/", stream.Self)
+
+	stream, err = findSessionStream("race", streams, "Pit Lane", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/channels/pit/", stream.Self)
+
+	_, err = findSessionStream("race", streams, "Hamilton", nil)
+	assert.EqualError(t, err, "found no perspective matching 'Hamilton', available perspectives: Pit Lane, Main Feed")
+
+	_, err = findSessionStream("race", nil, "", nil)
+	assert.Error(t, err)
+}
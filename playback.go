@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+)
+
+// playback is the content that was played last and the command it was played with
+type playback struct {
+	node    *tview.TreeNode
+	command command
+}
+
+// findParent returns the parent of the node in the tree, or nil if it isn't part of the tree
+func (session *viewerSession) findParent(node *tview.TreeNode) *tview.TreeNode {
+	var parent *tview.TreeNode
+	session.tree.GetRoot().Walk(func(n, p *tview.TreeNode) bool {
+		if n == node {
+			parent = p
+			return false
+		}
+		return parent == nil
+	})
+	return parent
+}
+
+// playableID returns the ID that is used to get the URL of a perspective or episode node
+func playableID(ref *NodeMetadata) (string, bool) {
+	if ref.nodeType == StreamNode {
+		return ref.id, true
+	}
+	if ep, ok := ref.metadata.(episode); ok && len(ep.Items) > 0 {
+		return ep.Items[0], true
+	}
+	return "", false
+}
+
+// setPlaying remembers the content node of a command node that was selected
+func (session *viewerSession) setPlaying(commandNode *tview.TreeNode, c command) {
+	content := session.findParent(commandNode)
+	if content == nil {
+		return
+	}
+	session.playbackLock.Lock()
+	defer session.playbackLock.Unlock()
+	session.playing = &playback{node: content, command: c}
+}
+
+// playDefault plays a perspective or episode node with the default player.
+// It returns false if the node isn't playable or there is no player.
+func (session *viewerSession) playDefault(node *tview.TreeNode) bool {
+	if node == nil {
+		return false
+	}
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok {
+		return false
+	}
+	epID, ok := playableID(ref)
+	if !ok {
+		return false
+	}
+	commands := session.playerCommands(ref.titles)
+	if len(commands) == 0 {
+		return false
+	}
+	session.playbackLock.Lock()
+	session.playing = &playback{node: node, command: commands[0]}
+	session.playbackLock.Unlock()
+
+	context := commandContext{Titles: ref.titles, EpID: epID, CustomOptions: commands[0], Node: node}
+	go func() {
+		err := session.runCustomCommand(context)
+		if err != nil {
+			session.logError(err)
+		}
+	}()
+	return true
+}
+
+// playSibling plays the next or previous perspective or episode next to the last played content with the same command
+func (session *viewerSession) playSibling(offset int) {
+	session.playbackLock.Lock()
+	playing := session.playing
+	session.playbackLock.Unlock()
+	if playing == nil {
+		session.logInfo("nothing was played yet")
+		return
+	}
+	parent := session.findParent(playing.node)
+	if parent == nil {
+		return
+	}
+	siblings := parent.GetChildren()
+	index := -1
+	for i, n := range siblings {
+		if n == playing.node {
+			index = i
+		}
+	}
+
+	for i := index + offset; i >= 0 && i < len(siblings); i += offset {
+		ref, ok := siblings[i].GetReference().(*NodeMetadata)
+		if !ok {
+			continue
+		}
+		epID, ok := playableID(ref)
+		if !ok {
+			continue
+		}
+		session.playbackLock.Lock()
+		session.playing = &playback{node: siblings[i], command: playing.command}
+		session.playbackLock.Unlock()
+		session.tree.SetCurrentNode(siblings[i])
+
+		context := commandContext{Titles: ref.titles, EpID: epID, CustomOptions: playing.command}
+		go func() {
+			err := session.runCustomCommand(context)
+			if err != nil {
+				session.logError(err)
+			}
+		}()
+		return
+	}
+	session.logInfo("there is nothing to play after ", nodeLabel(playing.node))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindParent(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	parent := tview.NewTreeNode("session")
+	child := tview.NewTreeNode("perspective")
+	parent.AddChild(child)
+	s.tree.GetRoot().AddChild(parent)
+
+	assert.Equal(t, parent, s.findParent(child))
+	assert.Equal(t, s.tree.GetRoot(), s.findParent(parent))
+	assert.Nil(t, s.findParent(tview.NewTreeNode("orphan")))
+}
+
+func TestPlayableID(t *testing.T) {
+	t.Parallel()
+	id, ok := playableID(&NodeMetadata{nodeType: StreamNode, id: "/api/channels/chan_1/"})
+	assert.True(t, ok)
+	assert.Equal(t, "/api/channels/chan_1/", id)
+
+	id, ok = playableID(&NodeMetadata{nodeType: PlayableNode, id: "ep_1", metadata: episode{Items: []string{"/api/assets/asset_1/"}}})
+	assert.True(t, ok)
+	assert.Equal(t, "/api/assets/asset_1/", id)
+
+	_, ok = playableID(&NodeMetadata{nodeType: PlayableNode, id: "sess_1"})
+	assert.False(t, ok)
+}
+
+func TestPlayDefault(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	perspective := tview.NewTreeNode("Pit Lane").SetReference(&NodeMetadata{nodeType: StreamNode, id: "/api/channels/chan_1/"})
+	category := tview.NewTreeNode("Collections").SetReference(&NodeMetadata{nodeType: CategoryNode})
+
+	// without a player enter has to expand the node
+	assert.False(t, s.playDefault(perspective))
+	assert.False(t, s.playDefault(category))
+	assert.False(t, s.playDefault(nil))
+	assert.Nil(t, s.playing)
+}
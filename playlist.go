@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/rivo/tview"
+)
+
+func getPlaylistPath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return path + "playlist.m3u", nil
+}
+
+// addToPlaylist appends the content's URL to the playlist file
+func (session *viewerSession) addToPlaylist(epID string, t Titles) error {
+	url, err := session.playableURL(epID)
+	if err != nil {
+		return err
+	}
+	path, err := getPlaylistPath()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("could not open playlist: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		fmt.Fprintln(f, "#EXTM3U")
+	}
+	_, err = fmt.Fprintf(f, "#EXTINF:-1,%s\n%s\n", t.String(), url)
+	return err
+}
+
+func (session *viewerSession) getPlaylistNode() *tview.TreeNode {
+	playlistNode := tview.NewTreeNode("Playlist").
+		SetColor(session.theme.CategoryNodeColor).
+		SetExpanded(false).
+		SetReference(&NodeMetadata{nodeType: CategoryNode})
+
+	playNode := tview.NewTreeNode("Play with MPV").
+		SetColor(session.theme.ActionNodeColor).
+		SetReference(&NodeMetadata{nodeType: ActionNode})
+	playNode.SetSelectedFunc(func() {
+		path, err := getPlaylistPath()
+		if err != nil {
+			session.logError(err)
+			return
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			session.logInfo("the playlist is empty")
+			return
+		}
+		if !session.commandAvailable("mpv") {
+			session.logError("playing the playlist requires MPV")
+			return
+		}
+		proc, ok := session.trackProcess("playlist", "Playlist", false)
+		if !ok {
+			session.logInfo("the playlist is already playing")
+			return
+		}
+		err = session.runTracked(proc, exec.Command("mpv", "--playlist="+path, "--alang="+session.cfg.Lang, "--quiet"))
+		if err != nil {
+			session.logError(err)
+		}
+	})
+
+	clearNode := tview.NewTreeNode("Clear playlist").
+		SetColor(session.theme.ActionNodeColor).
+		SetReference(&NodeMetadata{nodeType: ActionNode})
+	clearNode.SetSelectedFunc(func() {
+		path, err := getPlaylistPath()
+		if err != nil {
+			session.logError(err)
+			return
+		}
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			session.logError("could not clear playlist: ", err)
+			return
+		}
+		session.logInfo("playlist cleared")
+	})
+
+	appendNodes(playlistNode, playNode, clearNode)
+	return playlistNode
+}
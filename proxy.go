@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// configProxy returns the proxy set in the config, or nil if there is none
+func configProxy(cfg config) *url.URL {
+	if cfg.Proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(cfg.Proxy)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// newStreamTransport returns the transport playlists and segments are fetched with, it uses the proxy like the API
+func newStreamTransport(proxy *url.URL) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != nil {
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	return t
+}
+
+// dialThrough opens a connection to addr through the proxy, or directly if proxy is nil.
+// http and https proxies are asked to CONNECT to addr, socks5 proxies are asked to connect with their own protocol.
+func dialThrough(proxy *url.URL, addr string) (net.Conn, error) {
+	if proxy == nil {
+		return net.DialTimeout("tcp", addr, 10*time.Second)
+	}
+	conn, err := net.DialTimeout("tcp", proxyAddress(proxy), 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	switch proxy.Scheme {
+	case "socks5":
+		err = socksConnect(conn, proxy, addr)
+	case "https":
+		conn = tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		fallthrough
+	default:
+		err = httpConnect(conn, proxy, addr)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// proxyAddress returns the host and port of the proxy, with the default port of its scheme if it has none
+func proxyAddress(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	port := map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxy.Scheme]
+	return net.JoinHostPort(proxy.Hostname(), port)
+}
+
+func httpConnect(conn net.Conn, proxy *url.URL, addr string) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxy.User; user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("CONNECT to %s failed: %s", addr, resp.Status)
+	}
+	return nil
+}
+
+// socksConnect asks a SOCKS5 proxy to connect to addr, with the user and password of the proxy URL if it has one
+func socksConnect(conn net.Conn, proxy *url.URL, addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil || len(host) > 255 {
+		return fmt.Errorf("invalid address %s", addr)
+	}
+
+	methods := []byte{0}
+	if proxy.User != nil {
+		methods = append(methods, 2)
+	}
+	if _, err := conn.Write(append([]byte{5, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	switch {
+	case reply[0] != 5:
+		return errors.New("not a SOCKS5 proxy")
+	case reply[1] == 2 && proxy.User != nil:
+		user := proxy.User.Username()
+		password, _ := proxy.User.Password()
+		auth := append([]byte{1, byte(len(user))}, user...)
+		auth = append(append(auth, byte(len(password))), password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0 {
+			return errors.New("SOCKS5 authentication failed")
+		}
+	case reply[1] != 0:
+		return errors.New("no supported SOCKS5 authentication method")
+	}
+
+	req := append([]byte{5, 1, 0, 3, byte(len(host))}, host...)
+	req = append(req, byte(portNum>>8), byte(portNum))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0 {
+		return fmt.Errorf("SOCKS5 connect to %s failed with code %d", addr, header[1])
+	}
+	// skip the address the proxy bound to and its port
+	var skip int
+	switch header[3] {
+	case 1:
+		skip = net.IPv4len + 2
+	case 4:
+		skip = net.IPv6len + 2
+	case 3:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		skip = int(length[0]) + 2
+	default:
+		return errors.New("invalid SOCKS5 reply")
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip))
+	return err
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// get fetches the URL through the proxy and returns the body
+func get(t *testing.T, proxy, target string) string {
+	proxyURL, err := url.Parse(proxy)
+	assert.NoError(t, err)
+	transport := &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(target)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestChainedProxy(t *testing.T) {
+	t.Parallel()
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "plain")
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secure")
+	}))
+	defer secure.Close()
+
+	var forwarded int32
+	upstreamHandler := throttleProxy(nil, nil)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&forwarded, 1)
+		upstreamHandler.ServeHTTP(w, r)
+	}))
+	defer upstream.Close()
+	upstreamURL, err := url.Parse(upstream.URL)
+	assert.NoError(t, err)
+
+	local := httptest.NewServer(throttleProxy(newRateLimiter(1000), upstreamURL))
+	defer local.Close()
+
+	assert.Equal(t, "plain", get(t, local.URL, plain.URL))
+	assert.Equal(t, "secure", get(t, local.URL, secure.URL))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&forwarded))
+}
+
+// serveSocks accepts one SOCKS5 connection without authentication and connects it to the requested address
+func serveSocks(t *testing.T, listener net.Listener) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	greeting := make([]byte, 3)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		t.Error(err)
+		return
+	}
+	conn.Write([]byte{5, 0})
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Error(err)
+		return
+	}
+	rest := make([]byte, int(header[4])+2)
+	if _, err := io.ReadFull(conn, rest); err != nil {
+		t.Error(err)
+		return
+	}
+	host := string(rest[:header[4]])
+	port := int(rest[header[4]])<<8 | int(rest[header[4]+1])
+	server, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer server.Close()
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+	go io.Copy(server, conn)
+	io.Copy(conn, server)
+}
+
+func TestSocksProxy(t *testing.T) {
+	t.Parallel()
+
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secure")
+	}))
+	defer secure.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go serveSocks(t, listener)
+
+	local := httptest.NewServer(throttleProxy(nil, &url.URL{Scheme: "socks5", Host: listener.Addr().String()}))
+	defer local.Close()
+	assert.Equal(t, "secure", get(t, local.URL, secure.URL))
+}
+
+func TestStreamClientProxy(t *testing.T) {
+	t.Parallel()
+
+	s := &viewerSession{cfg: config{Proxy: "http://proxy.example.com:8080"}}
+	s.configureAPI()
+	proxy, err := s.streamClient.Transport.(*http.Transport).Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "f1tv.formula1.com"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:8080", proxy.String())
+
+	assert.Equal(t, "proxy.example.com:80", proxyAddress(&url.URL{Scheme: "http", Host: "proxy.example.com"}))
+	assert.Equal(t, "localhost:1080", proxyAddress(&url.URL{Scheme: "socks5", Host: "localhost"}))
+}
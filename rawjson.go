@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// replaces secrets in the raw JSON view
+const redacted = "REDACTED"
+
+// keys and query parameters whose values are redacted, matched case insensitively
+var secretKeys = []string{"token", "password", "secret", "authorization", "signature", "policy", "key-pair-id", "cookie"}
+
+// apiResource returns the API collection and UID the node's metadata was loaded from
+func apiResource(metadata interface{}) (string, string, bool) {
+	switch m := metadata.(type) {
+	case episode:
+		return "episodes", m.UID, true
+	case sessionStruct:
+		return "session-occurrence", m.UID, true
+	case eventStruct:
+		return "event-occurrence", m.UID, true
+	case seasonStruct:
+		return "race-season", m.UID, true
+	case channel:
+		return "channels", m.UID, true
+	case collection:
+		return "sets", m.UID, true
+	default:
+		return "", "", false
+	}
+}
+
+// getRawJSON requests all fields of an API object
+func (session *viewerSession) getRawJSON(collection, uid string) ([]byte, error) {
+	var raw json.RawMessage
+	err := session.newRequest(collection, uid).Execute(&raw)
+	return raw, err
+}
+
+// showRawJSON shows what the API returns for the current node.
+// If the request fails the data f1viewer decoded when the node was loaded is shown instead.
+func (session *viewerSession) showRawJSON(node *tview.TreeNode) {
+	if !session.cfg.DebugActions {
+		session.logInfo("the raw JSON view is a debug action, enable debug_actions or start f1viewer with -d")
+		return
+	}
+	if node == nil {
+		return
+	}
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok || ref.metadata == nil {
+		session.logInfo("no API data for ", nodeLabel(node))
+		return
+	}
+	go func() {
+		title := " " + nodeLabel(node) + " "
+		var data []byte
+		collection, uid, ok := apiResource(ref.metadata)
+		if ok {
+			var err error
+			data, err = session.getRawJSON(collection, uid)
+			if err != nil {
+				session.logWarn("could not request the raw JSON, showing the loaded data instead: ", err)
+				data = nil
+			} else {
+				title = " " + collection + "/" + uid + " "
+			}
+		}
+		if data == nil {
+			var err error
+			data, err = json.Marshal(ref.metadata)
+			if err != nil {
+				session.logError(err)
+				return
+			}
+			title += "(loaded data) "
+		}
+		text, err := formatJSON(data)
+		if err != nil {
+			session.logError("invalid JSON: ", err)
+			return
+		}
+		session.app.QueueUpdateDraw(func() {
+			session.showText(title, highlightJSON(text))
+		})
+	}()
+}
+
+// formatJSON indents the JSON and redacts secrets
+func formatJSON(data []byte) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(redact(v))
+	return strings.TrimSpace(out.String()), err
+}
+
+// redact replaces the values of secret keys, and secret query parameters in URLs
+func redact(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, field := range value {
+			if isSecret(k) {
+				value[k] = redacted
+			} else {
+				value[k] = redact(field)
+			}
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = redact(value[i])
+		}
+	case string:
+		return redactURL(value)
+	}
+	return v
+}
+
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") || !strings.Contains(s, "?") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	query := u.Query()
+	changed := false
+	for k := range query {
+		if isSecret(k) {
+			query.Set(k, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightJSON colors keys, strings and other values of indented JSON with tview color tags
+func highlightJSON(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				end = len(text) - 1
+			}
+			str := text[i : end+1]
+			color := "green"
+			if strings.HasPrefix(strings.TrimLeft(text[end+1:], " "), ":") {
+				color = "aqua"
+			}
+			out.WriteString("[" + color + "]" + tview.Escape(str) + "[-]")
+			i = end
+		case c == '-' || c >= '0' && c <= '9' || c == 't' || c == 'f' || c == 'n':
+			end := i
+			for end < len(text) && !strings.ContainsRune(",\n]} ", rune(text[end])) {
+				end++
+			}
+			out.WriteString("[yellow]" + text[i:end] + "[-]")
+			i = end - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatJSON(t *testing.T) {
+	t.Parallel()
+	text, err := formatJSON([]byte(`{"uid": "ep_1", "session_token": "abc", "items": [{"url": "https://example.com/a.m3u8?Policy=x&Signature=y&lang=en"}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "items": [
+    {
+      "url": "https://example.com/a.m3u8?Policy=REDACTED&Signature=REDACTED&lang=en"
+    }
+  ],
+  "session_token": "REDACTED",
+  "uid": "ep_1"
+}`, text)
+
+	_, err = formatJSON([]byte(`{"uid":`))
+	assert.Error(t, err)
+}
+
+func TestHighlightJSON(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, `{[aqua]"name"[-]: [green]"[red[]"[-], [aqua]"year"[-]: [yellow]2020[-], [aqua]"live"[-]: [yellow]false[-]}`,
+		highlightJSON(`{"name": "[red]", "year": 2020, "live": false}`))
+}
+
+func TestAPIResource(t *testing.T) {
+	t.Parallel()
+	collection, uid, ok := apiResource(episode{UID: "ep_1"})
+	assert.True(t, ok)
+	assert.Equal(t, "episodes", collection)
+	assert.Equal(t, "ep_1", uid)
+
+	_, _, ok = apiResource("text")
+	assert.False(t, ok)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// events first seen within this duration are shown as recently added
+const recentEventsAge = 7 * 24 * time.Hour
+
+// knownEvents holds the time each event was first seen, keyed by season and event ID
+type knownEvents map[string]map[string]time.Time
+
+func getKnownEventsPath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return path + "known_events.json", nil
+}
+
+// loadKnownEvents reads the known events, the returned bool is false if no events were saved yet.
+// If the file is invalid, no events are returned together with the error.
+func (session *viewerSession) loadKnownEvents() (knownEvents, bool, error) {
+	known := make(knownEvents)
+	path, err := getKnownEventsPath()
+	if err != nil {
+		return known, false, err
+	}
+	err = session.readCache(path, &known)
+	if os.IsNotExist(err) {
+		return known, false, nil
+	} else if err != nil {
+		return make(knownEvents), false, err
+	}
+	return known, true, nil
+}
+
+// saveKnownEvents writes the known events
+func (session *viewerSession) saveKnownEvents(k knownEvents) error {
+	path, err := getKnownEventsPath()
+	if err != nil {
+		return err
+	}
+	return session.writeCache(path, k)
+}
+
+func (k knownEvents) contains(seasonID, eventID string) bool {
+	_, ok := k[seasonID][eventID]
+	return ok
+}
+
+// add marks an event as seen at the given time
+func (k knownEvents) add(seasonID, eventID string, seen time.Time) {
+	if k[seasonID] == nil {
+		k[seasonID] = make(map[string]time.Time)
+	}
+	k[seasonID][eventID] = seen
+}
+
+// recent returns the IDs of the season's events that were first seen after the given time
+func (k knownEvents) recent(seasonID string, after time.Time) []string {
+	var ids []string
+	for id, seen := range k[seasonID] {
+		if seen.After(after) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// checkNewEvents adds a node with the events that were uploaded recently.
+// When it runs for the first time all existing events are saved without showing them.
+// Events are only requested when the node is opened, at startup only the seasons are loaded.
+func (session *viewerSession) checkNewEvents() {
+	s, err := session.getSeasons()
+	if err != nil {
+		session.logError("could not check for new events: ", err)
+		return
+	}
+	known, existed, err := session.loadKnownEvents()
+	if errors.Is(err, errInvalidCache) {
+		session.logWarn(err, ", starting over")
+	} else if err != nil {
+		session.logError("could not read known events: ", err)
+		return
+	}
+
+	var seasons []seasonStruct
+	var pending bool
+	for _, season := range s.Seasons {
+		if !season.HasContent {
+			continue
+		}
+		seasons = append(seasons, season)
+		for _, eventID := range season.EventoccurrenceUrls {
+			if known.contains(season.UID, eventID) {
+				continue
+			}
+			if !existed {
+				known.add(season.UID, eventID, time.Time{})
+				continue
+			}
+			pending = true
+		}
+		for _, eventID := range known.recent(season.UID, time.Now().Add(-recentEventsAge)) {
+			session.markIcon(iconNew, eventID)
+			pending = true
+		}
+	}
+	if !existed {
+		if err := session.saveKnownEvents(known); err != nil {
+			session.logError("could not save known events: ", err)
+		}
+		return
+	}
+	if !pending {
+		return
+	}
+
+	recentNode := tview.NewTreeNode("Recently Added").
+		SetColor(session.theme.UpdateColor).
+		SetExpanded(false).
+		SetReference(&NodeMetadata{nodeType: CategoryNode})
+	session.lazyLoad(recentNode, func() ([]*tview.TreeNode, error) {
+		return session.getNewEventNodes(seasons)
+	})
+	insertNodeAtTop(session.tree.GetRoot(), recentNode)
+	session.styleCategory(recentNode)
+	session.draw()
+}
+
+// getNewEventNodes saves the events that weren't seen before and returns nodes for the ones that were uploaded recently
+func (session *viewerSession) getNewEventNodes(seasons []seasonStruct) ([]*tview.TreeNode, error) {
+	known, _, err := session.loadKnownEvents()
+	if err != nil && !errors.Is(err, errInvalidCache) {
+		return nil, err
+	}
+	now := time.Now()
+	var nodes []*tview.TreeNode
+	for _, season := range seasons {
+		for _, eventID := range season.EventoccurrenceUrls {
+			if known.contains(season.UID, eventID) {
+				continue
+			}
+			// events without sessions aren't saved, so they are shown once content is uploaded
+			event, err := session.getEvent(eventID)
+			if err != nil {
+				session.logError("could not check for new events: ", err)
+				continue
+			}
+			if len(event.SessionoccurrenceUrls) > 0 {
+				known.add(season.UID, eventID, now)
+			}
+		}
+		for _, eventID := range known.recent(season.UID, now.Add(-recentEventsAge)) {
+			session.markIcon(iconNew, eventID)
+			node, err := session.getEventNode(eventID, season.Name)
+			if err == errNoSessions || err == errNoEventLabel {
+				continue
+			}
+			if err != nil {
+				session.logError("could not load new event: ", err)
+				continue
+			}
+			nodes = append(nodes, node.SetColor(session.theme.UpdateColor))
+		}
+	}
+	if err := session.saveKnownEvents(known); err != nil {
+		session.logError("could not save known events: ", err)
+	}
+	return nodes, nil
+}
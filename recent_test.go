@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnownEvents(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	known := make(knownEvents)
+	known.add("season_2019", "event_old", time.Time{})
+	known.add("season_2019", "event_week", now.Add(-3*24*time.Hour))
+	known.add("season_2019", "event_new", now)
+	known.add("season_2020", "event_other", now)
+
+	assert.True(t, known.contains("season_2019", "event_old"))
+	assert.False(t, known.contains("season_2019", "event_other"))
+	assert.False(t, known.contains("season_2018", "event_old"))
+
+	assert.Equal(t, []string{"event_new", "event_week"}, known.recent("season_2019", now.Add(-recentEventsAge)))
+	assert.Empty(t, known.recent("season_2018", now.Add(-recentEventsAge)))
+}
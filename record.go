@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a restart only counts as a failure if the recording stopped this soon after it started
+const recordingRestartWindow = time.Minute
+
+// liveURL keeps the stream URL of a live recording fresh, so restarts don't use an expired URL
+type liveURL struct {
+	lock    sync.Mutex
+	url     string
+	resolve func() (string, error)
+}
+
+func (l *liveURL) get() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.url
+}
+
+// refresh resolves the URL again, the old one is kept if that fails
+func (l *liveURL) refresh() error {
+	u, err := l.resolve()
+	if err != nil {
+		return err
+	}
+	l.lock.Lock()
+	l.url = u
+	l.lock.Unlock()
+	return nil
+}
+
+// refreshEvery refreshes the URL until done is closed
+func (l *liveURL) refreshEvery(interval time.Duration, done <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := l.refresh(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// partFile returns where part of a live recording is saved and if it's appended to the file.
+// Transport streams can be appended to, other containers are saved as numbered parts next to the file.
+func partFile(file string, part int) (string, bool) {
+	ext := filepath.Ext(file)
+	if part == 1 || strings.EqualFold(ext, ".ts") {
+		return file, part > 1
+	}
+	return fmt.Sprintf("%s (part %d)%s", strings.TrimSuffix(file, ext), part, ext), false
+}
+
+// recordLive records a live stream with ffmpeg. If ffmpeg fails, eg. because the URL expired,
+// it's restarted with a fresh URL up to live_recording_retries times in a row.
+func (session *viewerSession) recordLive(proc *runningProcess, epID, streamURL, file string, program int) error {
+	current := &liveURL{url: streamURL, resolve: func() (string, error) {
+		return session.freshURL(epID)
+	}}
+	done := make(chan struct{})
+	defer close(done)
+	if interval := time.Duration(session.cfg.LiveURLRefresh) * time.Minute; interval > 0 {
+		go current.refreshEvery(interval, done, func(err error) {
+			session.logWarn("could not refresh the URL of ", proc.title, ": ", err)
+		})
+	}
+
+	failures := 0
+	for part := 1; ; part++ {
+		started := time.Now()
+		output, appending := partFile(file, part)
+		err := session.runRecording(proc, current.get(), output, program, appending)
+		if err == nil || session.wasStopped(proc) {
+			return err
+		}
+		if time.Since(started) < recordingRestartWindow {
+			failures++
+		} else {
+			failures = 1
+		}
+		if failures > session.cfg.LiveRecordingRetries {
+			return err
+		}
+		if err := current.refresh(); err != nil {
+			session.logWarn("could not refresh the URL of ", proc.title, ": ", err)
+		}
+		next, appending := partFile(file, part+1)
+		if appending {
+			session.logWarn("recording ", proc.title, " stopped (", err, "), restarting with a fresh URL")
+		} else {
+			session.logWarn("recording ", proc.title, " stopped (", err, "), continuing in ", next)
+		}
+	}
+}
+
+// runRecording runs ffmpeg once. When appending, ffmpeg writes a transport stream to stdout that is added to the end of the file.
+func (session *viewerSession) runRecording(proc *runningProcess, streamURL, file string, program int, appending bool) error {
+	proxy, err := session.ffmpegProxyURL()
+	if err != nil {
+		return fmt.Errorf("could not start the download proxy: %w", err)
+	}
+	var cmd *exec.Cmd
+	if appending {
+		out, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		args := ffmpegArgs(streamURL, "pipe:1", program)
+		args = append(args[:len(args)-1], "-f", "mpegts", "pipe:1")
+		args = withProxy(args, proxy)
+		cmd = exec.Command(args[0], args[1:]...)
+		cmd.Stdout = out
+	} else {
+		args := withProxy(ffmpegArgs(streamURL, file, program), proxy)
+		cmd = exec.Command(args[0], args[1:]...)
+	}
+	if err := session.startCmd(cmd); err != nil {
+		return err
+	}
+	session.attachCmd(proc, cmd)
+	return cmd.Wait()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartFile(t *testing.T) {
+	t.Parallel()
+
+	file, appending := partFile(filepath.Join("dl", "Race.mkv"), 1)
+	assert.Equal(t, filepath.Join("dl", "Race.mkv"), file)
+	assert.False(t, appending)
+
+	file, appending = partFile(filepath.Join("dl", "Race.mkv"), 3)
+	assert.Equal(t, filepath.Join("dl", "Race (part 3).mkv"), file)
+	assert.False(t, appending)
+
+	file, appending = partFile(filepath.Join("dl", "Race.ts"), 2)
+	assert.Equal(t, filepath.Join("dl", "Race.ts"), file)
+	assert.True(t, appending)
+}
+
+func TestLiveURL(t *testing.T) {
+	t.Parallel()
+
+	urls := []string{"https://example.com/2.m3u8", ""}
+	errs := []error{nil, errors.New("token expired")}
+	calls := 0
+	l := &liveURL{url: "https://example.com/1.m3u8", resolve: func() (string, error) {
+		calls++
+		return urls[calls-1], errs[calls-1]
+	}}
+
+	assert.NoError(t, l.refresh())
+	assert.Equal(t, "https://example.com/2.m3u8", l.get())
+	assert.Error(t, l.refresh())
+	assert.Equal(t, "https://example.com/2.m3u8", l.get())
+}
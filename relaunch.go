@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// how often the playback position of MPV is requested
+const positionInterval = 5 * time.Second
+
+// a relaunched player starts this much before the last known position, so nothing is missed
+const relaunchRewind = 10 * time.Second
+
+// counter for unique IPC server names
+var ipcCounter int32
+
+// isMPV checks if the program of a command is MPV
+func isMPV(program string) bool {
+	name := strings.ToLower(filepath.Base(program))
+	return name == "mpv" || name == "mpv.exe" || name == "mpv.com"
+}
+
+// newIPCPath returns a path for the IPC server of a new MPV process
+func newIPCPath() string {
+	return ipcPath(fmt.Sprintf("f1viewer-mpv-%d-%d", os.Getpid(), atomic.AddInt32(&ipcCounter, 1)))
+}
+
+// positionTracker remembers the last playback position MPV reported over its IPC server
+type positionTracker struct {
+	lock sync.Mutex
+	pos  time.Duration
+	// called with every position MPV reports and whether it is paused, if set
+	onUpdate func(pos time.Duration, paused bool)
+}
+
+func (p *positionTracker) position() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.pos
+}
+
+// poll requests the position until done is closed. Connection errors are ignored,
+// MPV needs a moment to create the IPC server and the position is only used once it exits.
+func (p *positionTracker) poll(path string, done <-chan struct{}) {
+	ticker := time.NewTicker(positionInterval)
+	defer ticker.Stop()
+	var conn io.ReadWriteCloser
+	var reader *bufio.Reader
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for requestID := 1; ; requestID += 2 {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if conn == nil {
+			c, err := dialIPC(path)
+			if err != nil {
+				continue
+			}
+			conn, reader = c, bufio.NewReader(c)
+		}
+		pos, err := requestPosition(conn, reader, requestID)
+		var paused bool
+		if err == nil {
+			err = requestProperty(conn, reader, requestID+1, "pause", &paused)
+		}
+		if err != nil {
+			conn.Close()
+			conn = nil
+			continue
+		}
+		p.lock.Lock()
+		p.pos = pos
+		p.lock.Unlock()
+		if p.onUpdate != nil {
+			p.onUpdate(pos, paused)
+		}
+	}
+}
+
+// requestPosition asks MPV for the time-pos property
+func requestPosition(w io.Writer, r *bufio.Reader, requestID int) (time.Duration, error) {
+	var seconds float64
+	if err := requestProperty(w, r, requestID, "time-pos", &seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// requestProperty asks MPV for a property and reads lines until the response to the request arrives, the value is decoded into v
+func requestProperty(w io.Writer, r *bufio.Reader, requestID int, property string, v interface{}) error {
+	request := fmt.Sprintf(`{"command": ["get_property", %q], "request_id": %d}`+"\n", property, requestID)
+	if _, err := w.Write([]byte(request)); err != nil {
+		return err
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		data, ok, err := parsePropertyResponse(line, requestID)
+		if err != nil {
+			return fmt.Errorf("could not get %s: %w", property, err)
+		}
+		if ok {
+			return json.Unmarshal(data, v)
+		}
+	}
+}
+
+// parsePropertyResponse returns the data of MPV's response to a get_property request.
+// It returns false for other messages like events and responses to other requests.
+func parsePropertyResponse(line []byte, requestID int) (json.RawMessage, bool, error) {
+	var response struct {
+		Data      json.RawMessage `json:"data"`
+		Error     string          `json:"error"`
+		RequestID int             `json:"request_id"`
+	}
+	if err := json.Unmarshal(line, &response); err != nil {
+		return nil, false, err
+	}
+	if response.Error == "" || response.RequestID != requestID {
+		return nil, false, nil
+	}
+	if response.Error != "success" {
+		return nil, true, errors.New(response.Error)
+	}
+	if len(response.Data) == 0 || string(response.Data) == "null" {
+		return nil, true, errors.New("no value")
+	}
+	return response.Data, true, nil
+}
+
+// relaunchPosition returns where a relaunched player starts, a bit before the last known position
+func relaunchPosition(pos time.Duration) time.Duration {
+	if pos <= relaunchRewind {
+		return 0
+	}
+	return (pos - relaunchRewind).Truncate(time.Second)
+}
+
+// formatPosition formats a playback position as h:mm:ss
+func formatPosition(pos time.Duration) string {
+	seconds := int(pos.Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// playerCrashed relaunches MPV after it exited unexpectedly, at the position it stopped at.
+// It's relaunched automatically up to player_relaunch times, after that a dialog asks first.
+func (session *viewerSession) playerCrashed(cc commandContext, err error, pos time.Duration) {
+	cc.Start = relaunchPosition(pos)
+	where := "from the beginning"
+	if cc.Titles.Live {
+		where = "at the live edge"
+	} else if cc.Start > 0 {
+		where = "at " + formatPosition(cc.Start)
+	}
+	if cc.Relaunches < session.cfg.PlayerRelaunch {
+		session.logWarn(fmt.Sprintf("%s exited unexpectedly (%v), relaunching %s (%d of %d)", cc.CustomOptions.Title, err, where, cc.Relaunches+1, session.cfg.PlayerRelaunch))
+		go session.relaunchPlayer(cc)
+		return
+	}
+	session.logWarn(cc.CustomOptions.Title, " exited unexpectedly: ", err)
+	session.app.QueueUpdateDraw(func() {
+		text := fmt.Sprintf("%s exited unexpectedly while playing %s.\n\nRelaunch %s?", cc.CustomOptions.Title, cc.Titles.String(), where)
+		session.showModal(text, []string{"Relaunch", "Cancel"}, func(label string) {
+			session.app.SetFocus(session.tree)
+			if label == "Relaunch" {
+				go session.relaunchPlayer(cc)
+			}
+		})
+	})
+}
+
+func (session *viewerSession) relaunchPlayer(cc commandContext) {
+	cc.Relaunches++
+	if err := session.runCustomCommand(cc); err != nil {
+		session.logError("could not relaunch ", cc.CustomOptions.Title, ": ", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePropertyResponse(t *testing.T) {
+	t.Parallel()
+	data, ok, err := parsePropertyResponse([]byte(`{"data": 125.5, "error": "success", "request_id": 3}`), 3)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "125.5", string(data))
+
+	// events and responses to other requests are skipped
+	_, ok, err = parsePropertyResponse([]byte(`{"event": "playback-restart"}`), 3)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, _ = parsePropertyResponse([]byte(`{"data": 1, "error": "success", "request_id": 2}`), 3)
+	assert.False(t, ok)
+
+	_, ok, err = parsePropertyResponse([]byte(`{"error": "property unavailable", "request_id": 3}`), 3)
+	assert.True(t, ok)
+	assert.Error(t, err)
+	_, ok, err = parsePropertyResponse([]byte(`{"data": null, "error": "success", "request_id": 3}`), 3)
+	assert.True(t, ok)
+	assert.Error(t, err)
+}
+
+func TestRequestPosition(t *testing.T) {
+	t.Parallel()
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		if _, err := r.ReadBytes('\n'); err != nil {
+			return
+		}
+		server.Write([]byte("{\"event\": \"pause\"}\n{\"data\": 61.0, \"error\": \"success\", \"request_id\": 7}\n"))
+	}()
+	pos, err := requestPosition(client, bufio.NewReader(client), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 61*time.Second, pos)
+}
+
+func TestRequestPaused(t *testing.T) {
+	t.Parallel()
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		if _, err := r.ReadBytes('\n'); err != nil {
+			return
+		}
+		server.Write([]byte("{\"data\": true, \"error\": \"success\", \"request_id\": 8}\n"))
+	}()
+	var paused bool
+	assert.NoError(t, requestProperty(client, bufio.NewReader(client), 8, "pause", &paused))
+	assert.True(t, paused)
+}
+
+func TestRelaunchPosition(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, time.Duration(0), relaunchPosition(5*time.Second))
+	assert.Equal(t, 50*time.Second, relaunchPosition(60500*time.Millisecond))
+	assert.Equal(t, "1:02:03", formatPosition(time.Hour+2*time.Minute+3*time.Second))
+	assert.Equal(t, "0:00:50", formatPosition(50*time.Second))
+
+	assert.True(t, isMPV("mpv"))
+	assert.True(t, isMPV("/usr/bin/mpv"))
+	assert.True(t, isMPV("MPV.exe"))
+	assert.False(t, isMPV("vlc"))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// time url_resolver_command has to print the URL
+const resolverTimeout = 30 * time.Second
+
+// playableURL returns the stream URL of the content, from a session bundle if it has one that didn't expire yet,
+// otherwise from url_resolver_command if it's set
+func (session *viewerSession) playableURL(contentID string) (string, error) {
+	if u, ok := session.savedURL(contentID, time.Now()); ok {
+		return u, nil
+	}
+	return session.freshURL(contentID)
+}
+
+// freshURL resolves the stream URL of the content, even if a session bundle has one
+func (session *viewerSession) freshURL(contentID string) (string, error) {
+	if len(session.cfg.URLResolverCommand) > 0 {
+		return resolveURL(session.cfg.URLResolverCommand, contentID, session.authtoken)
+	}
+	return session.getPlayableURL(contentID, session.authtoken)
+}
+
+// resolverArgs replaces $id and $token in the command, the ID is added as the last argument if $id isn't used
+func resolverArgs(command []string, contentID, token string) []string {
+	args := make([]string, len(command))
+	hasID := false
+	for i, arg := range command {
+		if strings.Contains(arg, "$id") {
+			hasID = true
+		}
+		args[i] = strings.NewReplacer("$id", contentID, "$token", token).Replace(arg)
+	}
+	if !hasID {
+		args = append(args, contentID)
+	}
+	return args
+}
+
+// resolveURL runs the resolver command and returns the URL it printed
+func resolveURL(command []string, contentID, token string) (string, error) {
+	args := resolverArgs(command, contentID, token)
+	ctx, cancel := context.WithTimeout(context.Background(), resolverTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("url_resolver_command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("url_resolver_command failed: %w", err)
+	}
+	return parseResolvedURL(stdout.String())
+}
+
+// parseResolvedURL returns the first line of the output if it's an http(s) URL
+func parseResolvedURL(output string) (string, error) {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(output), "\n", 2)[0])
+	if line == "" {
+		return "", errors.New("url_resolver_command printed no URL")
+	}
+	u, err := url.Parse(line)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("url_resolver_command printed '%s', which is not an http(s) URL", truncate(line, 80))
+	}
+	return line, nil
+}
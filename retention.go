@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// files changed more recently than this are never deleted, they may still be downloading
+const retentionGrace = 10 * time.Minute
+
+// only downloads are deleted, other files in the download location are left alone
+var mediaExtensions = []string{".ts", ".mp4", ".mkv", ".mov", ".m4v", ".webm", ".avi", ".flv"}
+
+// metadata files saved next to downloads, they are deleted together with the download
+var sidecarExtensions = []string{".json", ".nfo"}
+
+type downloadedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// listDownloads returns the media files directly in the directory, with the size of their metadata files added
+func listDownloads(dir string) ([]downloadedFile, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []downloadedFile
+	for _, info := range infos {
+		if info.IsDir() || !containsFold(mediaExtensions, filepath.Ext(info.Name())) {
+			continue
+		}
+		f := downloadedFile{path: filepath.Join(dir, info.Name()), size: info.Size(), modTime: info.ModTime()}
+		for _, sidecar := range sidecarFiles(f.path) {
+			if s, err := os.Stat(sidecar); err == nil {
+				f.size += s.Size()
+			}
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func sidecarFiles(file string) []string {
+	base := strings.TrimSuffix(file, filepath.Ext(file))
+	paths := make([]string, len(sidecarExtensions))
+	for i, ext := range sidecarExtensions {
+		paths[i] = base + ext
+	}
+	return paths
+}
+
+// planRetention returns the files to delete, oldest first: everything older than maxAge,
+// then the oldest files until the rest fits in maxSize. A zero limit isn't applied.
+func planRetention(files []downloadedFile, maxAge time.Duration, maxSize int64, now time.Time) []downloadedFile {
+	sorted := append([]downloadedFile(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].modTime.Before(sorted[j].modTime)
+	})
+	var total int64
+	for _, f := range sorted {
+		total += f.size
+	}
+	var remove []downloadedFile
+	for _, f := range sorted {
+		if now.Sub(f.modTime) < retentionGrace {
+			break
+		}
+		tooOld := maxAge > 0 && now.Sub(f.modTime) > maxAge
+		tooBig := maxSize > 0 && total > maxSize
+		if !tooOld && !tooBig {
+			break
+		}
+		remove = append(remove, f)
+		total -= f.size
+	}
+	return remove
+}
+
+// formatSize formats a number of bytes, eg. 1.5 GB
+func formatSize(bytes int64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "kMGT"[exp])
+}
+
+// checkRetention asks to delete the downloads that exceed retention_days or retention_gb.
+// On startup nothing is logged if there is nothing to delete.
+func (session *viewerSession) checkRetention(startup bool) {
+	if session.cfg.RetentionDays == 0 && session.cfg.RetentionGB == 0 {
+		if !startup {
+			session.logInfo("set retention_days or retention_gb in the config to clean up the download location")
+		}
+		return
+	}
+	if session.cfg.DownloadLocation == "" {
+		session.logWarn("retention needs a download_location, downloads in the working directory are never deleted")
+		return
+	}
+	files, err := listDownloads(session.cfg.DownloadLocation)
+	if err != nil {
+		session.logError("could not check the download location: ", err)
+		return
+	}
+	maxAge := time.Duration(session.cfg.RetentionDays) * 24 * time.Hour
+	maxSize := int64(session.cfg.RetentionGB * 1e9)
+	remove := planRetention(files, maxAge, maxSize, time.Now())
+	if len(remove) == 0 {
+		if !startup {
+			session.logInfo("no downloads exceed the retention limits")
+		}
+		return
+	}
+
+	var size int64
+	names := make([]string, len(remove))
+	for i, f := range remove {
+		size += f.size
+		names[i] = filepath.Base(f.path)
+	}
+	text := fmt.Sprintf("Delete %d old download(s) (%s) from %s?\n\n%s", len(remove), formatSize(size), session.cfg.DownloadLocation, strings.Join(names, "\n"))
+	session.app.QueueUpdateDraw(func() {
+		session.showModal(text, []string{"Delete", "Keep"}, func(label string) {
+			session.app.SetFocus(session.tree)
+			if label == "Delete" {
+				go session.deleteDownloads(remove)
+			}
+		})
+	})
+}
+
+// deleteDownloads deletes the files and their metadata files and logs what was removed
+func (session *viewerSession) deleteDownloads(files []downloadedFile) {
+	for _, f := range files {
+		if err := os.Remove(f.path); err != nil {
+			session.logError("could not delete ", f.path, ": ", err)
+			continue
+		}
+		for _, sidecar := range sidecarFiles(f.path) {
+			if err := os.Remove(sidecar); err != nil && !os.IsNotExist(err) {
+				session.logWarn("could not delete ", sidecar, ": ", err)
+			}
+		}
+		session.logInfo(fmt.Sprintf("deleted %s (%s, from %s)", f.path, formatSize(f.size), f.modTime.Format("2006-01-02")))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanRetention(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, 7, 5, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	files := []downloadedFile{
+		{path: "new.mkv", size: 400, modTime: now.Add(-day)},
+		{path: "old.mkv", size: 300, modTime: now.Add(-10 * day)},
+		{path: "older.mkv", size: 200, modTime: now.Add(-20 * day)},
+		{path: "recording.ts", size: 1000, modTime: now.Add(-time.Minute)},
+	}
+	paths := func(files []downloadedFile) []string {
+		var p []string
+		for _, f := range files {
+			p = append(p, f.path)
+		}
+		return p
+	}
+
+	assert.Empty(t, planRetention(files, 0, 0, now))
+	assert.Equal(t, []string{"older.mkv", "old.mkv"}, paths(planRetention(files, 5*day, 0, now)))
+	assert.Equal(t, []string{"older.mkv"}, paths(planRetention(files, 0, 1700, now)))
+	// files that may still be downloading are kept
+	assert.Equal(t, []string{"older.mkv", "old.mkv", "new.mkv"}, paths(planRetention(files, 0, 100, now)))
+}
+
+func TestListDownloads(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer-retention")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for name, size := range map[string]int{"Race.mkv": 10, "Race.nfo": 5, "notes.txt": 3, "Quali.ts": 7} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644))
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "old.mkv"), 0755))
+
+	files, err := listDownloads(dir)
+	assert.NoError(t, err)
+	sizes := make(map[string]int64)
+	for _, f := range files {
+		sizes[filepath.Base(f.path)] = f.size
+	}
+	assert.Equal(t, map[string]int64{"Race.mkv": 15, "Quali.ts": 7}, sizes)
+}
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "999 B", formatSize(999))
+	assert.Equal(t, "1.5 kB", formatSize(1500))
+	assert.Equal(t, "2.3 GB", formatSize(2300000000))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+)
+
+type searchResult struct {
+	event  eventStruct
+	season seasonStruct
+}
+
+// searchEvents fetches the events of all seasons and adds the ones matching the query to a results node.
+// If the query contains the year of a season only that season is searched.
+func (session *viewerSession) searchEvents(query string) {
+	words := strings.Fields(strings.ToLower(query))
+	resultNode := tview.NewTreeNode(fmt.Sprintf("Search: %s (loading seasons)", query)).
+		SetColor(session.theme.CategoryNodeColor).
+		SetReference(&NodeMetadata{nodeType: CategoryNode})
+	session.app.QueueUpdateDraw(func() {
+		root := session.tree.GetRoot()
+		if session.searchNode != nil {
+			removeNode(root, session.searchNode)
+		}
+		session.searchNode = resultNode
+		insertNodeAtTop(root, resultNode)
+		session.styleCategory(resultNode)
+		session.jumpTo(resultNode)
+	})
+
+	s, err := session.getSeasons()
+	if err != nil {
+		session.logError("could not load seasons: ", err)
+		return
+	}
+	candidates := searchSeasons(s.Seasons, words)
+
+	var total int
+	for _, season := range candidates {
+		total += len(season.EventoccurrenceUrls)
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]searchResult)
+	var errs []error
+	searched := 0
+	for _, season := range candidates {
+		for _, eventID := range season.EventoccurrenceUrls {
+			wg.Add(1)
+			go func(season seasonStruct, eventID string) {
+				defer wg.Done()
+				event, err := session.getEvent(eventID)
+
+				lock.Lock()
+				defer lock.Unlock()
+				searched++
+				if err != nil {
+					errs = append(errs, err)
+				} else if len(event.SessionoccurrenceUrls) > 0 && matchesQuery(words, strconv.Itoa(season.Year), season.Name, event.Name, event.OfficialName) {
+					results[eventID] = searchResult{event: event, season: season}
+				}
+				text := fmt.Sprintf("Search: %s (%d/%d events, %d found)", query, searched, total, len(results))
+				session.app.QueueUpdateDraw(func() {
+					resultNode.SetText(text)
+				})
+			}(season, eventID)
+		}
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		session.logWarn(fmt.Sprintf("could not load %d of %d events, the search results may be incomplete: ", len(errs), total), errs[0])
+	}
+	session.logInfo(fmt.Sprintf("found %d events matching '%s'", len(results), query))
+
+	// keep the order of the seasons and events
+	sortedResults := make([]*tview.TreeNode, 0, len(results))
+	for _, season := range candidates {
+		for _, eventID := range season.EventoccurrenceUrls {
+			if r, ok := results[eventID]; ok {
+				node := session.newEventNode(eventID, r.event, r.season.Name)
+				node.SetText(fmt.Sprintf("%d %s", r.season.Year, r.event.label()))
+				sortedResults = append(sortedResults, node)
+			}
+		}
+	}
+
+	session.app.QueueUpdateDraw(func() {
+		resultNode.SetText("Search: " + query)
+		if len(sortedResults) == 0 {
+			resultNode.AddChild(session.nocontentNode())
+			session.styleNodes(resultNode)
+			return
+		}
+		appendNodes(resultNode, sortedResults...)
+		session.styleNodes(resultNode)
+		resultNode.SetExpanded(true)
+		// jump to the first result
+		session.jumpTo(sortedResults[0])
+	})
+}
+
+// searchSeasons returns the seasons with content, limited to the years contained in the query if there are any
+func searchSeasons(seasons []seasonStruct, words []string) []seasonStruct {
+	var all, matching []seasonStruct
+	for _, season := range seasons {
+		if !season.HasContent {
+			continue
+		}
+		all = append(all, season)
+		for _, w := range words {
+			if w == strconv.Itoa(season.Year) {
+				matching = append(matching, season)
+				break
+			}
+		}
+	}
+	if len(matching) > 0 {
+		return matching
+	}
+	return all
+}
+
+// matchesQuery checks if every word of the query is contained in at least one of the fields
+func matchesQuery(words []string, fields ...string) bool {
+	text := strings.ToLower(strings.Join(fields, " "))
+	for _, w := range words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
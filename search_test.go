@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesQuery(t *testing.T) {
+	t.Parallel()
+	words := strings.Fields("monaco 2019")
+	assert.True(t, matchesQuery(words, "2019", "2019 Formula 1 World Championship", "Monaco Grand Prix"))
+	assert.False(t, matchesQuery(words, "2018", "2018 Formula 1 World Championship", "Monaco Grand Prix"))
+	assert.True(t, matchesQuery(nil, "anything"))
+}
+
+func TestSearchSeasons(t *testing.T) {
+	t.Parallel()
+	seasons := []seasonStruct{
+		{Year: 2018, HasContent: true},
+		{Year: 2019, HasContent: true},
+		{Year: 2020, HasContent: false},
+	}
+	assert.Len(t, searchSeasons(seasons, []string{"monaco", "2019"}), 1)
+	assert.Len(t, searchSeasons(seasons, []string{"monaco"}), 2)
+	assert.Len(t, searchSeasons(seasons, []string{"2020"}), 2)
+}
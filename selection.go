@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// shown in front of the label of selected nodes
+const selectedPrefix = "✓ "
+
+// selectedNode is a perspective or episode that was selected for a multi-select action
+type selectedNode struct {
+	node  *tview.TreeNode
+	color tcell.Color
+}
+
+// selectedContent is what the multi-select actions need to know about a selected node
+type selectedContent struct {
+	node   *tview.TreeNode
+	id     string
+	titles Titles
+}
+
+// toggleSelection selects or unselects a perspective or episode node
+func (session *viewerSession) toggleSelection(node *tview.TreeNode) {
+	if node == nil {
+		return
+	}
+	for i, s := range session.selection {
+		if s.node == node {
+			s.unmark()
+			session.selection = append(session.selection[:i], session.selection[i+1:]...)
+			return
+		}
+	}
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok {
+		return
+	}
+	if _, ok := playableID(ref); !ok {
+		session.logInfo("only perspectives and episodes can be selected")
+		return
+	}
+	session.selection = append(session.selection, selectedNode{node: node, color: node.GetColor()})
+	decorate(node, ref.prefix, ref.icons, selectedPrefix)
+	node.SetColor(session.theme.MultiCommandColor)
+}
+
+// unmark removes the selection mark and color from the node
+func (s selectedNode) unmark() {
+	if ref, ok := s.node.GetReference().(*NodeMetadata); ok {
+		decorate(s.node, ref.prefix, ref.icons, "")
+	}
+	s.node.SetColor(s.color)
+}
+
+// clearSelection unselects all nodes
+func (session *viewerSession) clearSelection() {
+	for _, s := range session.selection {
+		s.unmark()
+	}
+	session.selection = nil
+}
+
+// selectedContents returns the IDs and titles of the selected nodes
+func (session *viewerSession) selectedContents() []selectedContent {
+	var contents []selectedContent
+	for _, s := range session.selection {
+		ref, ok := s.node.GetReference().(*NodeMetadata)
+		if !ok {
+			continue
+		}
+		if id, ok := playableID(ref); ok {
+			contents = append(contents, selectedContent{node: s.node, id: id, titles: ref.titles})
+		}
+	}
+	return contents
+}
+
+// selectionAction asks what to do with the selected nodes, the selection is cleared afterwards
+func (session *viewerSession) selectionAction() {
+	contents := session.selectedContents()
+	if len(contents) == 0 {
+		session.logInfo("nothing is selected, select perspectives or episodes with x")
+		return
+	}
+	text := fmt.Sprintf("%d selected", len(contents))
+	session.showModal(text, []string{"Play", "Download", "Cancel"}, func(label string) {
+		session.app.SetFocus(session.tree)
+		switch label {
+		case "Play":
+			go session.playSelected(contents)
+		case "Download":
+			go session.downloadSelected(contents)
+		default:
+			return
+		}
+		session.clearSelection()
+	})
+}
+
+// playSelected plays all contents at once with the default player
+func (session *viewerSession) playSelected(contents []selectedContent) {
+	b := session.startBatch()
+	defer session.endBatch(b)
+	for i, c := range contents {
+		if b.cancelled(session, i, len(contents)) {
+			return
+		}
+		commands := session.playerCommands(c.titles)
+		if len(commands) == 0 {
+			session.logWarn("can't play ", c.titles.String(), ", no player is available")
+			return
+		}
+		cc := commandContext{Titles: c.titles, EpID: c.id, CustomOptions: commands[0], Node: c.node}
+		b.addProcess(playerKey(cc))
+		err := session.runCustomCommand(cc)
+		if err != nil {
+			session.logError(err)
+		}
+	}
+}
+
+// downloadSelected downloads the contents one after another
+func (session *viewerSession) downloadSelected(contents []selectedContent) {
+	b := session.startBatch()
+	defer session.endBatch(b)
+	var failed int
+	for i, c := range contents {
+		if b.cancelled(session, i, len(contents)) {
+			return
+		}
+		b.addProcess(downloadKey(c.id))
+		if err := session.downloadAsset(c.id, c.titles, c.node); err != nil {
+			session.logError("download failed: ", err)
+			failed++
+		}
+	}
+	summary := fmt.Sprintf("finished %d of %d downloads", len(contents)-failed, len(contents))
+	session.logInfo(summary)
+	session.notify(notifyBatch, summary)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelection(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	perspective := tview.NewTreeNode("Pit Lane").
+		SetReference(&NodeMetadata{nodeType: StreamNode, id: "/api/channels/chan_1/", titles: Titles{PerspectiveTitle: "Pit Lane"}})
+	ep := tview.NewTreeNode("Highlights").
+		SetReference(&NodeMetadata{nodeType: PlayableNode, id: "epi_1", metadata: episode{Items: []string{"/api/assets/asse_1/"}}})
+	category := tview.NewTreeNode("Collections").SetReference(&NodeMetadata{nodeType: CategoryNode})
+
+	s.toggleSelection(perspective)
+	s.toggleSelection(ep)
+	s.toggleSelection(category)
+	assert.Equal(t, "✓ Pit Lane", perspective.GetText())
+	assert.Equal(t, "Collections", category.GetText())
+	assert.Equal(t, []selectedContent{
+		{node: perspective, id: "/api/channels/chan_1/", titles: Titles{PerspectiveTitle: "Pit Lane"}},
+		{node: ep, id: "/api/assets/asse_1/"},
+	}, s.selectedContents())
+
+	s.toggleSelection(perspective)
+	assert.Equal(t, "Pit Lane", perspective.GetText())
+	assert.Len(t, s.selectedContents(), 1)
+
+	s.clearSelection()
+	assert.Equal(t, "Highlights", ep.GetText())
+	assert.Empty(t, s.selectedContents())
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// default port of the stream relay started with -serve
+const defaultServePort = 8420
+
+// the relay only listens on the loopback interface by default, it serves the streams of the logged in account without authentication
+const defaultServeAddress = "127.0.0.1"
+
+// matches URI attributes of playlist tags, eg. the audio renditions of #EXT-X-MEDIA
+var playlistURIRegex = regexp.MustCompile(`URI="([^"]*)"`)
+
+// streamRelay serves streams under stable local paths, so devices that can't use the F1TV API can still play them.
+// Requests are redirected to the stream, or if proxy is set the stream itself is passed through the relay.
+type streamRelay struct {
+	session *viewerSession
+	proxy   bool
+
+	tokenLock sync.Mutex
+	token     string
+
+	// hosts the proxy is allowed to fetch from, so the relay can't be used as an open proxy
+	hostLock sync.Mutex
+	hosts    map[string]bool
+}
+
+// serve starts the stream relay and blocks until it fails
+func serve(profile string) error {
+	cfg, err := loadConfig(profile)
+	if err != nil {
+		return fmt.Errorf("Could not open config: %w", err)
+	}
+
+	session := &viewerSession{cfg: cfg}
+	session.configureAPI()
+	if err := session.openRing(); err != nil {
+		return fmt.Errorf("Could not access credential store: %w", err)
+	}
+	if err := session.loadCredentials(); err != nil {
+		return fmt.Errorf("%w, log in once without -serve to save your credentials", err)
+	}
+
+	port := cfg.ServePort
+	if port == 0 {
+		port = defaultServePort
+	}
+	address := cfg.ServeAddress
+	if address == "" {
+		address = defaultServeAddress
+	}
+	relay := &streamRelay{session: session, proxy: cfg.ServeProxy, hosts: make(map[string]bool)}
+	log.Printf("serving streams on %s port %d, eg. http://localhost:%d/session/<session UID>", address, port, port)
+	return http.ListenAndServe(net.JoinHostPort(address, strconv.Itoa(port)), relay)
+}
+
+// ServeHTTP handles the paths
+//
+//	/session/<session UID>               the main feed of a session
+//	/session/<session UID>/<perspective> a perspective of a session, matched like in multi commands
+//	/asset/<asset UID>                   an episode or other asset
+//	/proxy?url=<url>                     a playlist or segment passed through the relay
+func (r *streamRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case parts[0] == "proxy" && len(parts) == 1:
+		r.proxyStream(w, req.URL.Query().Get("url"))
+		return
+	case parts[0] == "session" && (len(parts) == 2 || len(parts) == 3):
+		perspective := ""
+		if len(parts) == 3 {
+			perspective = parts[2]
+		}
+		contentID, err := r.findStream(parts[1], perspective)
+		if err != nil {
+			log.Println("[ERROR]", err)
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		r.play(w, req, contentID)
+	case parts[0] == "asset" && len(parts) == 2:
+		r.play(w, req, "/api/assets/"+parts[1]+"/")
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// findStream returns the content ID of a perspective of the session, or of the main feed if perspective is empty
+func (r *streamRelay) findStream(sessionID, perspective string) (string, error) {
+	streams, err := r.session.getSessionStreams(sessionID)
+	if err != nil {
+		return "", err
+	}
+	stream, err := findSessionStream(sessionID, streams, perspective, r.session.cfg.PerspectiveLabels)
+	return stream.Self, err
+}
+
+// play redirects to the playable URL of the content, or passes it through the proxy
+func (r *streamRelay) play(w http.ResponseWriter, req *http.Request, contentID string) {
+	streamURL, err := r.playableURL(contentID)
+	if err != nil {
+		log.Println("[ERROR]", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Println("[INFO] playing", contentID, "for", req.RemoteAddr)
+	if r.proxy {
+		r.allowHost(streamURL)
+		http.Redirect(w, req, proxyPath(streamURL), http.StatusFound)
+		return
+	}
+	http.Redirect(w, req, streamURL, http.StatusFound)
+}
+
+// playableURL resolves the content ID, the relay logs in again once if the token expired
+func (r *streamRelay) playableURL(contentID string) (string, error) {
+	r.tokenLock.Lock()
+	defer r.tokenLock.Unlock()
+
+	if cmd := r.session.cfg.URLResolverCommand; len(cmd) > 0 {
+		return resolveURL(cmd, contentID, r.token)
+	}
+	if r.token != "" {
+		streamURL, err := r.session.getPlayableURL(contentID, r.token)
+		if !isAuthError(err) {
+			return streamURL, err
+		}
+	}
+	token, err := r.session.login()
+	if err != nil {
+		return "", err
+	}
+	r.token = token
+	return r.session.getPlayableURL(contentID, r.token)
+}
+
+func (r *streamRelay) allowHost(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	r.hostLock.Lock()
+	r.hosts[u.Host] = true
+	r.hostLock.Unlock()
+}
+
+func (r *streamRelay) hostAllowed(u *url.URL) bool {
+	r.hostLock.Lock()
+	defer r.hostLock.Unlock()
+	return r.hosts[u.Host]
+}
+
+// proxyStream fetches the target and passes it on, URLs in playlists are rewritten to go through the proxy as well
+func (r *streamRelay) proxyStream(w http.ResponseWriter, target string) {
+	u, err := url.Parse(target)
+	if err != nil || !u.IsAbs() || !r.hostAllowed(u) {
+		http.Error(w, "invalid proxy URL", http.StatusBadRequest)
+		return
+	}
+	resp, err := r.session.streamClient.Get(target)
+	if err != nil {
+		log.Println("[ERROR]", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if !isPlaylist(u, resp.Header.Get("Content-Type")) {
+		w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	playlist, err := rewritePlaylist(data, u, r.allowHost)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(playlist)
+}
+
+func isPlaylist(u *url.URL, contentType string) bool {
+	return strings.HasSuffix(u.Path, ".m3u8") || strings.Contains(strings.ToLower(contentType), "mpegurl")
+}
+
+func proxyPath(target string) string {
+	return "/proxy?url=" + url.QueryEscape(target)
+}
+
+// rewritePlaylist replaces every URL in an HLS playlist with its proxy path.
+// Relative URLs are resolved against base, allow is called with every resulting URL.
+func rewritePlaylist(data []byte, base *url.URL, allow func(string)) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("#EXTM3U")) {
+		return nil, errors.New("not a playlist")
+	}
+	var out bytes.Buffer
+	var resolveErr error
+	resolve := func(ref string) string {
+		u, err := base.Parse(ref)
+		if err != nil {
+			resolveErr = err
+			return ref
+		}
+		allow(u.String())
+		return proxyPath(u.String())
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			line = playlistURIRegex.ReplaceAllStringFunc(line, func(attr string) string {
+				return `URI="` + resolve(playlistURIRegex.FindStringSubmatch(attr)[1]) + `"`
+			})
+		default:
+			line = resolve(line)
+		}
+		out.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if resolveErr != nil {
+		return nil, fmt.Errorf("invalid URL in playlist: %w", resolveErr)
+	}
+	return out.Bytes(), nil
+}
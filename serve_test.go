@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewritePlaylist(t *testing.T) {
+	t.Parallel()
+	base, _ := url.Parse("https://cdn.example.com/stream/master.m3u8?token=abc")
+	playlist := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",URI="audio/en.m3u8"
+
+#EXT-X-STREAM-INF:BANDWIDTH=1000000
+video/720.m3u8
+https://other.example.com/1080.m3u8
+`
+	var allowed []string
+	rewritten, err := rewritePlaylist([]byte(playlist), base, func(u string) { allowed = append(allowed, u) })
+	assert.NoError(t, err)
+	assert.Equal(t, `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",URI="/proxy?url=https%3A%2F%2Fcdn.example.com%2Fstream%2Faudio%2Fen.m3u8"
+
+#EXT-X-STREAM-INF:BANDWIDTH=1000000
+/proxy?url=https%3A%2F%2Fcdn.example.com%2Fstream%2Fvideo%2F720.m3u8
+/proxy?url=https%3A%2F%2Fother.example.com%2F1080.m3u8
+`, string(rewritten))
+	assert.Equal(t, []string{
+		"https://cdn.example.com/stream/audio/en.m3u8",
+		"https://cdn.example.com/stream/video/720.m3u8",
+		"https://other.example.com/1080.m3u8",
+	}, allowed)
+
+	_, err = rewritePlaylist([]byte("<html></html>"), base, func(string) {})
+	assert.Error(t, err)
+}
+
+func TestIsAuthError(t *testing.T) {
+	t.Parallel()
+	assert.True(t, isAuthError(statusError{code: http.StatusUnauthorized}))
+	assert.True(t, isAuthError(fmt.Errorf("could not play: %w", statusError{code: http.StatusForbidden})))
+	// only an expired token is fixed by logging in again
+	assert.False(t, isAuthError(statusError{code: http.StatusNotFound}))
+	assert.False(t, isAuthError(errors.New("connection refused")))
+	assert.False(t, isAuthError(nil))
+}
+
+func TestServeAddress(t *testing.T) {
+	t.Parallel()
+	assert.Empty(t, validateConfig([]byte(`{"serve_address": "0.0.0.0"}`)))
+	assert.NotEmpty(t, validateConfig([]byte(`{"serve_address": "0.0.0.0:8420"}`)))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// contentMetadata is saved next to downloads, so media managers can index them
+type contentMetadata struct {
+	Title       string   `json:"title"`
+	Category    string   `json:"category,omitempty"`
+	Season      string   `json:"season,omitempty"`
+	Event       string   `json:"event,omitempty"`
+	Session     string   `json:"session,omitempty"`
+	Perspective string   `json:"perspective,omitempty"`
+	Episode     string   `json:"episode,omitempty"`
+	Subtitle    string   `json:"subtitle,omitempty"`
+	Synopsis    string   `json:"synopsis,omitempty"`
+	Date        string   `json:"date,omitempty"`
+	Circuit     string   `json:"circuit,omitempty"`
+	Country     string   `json:"country,omitempty"`
+	Drivers     []string `json:"drivers,omitempty"`
+	Teams       []string `json:"teams,omitempty"`
+}
+
+// nfoEpisode is the Kodi/Jellyfin NFO format for episodes
+type nfoEpisode struct {
+	XMLName   xml.Name   `xml:"episodedetails"`
+	Title     string     `xml:"title"`
+	ShowTitle string     `xml:"showtitle,omitempty"`
+	Season    string     `xml:"season,omitempty"`
+	Plot      string     `xml:"plot,omitempty"`
+	Aired     string     `xml:"aired,omitempty"`
+	Studio    string     `xml:"studio,omitempty"`
+	Genre     string     `xml:"genre,omitempty"`
+	Actors    []nfoActor `xml:"actor"`
+}
+
+type nfoActor struct {
+	Name string `xml:"name"`
+	Role string `xml:"role,omitempty"`
+}
+
+// contentMetadata collects the metadata of the node and its parents from the tree.
+// Driver and team names that aren't cached yet are requested.
+func (session *viewerSession) contentMetadata(node *tview.TreeNode, t Titles) contentMetadata {
+	meta := contentMetadata{
+		Title:       t.String(),
+		Category:    t.CategoryTitle,
+		Season:      t.SeasonTitle,
+		Event:       t.EventTitle,
+		Session:     t.SessionTitle,
+		Perspective: t.PerspectiveTitle,
+		Episode:     t.EpisodeTitle,
+	}
+	for n := node; n != nil; n = session.findParent(n) {
+		ref, ok := n.GetReference().(*NodeMetadata)
+		if !ok {
+			continue
+		}
+		switch m := ref.metadata.(type) {
+		case episode:
+			meta.Subtitle = m.Subtitle
+			meta.Synopsis = m.Synopsis
+			meta.Drivers = session.resolveNames(m.DriverUrls, "driver", session.getDriverName)
+			meta.Teams = session.resolveNames(m.TeamUrls, "team", session.getTeamName)
+		case sessionStruct:
+			if !m.StartTime.IsZero() {
+				meta.Date = m.StartTime.Format("2006-01-02")
+			}
+		case eventStruct:
+			if meta.Date == "" {
+				meta.Date = m.StartDate
+			}
+			meta.Circuit = m.Circuit.Name
+			meta.Country = m.Nation.Name
+		}
+	}
+	return meta
+}
+
+// resolveNames returns the names for the IDs, IDs that can't be resolved are shown as configured with name_fallback
+func (session *viewerSession) resolveNames(ids []string, kind string, resolve func(id string) (string, error)) []string {
+	var names []string
+	for _, id := range ids {
+		session.nameLock.Lock()
+		name, ok := session.nameCache[id]
+		session.nameLock.Unlock()
+		session.metrics.recordLookup("name", ok)
+		if !ok {
+			if name, ok = session.lookupName(id, kind, resolve); !ok {
+				name = session.fallbackName(id, kind)
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// writeSidecar saves the metadata next to the downloaded file in the format, json or nfo
+func writeSidecar(file, format string, meta contentMetadata) (string, error) {
+	var data []byte
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		data, err = json.MarshalIndent(meta, "", "\t")
+	case "nfo":
+		data, err = xml.MarshalIndent(meta.nfo(), "", "\t")
+		data = append([]byte(xml.Header), data...)
+	default:
+		return "", fmt.Errorf("invalid download_metadata '%s', must be json or nfo", format)
+	}
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(file, filepath.Ext(file)) + "." + strings.ToLower(format)
+	return path, ioutil.WriteFile(path, data, 0644)
+}
+
+func (meta contentMetadata) nfo() nfoEpisode {
+	title := meta.Episode
+	if title == "" {
+		title = strings.Join(nonEmpty(meta.Event, meta.Session, meta.Perspective), " - ")
+	}
+	if title == "" {
+		title = meta.Title
+	}
+	plot := meta.Synopsis
+	if plot == "" {
+		plot = meta.Subtitle
+	}
+	showTitle := meta.Category
+	if showTitle == "" {
+		showTitle = meta.Season
+	}
+	nfo := nfoEpisode{
+		Title:     title,
+		ShowTitle: showTitle,
+		Season:    meta.Season,
+		Plot:      plot,
+		Aired:     meta.Date,
+		Studio:    "F1TV",
+		Genre:     "Motorsport",
+	}
+	for _, driver := range meta.Drivers {
+		nfo.Actors = append(nfo.Actors, nfoActor{Name: driver, Role: "Driver"})
+	}
+	for _, team := range meta.Teams {
+		nfo.Actors = append(nfo.Actors, nfoActor{Name: team, Role: "Team"})
+	}
+	return nfo
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentMetadata(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	s.nameCache = map[string]string{"/api/driver/1/": "Lewis Hamilton"}
+
+	event := eventStruct{StartDate: "2019-05-23"}
+	event.Circuit.Name = "Circuit de Monaco"
+	eventNode := tview.NewTreeNode("Monaco").SetReference(&NodeMetadata{metadata: event})
+	sessionNode := tview.NewTreeNode("Race").
+		SetReference(&NodeMetadata{metadata: sessionStruct{StartTime: time.Date(2019, 5, 26, 13, 10, 0, 0, time.UTC)}})
+	perspective := tview.NewTreeNode("Main Feed").SetReference(&NodeMetadata{nodeType: StreamNode})
+	s.tree.GetRoot().AddChild(eventNode)
+	eventNode.AddChild(sessionNode)
+	sessionNode.AddChild(perspective)
+
+	titles := Titles{EventTitle: "Monaco", SessionTitle: "Race", PerspectiveTitle: "Main Feed"}
+	meta := s.contentMetadata(perspective, titles)
+	assert.Equal(t, "2019-05-26", meta.Date)
+	assert.Equal(t, "Circuit de Monaco", meta.Circuit)
+	assert.Equal(t, "Monaco", meta.Event)
+	assert.Equal(t, "Monaco - Race - Main Feed", meta.nfo().Title)
+
+	ep := episode{Title: "Highlights", Synopsis: "The best moments", DriverUrls: []string{"/api/driver/1/"}}
+	epNode := tview.NewTreeNode("Highlights").SetReference(&NodeMetadata{nodeType: PlayableNode, metadata: ep})
+	meta = s.contentMetadata(epNode, Titles{EpisodeTitle: "Highlights"})
+	assert.Equal(t, []string{"Lewis Hamilton"}, meta.Drivers)
+	assert.Equal(t, "The best moments", meta.nfo().Plot)
+}
+
+func TestWriteSidecar(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "f1viewer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "Monaco - Race.mp4")
+	meta := contentMetadata{Title: "Monaco - Race", Event: "Monaco", Drivers: []string{"Lewis Hamilton"}}
+
+	path, err := writeSidecar(file, "json", meta)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "Monaco - Race.json"), path)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"title": "Monaco - Race", "event": "Monaco", "drivers": ["Lewis Hamilton"]}`, string(data))
+
+	path, err = writeSidecar(file, "NFO", meta)
+	assert.NoError(t, err)
+	data, err = ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "<episodedetails>")
+	assert.Contains(t, string(data), "<name>Lewis Hamilton</name>")
+
+	_, err = writeSidecar(file, "xml", meta)
+	assert.Error(t, err)
+}
+
+func TestResolveNames(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 40, 5)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+
+	calls := 0
+	resolve := func(id string) (string, error) {
+		calls++
+		switch {
+		case id == "/api/driver/empty/":
+			return "", nil
+		case calls == 1:
+			return "", errors.New("timeout")
+		default:
+			return "Lewis Hamilton", nil
+		}
+	}
+	ids := []string{"/api/driver/1/"}
+
+	// failures show the ID and aren't cached
+	assert.Equal(t, []string{"1"}, s.resolveNames(ids, "driver", resolve))
+	assert.NotContains(t, s.nameCache, "/api/driver/1/")
+	assert.Equal(t, []string{"Lewis Hamilton"}, s.resolveNames(ids, "driver", resolve))
+	assert.Equal(t, []string{"Lewis Hamilton"}, s.resolveNames(ids, "driver", resolve))
+	assert.Equal(t, 2, calls)
+
+	s.cfg.NameFallback = "placeholder"
+	assert.Equal(t, []string{"unknown driver"}, s.resolveNames([]string{"/api/driver/empty/"}, "driver", resolve))
+	assert.NotContains(t, s.nameCache, "/api/driver/empty/")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// number of items shown in the most watched node
+const mostWatchedCount = 10
+
+// watchStat counts how often a piece of content was played, the stats are only saved locally
+type watchStat struct {
+	ID         string    `json:"id"`
+	Titles     Titles    `json:"titles"`
+	Count      int       `json:"count"`
+	LastPlayed time.Time `json:"last_played"`
+}
+
+func getStatsPath() (string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return path + "stats.json", nil
+}
+
+// loadStats reads the saved stats, keyed by content ID.
+// If the file is invalid, empty stats are returned together with the error.
+func (session *viewerSession) loadStats() (map[string]*watchStat, error) {
+	stats := make(map[string]*watchStat)
+	path, err := getStatsPath()
+	if err != nil {
+		return stats, err
+	}
+	err = session.readCache(path, &stats)
+	if os.IsNotExist(err) {
+		return stats, nil
+	} else if err != nil {
+		return make(map[string]*watchStat), err
+	}
+	return stats, nil
+}
+
+// countPlay increments the play count of the content
+func (session *viewerSession) countPlay(epID string, t Titles) error {
+	path, err := getStatsPath()
+	if err != nil {
+		return err
+	}
+	stats := make(map[string]*watchStat)
+	return session.updateCache(path, &stats, func(readErr error) error {
+		if errors.Is(readErr, errInvalidCache) {
+			session.logWarn(readErr, ", starting with empty stats")
+			stats = make(map[string]*watchStat)
+		} else if readErr != nil {
+			return fmt.Errorf("could not read stats: %w", readErr)
+		}
+		stat, ok := stats[epID]
+		if !ok {
+			stat = &watchStat{ID: epID}
+			stats[epID] = stat
+		}
+		stat.Titles = t
+		stat.Count++
+		stat.LastPlayed = time.Now()
+		session.markIcon(iconPlayed, epID)
+		return nil
+	})
+}
+
+// mostWatched returns the n most played items, recently played items first if the count is equal
+func mostWatched(stats map[string]*watchStat, n int) []watchStat {
+	list := make([]watchStat, 0, len(stats))
+	for _, stat := range stats {
+		list = append(list, *stat)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].LastPlayed.After(list[j].LastPlayed)
+	})
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// getMostWatchedNode returns a node listing the most played content, it's rebuilt every time it's selected
+func (session *viewerSession) getMostWatchedNode() *tview.TreeNode {
+	node := tview.NewTreeNode("Most Watched").
+		SetColor(session.theme.CategoryNodeColor).
+		SetExpanded(false).
+		SetReference(&NodeMetadata{nodeType: CategoryNode})
+	node.SetSelectedFunc(func() {
+		node.ClearChildren()
+		stats, err := session.loadStats()
+		if err != nil {
+			session.logError("could not read stats: ", err)
+		}
+		for _, stat := range mostWatched(stats, mostWatchedCount) {
+			item := tview.NewTreeNode(fmt.Sprintf("%s (%d)", stat.Titles.String(), stat.Count)).
+				SetColor(session.theme.ItemNodeColor).
+				SetExpanded(false).
+				SetReference(&NodeMetadata{nodeType: PlayableNode, id: stat.ID, titles: stat.Titles})
+			appendNodes(item, session.getPlaybackNodes(stat.Titles, stat.ID)...)
+			node.AddChild(item)
+		}
+		if len(node.GetChildren()) == 0 {
+			node.AddChild(session.nocontentNode())
+		}
+		session.styleNodes(node)
+	})
+	return node
+}
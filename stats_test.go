@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMostWatched(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	stats := map[string]*watchStat{
+		"a": {ID: "a", Count: 1, LastPlayed: now},
+		"b": {ID: "b", Count: 5, LastPlayed: now.Add(-time.Hour)},
+		"c": {ID: "c", Count: 2, LastPlayed: now.Add(-time.Hour)},
+		"d": {ID: "d", Count: 2, LastPlayed: now},
+	}
+
+	top := mostWatched(stats, 3)
+	assert.Len(t, top, 3)
+	assert.Equal(t, "b", top[0].ID)
+	assert.Equal(t, "d", top[1].ID)
+	assert.Equal(t, "c", top[2].ID)
+
+	assert.Empty(t, mostWatched(nil, 3))
+}
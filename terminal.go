@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// newScreen initializes the terminal for the UI, the error explains what can be done if that isn't possible
+func newScreen() (tcell.Screen, error) {
+	screen, err := tcell.NewScreen()
+	if err == nil {
+		err = screen.Init()
+	}
+	if err != nil {
+		return nil, errors.New(screenError(err, os.Getenv("TERM"), runtime.GOOS))
+	}
+	screen.EnableMouse()
+	return screen, nil
+}
+
+// screenError describes why the terminal couldn't be initialized and how to fix it
+func screenError(err error, term, goos string) string {
+	if term == "" {
+		term = "not set"
+	}
+	lines := []string{
+		fmt.Sprintf("could not initialize the terminal: %v", err),
+		"TERM: " + term,
+		"",
+		"try one of these:",
+		" - run f1viewer in an interactive terminal, its input and output can't be redirected",
+		" - set TERM to a common terminal type, eg. TERM=xterm-256color f1viewer",
+	}
+	if goos == "windows" {
+		lines = append(lines, " - use Windows Terminal, PowerShell or cmd, or start f1viewer with winpty in Git Bash and other mintty terminals")
+	}
+	lines = append(lines, " - -list, -serve and -check-config don't need a terminal")
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreenError(t *testing.T) {
+	t.Parallel()
+	msg := screenError(errors.New("terminal entry not found"), "", "linux")
+	assert.Contains(t, msg, "could not initialize the terminal: terminal entry not found")
+	assert.Contains(t, msg, "TERM: not set")
+	assert.NotContains(t, msg, "winpty")
+
+	assert.Contains(t, screenError(errors.New("not a console"), "xterm", "windows"), "winpty")
+}
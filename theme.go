@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+type namedTheme struct {
+	name  string
+	theme theme
+}
+
+// bundled themes that can be selected by name in the config or cycled through at runtime, every color is set so they fully replace each other
+var bundledThemes = []namedTheme{
+	{
+		name: "default",
+		theme: theme{
+			BackgroundColor:     "#000000",
+			BorderColor:         "#ffffff",
+			CategoryNodeColor:   "#ffa500",
+			FolderNodeColor:     "#ffffff",
+			ItemNodeColor:       "#90ee90",
+			ActionNodeColor:     "#008b8b",
+			LoadingColor:        "#008b8b",
+			LiveColor:           "#ff0000",
+			UpdateColor:         "#8b0000",
+			NoContentColor:      "#ff4500",
+			RateLimitColor:      "#ffff00",
+			GeoBlockColor:       "#ff00ff",
+			VerifiedColor:       "#00ff00",
+			WarnColor:           "#ffa500",
+			InfoColor:           "#008000",
+			ErrorColor:          "#ff0000",
+			TerminalAccentColor: "#008000",
+			TerminalTextColor:   "#ffffff",
+			MultiCommandColor:   "#7fffd4",
+		},
+	},
+	{
+		name: "dark",
+		theme: theme{
+			BackgroundColor:     "#1d2021",
+			BorderColor:         "#665c54",
+			CategoryNodeColor:   "#fe8019",
+			FolderNodeColor:     "#ebdbb2",
+			ItemNodeColor:       "#b8bb26",
+			ActionNodeColor:     "#83a598",
+			LoadingColor:        "#8ec07c",
+			LiveColor:           "#fb4934",
+			UpdateColor:         "#d3869b",
+			NoContentColor:      "#928374",
+			RateLimitColor:      "#fabd2f",
+			GeoBlockColor:       "#b16286",
+			VerifiedColor:       "#98971a",
+			WarnColor:           "#fe8019",
+			InfoColor:           "#b8bb26",
+			ErrorColor:          "#fb4934",
+			TerminalAccentColor: "#fabd2f",
+			TerminalTextColor:   "#ebdbb2",
+			MultiCommandColor:   "#8ec07c",
+		},
+	},
+	{
+		name: "light",
+		theme: theme{
+			BackgroundColor:     "#fdf6e3",
+			BorderColor:         "#93a1a1",
+			CategoryNodeColor:   "#cb4b16",
+			FolderNodeColor:     "#073642",
+			ItemNodeColor:       "#859900",
+			ActionNodeColor:     "#268bd2",
+			LoadingColor:        "#2aa198",
+			LiveColor:           "#dc322f",
+			UpdateColor:         "#d33682",
+			NoContentColor:      "#93a1a1",
+			RateLimitColor:      "#b58900",
+			GeoBlockColor:       "#6c71c4",
+			VerifiedColor:       "#5f8700",
+			WarnColor:           "#cb4b16",
+			InfoColor:           "#859900",
+			ErrorColor:          "#dc322f",
+			TerminalAccentColor: "#6c71c4",
+			TerminalTextColor:   "#586e75",
+			MultiCommandColor:   "#2aa198",
+		},
+	},
+	{
+		name: "nord",
+		theme: theme{
+			BackgroundColor:     "#2e3440",
+			BorderColor:         "#4c566a",
+			CategoryNodeColor:   "#88c0d0",
+			FolderNodeColor:     "#d8dee9",
+			ItemNodeColor:       "#a3be8c",
+			ActionNodeColor:     "#81a1c1",
+			LoadingColor:        "#b48ead",
+			LiveColor:           "#bf616a",
+			UpdateColor:         "#b48ead",
+			NoContentColor:      "#d08770",
+			RateLimitColor:      "#ebcb8b",
+			GeoBlockColor:       "#5e81ac",
+			VerifiedColor:       "#97b67c",
+			WarnColor:           "#d08770",
+			InfoColor:           "#a3be8c",
+			ErrorColor:          "#bf616a",
+			TerminalAccentColor: "#88c0d0",
+			TerminalTextColor:   "#eceff4",
+			MultiCommandColor:   "#8fbcbb",
+		},
+	},
+	{
+		name: "dracula",
+		theme: theme{
+			BackgroundColor:     "#282a36",
+			BorderColor:         "#6272a4",
+			CategoryNodeColor:   "#ffb86c",
+			FolderNodeColor:     "#f8f8f2",
+			ItemNodeColor:       "#50fa7b",
+			ActionNodeColor:     "#8be9fd",
+			LoadingColor:        "#bd93f9",
+			LiveColor:           "#ff5555",
+			UpdateColor:         "#ff79c6",
+			NoContentColor:      "#6272a4",
+			RateLimitColor:      "#f1fa8c",
+			GeoBlockColor:       "#bd93f9",
+			VerifiedColor:       "#69ff94",
+			WarnColor:           "#ffb86c",
+			InfoColor:           "#50fa7b",
+			ErrorColor:          "#ff5555",
+			TerminalAccentColor: "#bd93f9",
+			TerminalTextColor:   "#f8f8f2",
+			MultiCommandColor:   "#a4ffff",
+		},
+	},
+	{
+		name: "monokai",
+		theme: theme{
+			BackgroundColor:     "#272822",
+			BorderColor:         "#75715e",
+			CategoryNodeColor:   "#fd971f",
+			FolderNodeColor:     "#f8f8f2",
+			ItemNodeColor:       "#a6e22e",
+			ActionNodeColor:     "#66d9ef",
+			LoadingColor:        "#ae81ff",
+			LiveColor:           "#f92672",
+			UpdateColor:         "#ae81ff",
+			NoContentColor:      "#75715e",
+			RateLimitColor:      "#e6db74",
+			GeoBlockColor:       "#fd5ff0",
+			VerifiedColor:       "#b6e354",
+			WarnColor:           "#fd971f",
+			InfoColor:           "#a6e22e",
+			ErrorColor:          "#f92672",
+			TerminalAccentColor: "#e6db74",
+			TerminalTextColor:   "#f8f8f2",
+			MultiCommandColor:   "#a1efe4",
+		},
+	},
+	{
+		name: "high-contrast",
+		theme: theme{
+			BackgroundColor:     "#000000",
+			BorderColor:         "#ffffff",
+			CategoryNodeColor:   "#ffff00",
+			FolderNodeColor:     "#ffffff",
+			ItemNodeColor:       "#00ff00",
+			ActionNodeColor:     "#00ffff",
+			LoadingColor:        "#ff00ff",
+			LiveColor:           "#ff0000",
+			UpdateColor:         "#ff00ff",
+			NoContentColor:      "#ff8000",
+			RateLimitColor:      "#ffc0cb",
+			GeoBlockColor:       "#0080ff",
+			VerifiedColor:       "#80ff80",
+			WarnColor:           "#ff8000",
+			InfoColor:           "#00ff00",
+			ErrorColor:          "#ff0000",
+			TerminalAccentColor: "#ffff00",
+			TerminalTextColor:   "#ffffff",
+			MultiCommandColor:   "#8080ff",
+		},
+	},
+}
+
+// themeFields has the same fields as theme without its JSON methods
+type themeFields theme
+
+// UnmarshalJSON accepts the name of a bundled theme as well as an object.
+// Fields that aren't set in the object keep their value, so profiles can override single colors.
+func (t *theme) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		t.Preset = name
+		return nil
+	}
+	return json.Unmarshal(data, (*themeFields)(t))
+}
+
+// MarshalJSON saves a theme that only consists of a preset as its name
+func (t theme) MarshalJSON() ([]byte, error) {
+	if t.Preset != "" && t == (theme{Preset: t.Preset}) {
+		return json.Marshal(t.Preset)
+	}
+	return json.Marshal(themeFields(t))
+}
+
+// findTheme returns the index of the bundled theme with the name, or -1 if there is none
+func findTheme(name string) int {
+	for i, t := range bundledThemes {
+		if strings.EqualFold(t.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func themeNames() []string {
+	names := make([]string, len(bundledThemes))
+	for i, t := range bundledThemes {
+		names[i] = t.name
+	}
+	return names
+}
+
+// cycleTheme switches to the next bundled theme and saves it in the config, the colors set in the config still override it
+func (session *viewerSession) cycleTheme() {
+	session.themeIndex = (session.themeIndex + 1) % len(bundledThemes)
+	next := bundledThemes[session.themeIndex]
+	t := session.cfg.Theme
+	t.Preset = next.name
+	session.setTheme(t)
+	session.logInfo("switched to theme ", next.name)
+
+	profile := session.cfg.profile
+	var profileErr error
+	err := session.saveConfig(func(cfg *config) { profileErr = cfg.setPreset(next.name, profile) })
+	if err != nil {
+		session.logError(err)
+	} else if profileErr != nil {
+		session.logWarn("could not save the theme in profile '", profile, "': ", profileErr)
+	}
+}
+
+// setPreset replaces the preset of the theme and keeps the colors that override it.
+// If the profile selects its own preset it's replaced there as well, otherwise the profile would switch back on the next start.
+func (cfg *config) setPreset(preset, profile string) error {
+	cfg.Theme.Preset = preset
+	raw, ok := cfg.Profiles[profile]
+	if !ok {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	rawTheme, ok := fields["theme"]
+	if !ok {
+		return nil
+	}
+	name, err := json.Marshal(preset)
+	if err != nil {
+		return err
+	}
+	// the theme is either the name of a preset or an object, only its preset is replaced so its colors are kept
+	var object map[string]json.RawMessage
+	if json.Unmarshal(rawTheme, &object) == nil {
+		if _, ok := object["preset"]; !ok {
+			return nil
+		}
+		object["preset"] = name
+		if fields["theme"], err = json.Marshal(object); err != nil {
+			return err
+		}
+	} else {
+		fields["theme"] = name
+	}
+	cfg.Profiles[profile], err = json.Marshal(fields)
+	return err
+}
+
+// nodeColors returns the colors that mark the role or state of a node.
+// They have to be unique within a theme, otherwise nodes get the wrong color when the theme is switched.
+func (t themeColors) nodeColors() []tcell.Color {
+	return []tcell.Color{
+		t.CategoryNodeColor,
+		t.FolderNodeColor,
+		t.ItemNodeColor,
+		t.ActionNodeColor,
+		t.LiveColor,
+		t.MultiCommandColor,
+		t.UpdateColor,
+		t.NoContentColor,
+		t.RateLimitColor,
+		t.GeoBlockColor,
+		t.VerifiedColor,
+	}
+}
+
+// setTheme replaces the active theme and recolors the existing UI
+func (session *viewerSession) setTheme(t theme) {
+	old := session.theme
+	colors := session.loadTheme(t)
+	session.theme = colors
+
+	// map the node colors of the old theme to the new one
+	colorMap := make(map[tcell.Color]tcell.Color)
+	newColors := colors.nodeColors()
+	for i, c := range old.nodeColors() {
+		colorMap[c] = newColors[i]
+	}
+	session.tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		if c, ok := colorMap[node.GetColor()]; ok {
+			node.SetColor(c)
+		}
+		return true
+	})
+
+	session.tree.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
+	session.textWindow.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
+	session.textWindow.SetBorderColor(tview.Styles.BorderColor)
+	session.textWindow.SetTextColor(colors.TerminalTextColor)
+	if session.debugWindow != nil {
+		session.debugWindow.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
+		session.debugWindow.SetBorderColor(tview.Styles.BorderColor)
+		session.debugWindow.SetTextColor(colors.TerminalTextColor)
+	}
+	session.infoTable.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
+	session.infoTable.SetBorderColor(tview.Styles.BorderColor)
+	if session.pinnedNode != nil {
+		session.updateInfo(session.pinnedNode)
+	} else {
+		session.updateInfo(session.tree.GetCurrentNode())
+	}
+}
+
+// loadTheme returns the default colors overridden by the theme's preset and its own colors, limited to the configured color mode
+func (session *viewerSession) loadTheme(t theme) themeColors {
+	colors := defaultTheme()
+	if i := findTheme(t.Preset); i >= 0 {
+		bundledThemes[i].theme.apply(&colors)
+	}
+	t.apply(&colors)
+	if n := paletteSize(session.cfg.ColorMode); n > 0 {
+		colors.limit(n)
+	}
+	return colors
+}
+
+// configureColorMode prevents tcell from using RGB colors if a limited color mode is configured.
+// In auto mode tcell already maps colors to the terminal's palette if it doesn't support RGB colors.
+func configureColorMode(mode string) {
+	if paletteSize(mode) > 0 {
+		os.Setenv("TCELL_TRUECOLOR", "disable")
+	}
+}
+
+// paletteSize returns the number of colors for a color mode, or 0 if colors aren't limited
+func paletteSize(mode string) int {
+	switch mode {
+	case "256":
+		return 256
+	case "16":
+		return 16
+	default:
+		return 0
+	}
+}
+
+// palette returns the first n palette colors
+func palette(n int) []tcell.Color {
+	colors := make([]tcell.Color, n)
+	for i := range colors {
+		colors[i] = tcell.Color(i)
+	}
+	return colors
+}
+
+// limit replaces every color with the closest of the first n palette colors
+func (colors *themeColors) limit(n int) {
+	palette := palette(n)
+	for _, c := range []*tcell.Color{
+		&colors.CategoryNodeColor,
+		&colors.FolderNodeColor,
+		&colors.ItemNodeColor,
+		&colors.ActionNodeColor,
+		&colors.LoadingColor,
+		&colors.LiveColor,
+		&colors.MultiCommandColor,
+		&colors.UpdateColor,
+		&colors.NoContentColor,
+		&colors.RateLimitColor,
+		&colors.GeoBlockColor,
+		&colors.VerifiedColor,
+		&colors.WarnColor,
+		&colors.InfoColor,
+		&colors.ErrorColor,
+		&colors.TerminalAccentColor,
+		&colors.TerminalTextColor,
+		&tview.Styles.PrimitiveBackgroundColor,
+		&tview.Styles.BorderColor,
+		&tview.Styles.PrimaryTextColor,
+	} {
+		*c = tcell.FindColor(*c, palette)
+	}
+}
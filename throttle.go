@@ -0,0 +1,167 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// rateLimiter limits how many bytes per second are read by all readers that share it
+type rateLimiter struct {
+	lock sync.Mutex
+	// bytes per second
+	rate int
+	// when the bandwidth is available again
+	next time.Time
+}
+
+func newRateLimiter(kbits int) *rateLimiter {
+	return &rateLimiter{rate: kbits * 1000 / 8}
+}
+
+// chunk is the most that is read at once, so the limit is kept without long pauses
+func (l *rateLimiter) chunk() int {
+	if c := l.rate / 10; c > 1024 {
+		return c
+	}
+	return 1024
+}
+
+// wait blocks until n bytes may be read
+func (l *rateLimiter) wait(n int) {
+	l.lock.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.rate))
+	l.lock.Unlock()
+	time.Sleep(delay)
+}
+
+type throttledReader struct {
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+// throttle limits the reader, a nil limiter doesn't limit it
+func throttle(r io.Reader, limiter *rateLimiter) io.Reader {
+	if limiter == nil {
+		return r
+	}
+	return &throttledReader{r: r, limiter: limiter}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if max := t.limiter.chunk(); len(p) > max {
+		p = p[:max]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.limiter.wait(n)
+	}
+	return n, err
+}
+
+// downloadLimiter returns the limiter all downloads share, or nil if download_bandwidth isn't set
+func (session *viewerSession) downloadLimiter() *rateLimiter {
+	if session.cfg.DownloadBandwidth <= 0 {
+		return nil
+	}
+	session.throttleOnce.Do(func() {
+		session.limiter = newRateLimiter(session.cfg.DownloadBandwidth)
+	})
+	return session.limiter
+}
+
+// ffmpegProxyURL returns the address of a local proxy that limits ffmpeg's downloads to download_bandwidth and forwards them to proxy.
+// ffmpeg can't limit its bandwidth itself and only supports http proxies. The proxy is started the first time it's needed,
+// without download_bandwidth and proxy ffmpeg downloads directly.
+func (session *viewerSession) ffmpegProxyURL() (string, error) {
+	limiter := session.downloadLimiter()
+	upstream := configProxy(session.cfg)
+	if limiter == nil && upstream == nil {
+		return "", nil
+	}
+	session.proxyLock.Lock()
+	defer session.proxyLock.Unlock()
+	if session.proxyURL != "" {
+		return session.proxyURL, nil
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	go http.Serve(listener, throttleProxy(limiter, upstream))
+	session.proxyURL = "http://" + listener.Addr().String()
+	return session.proxyURL, nil
+}
+
+// throttleProxy is an HTTP proxy that limits everything it downloads, a nil limiter doesn't limit it.
+// HTTPS is tunneled with CONNECT, so only the encrypted data is throttled. Requests go through upstream if it isn't nil.
+func throttleProxy(limiter *rateLimiter, upstream *url.URL) http.Handler {
+	transport := newStreamTransport(upstream)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			tunnel(w, r, limiter, upstream)
+			return
+		}
+		r.RequestURI = ""
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for key, values := range resp.Header {
+			for _, v := range values {
+				w.Header().Add(key, v)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, throttle(resp.Body, limiter))
+	})
+}
+
+func tunnel(w http.ResponseWriter, r *http.Request, limiter *rateLimiter, upstream *url.URL) {
+	server, err := dialThrough(upstream, r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		server.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		server.Close()
+		return
+	}
+	_, _ = client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	go func() {
+		_, _ = io.Copy(server, client)
+		server.Close()
+	}()
+	_, _ = io.Copy(client, throttle(server, limiter))
+	client.Close()
+}
+
+// withProxy adds ffmpeg's http_proxy option before the input, if there is a proxy
+func withProxy(args []string, proxy string) []string {
+	if proxy == "" {
+		return args
+	}
+	for i, arg := range args {
+		if arg == "-i" {
+			return append(append(append([]string(nil), args[:i]...), "-http_proxy", proxy), args[i:]...)
+		}
+	}
+	return args
+}
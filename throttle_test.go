@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	t.Parallel()
+
+	// 80 kbit/s are 10000 bytes per second
+	limiter := newRateLimiter(80)
+	start := time.Now()
+	data, err := ioutil.ReadAll(throttle(strings.NewReader(strings.Repeat("x", 3000)), limiter))
+	assert.NoError(t, err)
+	assert.Len(t, data, 3000)
+	assert.True(t, time.Since(start) >= 200*time.Millisecond, time.Since(start))
+
+	assert.Equal(t, strings.NewReader("x"), throttle(strings.NewReader("x"), nil))
+}
+
+func TestDownloadSegments(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// later segments are faster, they still have to be written in order
+		switch r.URL.Path {
+		case "/0.ts":
+			time.Sleep(50 * time.Millisecond)
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	var urls []string
+	for i := 0; i < 5; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d.ts", server.URL, i))
+	}
+	for _, concurrency := range []int{0, 1, 3} {
+		var out bytes.Buffer
+		assert.NoError(t, downloadSegments(http.DefaultClient, urls, &out, concurrency, nil))
+		assert.Equal(t, "/0.ts/1.ts/2.ts/3.ts/4.ts", out.String())
+	}
+
+	var out bytes.Buffer
+	assert.Error(t, downloadSegments(http.DefaultClient, []string{urls[0], server.URL + "/missing"}, &out, 2, nil))
+}
+
+func TestThrottleProxy(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "segment")
+	}))
+	defer server.Close()
+	proxy := httptest.NewServer(throttleProxy(newRateLimiter(1000), nil))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	assert.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	resp, err := client.Get(server.URL)
+	if assert.NoError(t, err) {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "segment", string(body))
+	}
+
+	args := []string{"ffmpeg", "-n", "-i", "url", "out.mp4"}
+	assert.Equal(t, []string{"ffmpeg", "-n", "-http_proxy", "http://127.0.0.1:1", "-i", "url", "out.mp4"}, withProxy(args, "http://127.0.0.1:1"))
+	assert.Equal(t, args, withProxy(args, ""))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// kinds of nodes that can get a prefix with tree_prefixes
+const (
+	folderPrefix = "folder"
+	leafPrefix   = "leaf"
+	actionPrefix = "action"
+)
+
+// nodeKind returns which prefix a node gets, perspectives and episodes are leaves and everything that contains them is a folder
+func nodeKind(node *tview.TreeNode) string {
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok {
+		return ""
+	}
+	if ref.nodeType == ActionNode {
+		return actionPrefix
+	}
+	if ref.nodeType == MiscNode && len(node.GetChildren()) == 0 {
+		// eg. "no content"
+		return ""
+	}
+	if _, ok := playableID(ref); ok {
+		return leafPrefix
+	}
+	return folderPrefix
+}
+
+// decoration is what's shown in front of the label, the prefix, the icons and the selection mark
+func (ref *NodeMetadata) decoration() string {
+	return ref.prefix + ref.icons + ref.mark
+}
+
+// nodeLabel returns the text of the node without the prefix, icons and selection mark in front of it.
+// Everything that shows or matches labels outside of the tree uses it instead of GetText.
+func nodeLabel(node *tview.TreeNode) string {
+	text := node.GetText()
+	if ref, ok := node.GetReference().(*NodeMetadata); ok {
+		return strings.TrimPrefix(text, ref.decoration())
+	}
+	return text
+}
+
+// decorate replaces what's shown in front of the label of the node
+func decorate(node *tview.TreeNode, prefix, icons, mark string) {
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok || ref.prefix == prefix && ref.icons == icons && ref.mark == mark {
+		return
+	}
+	label := nodeLabel(node)
+	ref.prefix, ref.icons, ref.mark = prefix, icons, mark
+	node.SetText(ref.decoration() + label)
+}
+
+// setLabel replaces the label of the node, what's shown in front of it stays
+func setLabel(node *tview.TreeNode, label string) {
+	if ref, ok := node.GetReference().(*NodeMetadata); ok {
+		label = ref.decoration() + label
+	}
+	node.SetText(label)
+}
+
+// treeStyled checks if any of tree_indent, tree_prefixes and node_icons is set
+func (session *viewerSession) treeStyled() bool {
+	return session.cfg.TreeIndent != nil || len(session.cfg.TreePrefixes) > 0 || len(session.cfg.NodeIcons) > 0
+}
+
+// styleTree sets up tree_indent, tree_prefixes and node_icons for the nodes that are already in the tree.
+// Nodes that are added later are styled with styleNodes when they're added.
+func (session *viewerSession) styleTree() {
+	if !session.treeStyled() {
+		return
+	}
+	if len(session.cfg.NodeIcons) > 0 {
+		session.loadIconState()
+	}
+	session.styleNodes(session.tree.GetRoot())
+}
+
+// styleCategory styles a node that's added to the top level of the tree and everything below it
+func (session *viewerSession) styleCategory(node *tview.TreeNode) {
+	if !session.treeStyled() {
+		return
+	}
+	session.styleNode(node, false)
+	session.styleNodes(node)
+}
+
+// styleNodes styles everything below node. It has to be called after nodes are added to the tree.
+func (session *viewerSession) styleNodes(node *tview.TreeNode) {
+	if !session.treeStyled() || session.tree == nil {
+		return
+	}
+	topLevel := node == session.tree.GetRoot()
+	for _, child := range node.GetChildren() {
+		session.styleNode(child, !topLevel)
+		session.styleNodes(child)
+	}
+}
+
+// styleNode sets the indentation and the prefix of nodes below the top level, top level categories are matched by their names.
+// Icons are shown between the prefix and the label.
+func (session *viewerSession) styleNode(node *tview.TreeNode, belowTopLevel bool) {
+	ref, ok := node.GetReference().(*NodeMetadata)
+	if !ok {
+		return
+	}
+	var prefix string
+	if belowTopLevel {
+		if session.cfg.TreeIndent != nil {
+			node.SetIndent(*session.cfg.TreeIndent)
+		}
+		if p := session.cfg.TreePrefixes[nodeKind(node)]; p != "" {
+			prefix = p + " "
+		}
+	}
+	var icons string
+	if len(session.cfg.NodeIcons) > 0 {
+		icons = session.nodeIcons(node)
+	}
+	decorate(node, prefix, icons, ref.mark)
+}
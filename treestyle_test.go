@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStyleNodes(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	indent := 0
+	s.cfg.TreeIndent = &indent
+	s.cfg.TreePrefixes = map[string]string{folderPrefix: "▸", leafPrefix: "♪", actionPrefix: "↓"}
+	s.styleTree()
+
+	category := tview.NewTreeNode("Full Seasons").SetReference(&NodeMetadata{nodeType: CategoryNode})
+	race := tview.NewTreeNode("Race").SetReference(&NodeMetadata{nodeType: PlayableNode, metadata: sessionStruct{}})
+	feed := tview.NewTreeNode("Main Feed").SetReference(&NodeMetadata{nodeType: StreamNode, id: "1"})
+	play := tview.NewTreeNode("Play with MPV").SetReference(&NodeMetadata{nodeType: ActionNode})
+	empty := tview.NewTreeNode("no content").SetReference(&NodeMetadata{nodeType: MiscNode})
+	s.tree.GetRoot().AddChild(category)
+	category.AddChild(race).AddChild(empty)
+	race.AddChild(feed)
+	feed.AddChild(play)
+
+	s.styleNodes(s.tree.GetRoot())
+	s.styleNodes(s.tree.GetRoot())
+	assert.Equal(t, "Full Seasons", category.GetText())
+	assert.Equal(t, "▸ Race", race.GetText())
+	assert.Equal(t, "♪ Main Feed", feed.GetText())
+	assert.Equal(t, "↓ Play with MPV", play.GetText())
+	assert.Equal(t, "no content", empty.GetText())
+	assert.Equal(t, "Race", nodeLabel(race))
+	assert.Equal(t, "Play with MPV", nodeLabel(play))
+
+	s.toggleSelection(feed)
+	assert.Equal(t, "♪ "+selectedPrefix+"Main Feed", feed.GetText())
+	assert.Equal(t, "Main Feed", nodeLabel(feed))
+	s.clearSelection()
+	assert.Equal(t, "♪ Main Feed", feed.GetText())
+}
+
+func TestNodeIcons(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+	s.cfg.TreePrefixes = map[string]string{leafPrefix: "♪"}
+	s.cfg.NodeIcons = map[string]string{iconNew: "★", iconPlaying: "▶", iconPlayed: "✓"}
+
+	category := tview.NewTreeNode("Full Seasons").SetReference(&NodeMetadata{nodeType: CategoryNode})
+	event := tview.NewTreeNode("Monaco").SetReference(&NodeMetadata{nodeType: EventNode, id: "event"})
+	feed := tview.NewTreeNode("Main Feed").SetReference(&NodeMetadata{nodeType: StreamNode, id: "feed"})
+	s.tree.GetRoot().AddChild(category)
+	category.AddChild(event)
+	event.AddChild(feed)
+	s.styleNodes(s.tree.GetRoot())
+	assert.Equal(t, "Monaco", event.GetText())
+	assert.Equal(t, "♪ Main Feed", feed.GetText())
+
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+	// the icons are updated on the UI goroutine, so the text is read there as well
+	text := func(node *tview.TreeNode) string {
+		var text string
+		s.app.QueueUpdate(func() { text = node.GetText() })
+		return text
+	}
+
+	s.markIcon(iconNew, "event")
+	s.markIcon(iconPlayed, "feed")
+	proc, ok := s.trackProcess(playerKey(commandContext{EpID: "feed", CustomOptions: command{Title: "MPV"}}), "Main Feed", false)
+	assert.True(t, ok)
+	assert.Eventually(t, func() bool { return text(event) == "★ Monaco" }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return text(feed) == "♪ ▶✓ Main Feed" }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "Main Feed", nodeLabel(feed))
+
+	s.untrackProcess(proc)
+	assert.Eventually(t, func() bool { return text(feed) == "♪ ✓ Main Feed" }, time.Second, 10*time.Millisecond)
+}
@@ -48,10 +48,10 @@ func (session *viewerSession) CheckUpdate() {
 
 	updateNode := tview.NewTreeNode("UPDATE AVAILABLE").
 		SetReference(&NodeMetadata{nodeType: MiscNode}).
-		SetColor(activeTheme.UpdateColor).
+		SetColor(session.theme.UpdateColor).
 		SetExpanded(false)
 	getUpdateNode := tview.NewTreeNode("download update").
-		SetColor(activeTheme.ActionNodeColor).
+		SetColor(session.theme.ActionNodeColor).
 		SetReference(&NodeMetadata{nodeType: ActionNode}).
 		SetSelectedFunc(func() {
 			err := openbrowser("https://github.com/SoMuchForSubtlety/F1viewer/releases/latest")
@@ -60,21 +60,21 @@ func (session *viewerSession) CheckUpdate() {
 			}
 		})
 	stopCheckingNode := tview.NewTreeNode("don't tell me about updates").
-		SetColor(activeTheme.ActionNodeColor).
+		SetColor(session.theme.ActionNodeColor).
 		SetReference(&NodeMetadata{nodeType: ActionNode})
 	stopCheckingNode.SetSelectedFunc(func() {
-		session.cfg.CheckUpdate = false
-		err := session.cfg.save()
+		err := session.saveConfig(func(cfg *config) { cfg.CheckUpdate = false })
 		if err != nil {
 			session.logError(err)
 		}
-		stopCheckingNode.SetText("update checks turned off")
+		setLabel(stopCheckingNode, "update checks turned off")
 	})
 
 	appendNodes(updateNode, getUpdateNode, stopCheckingNode)
 
 	insertNodeAtTop(session.tree.GetRoot(), updateNode)
-	session.app.Draw()
+	session.styleCategory(updateNode)
+	session.draw()
 }
 
 func openbrowser(url string) error {
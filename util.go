@@ -16,21 +16,47 @@ import (
 )
 
 func (session *viewerSession) checkCommands(commands ...string) {
-	var found int
 	for _, cmd := range commands {
 		_, err := exec.LookPath(cmd)
 		session.commands[cmd] = err == nil
-		if err == nil {
-			found++
-		} else {
+		if err != nil {
 			session.logInfo("could not find ", cmd)
 		}
 	}
-	if found == 0 {
-		session.logError("Both MPV and VLC are unavailable!")
+	// the system default opener is checked separately, it's only a fallback for players
+	if opener := openerCommand(runtime.GOOS); len(opener) > 0 {
+		_, err := exec.LookPath(opener[0])
+		session.commands[opener[0]] = err == nil
+	}
+	if !session.commandAvailable("mpv") && !session.commandAvailable("vlc") {
+		if session.openerAvailable() {
+			session.logWarn("Both MPV and VLC are unavailable, content can only be opened with the system default application")
+		} else {
+			session.logError("Both MPV and VLC are unavailable!")
+		}
+	}
+}
+
+// openerCommand returns the command that opens a URL with the system default application, or nil if there is none
+func openerCommand(goos string) []string {
+	switch goos {
+	case "windows":
+		// start is a cmd builtin that would interpret the & in stream URLs
+		return []string{"rundll32", "url.dll,FileProtocolHandler", "$url"}
+	case "darwin":
+		return []string{"open", "$url"}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"xdg-open", "$url"}
+	default:
+		return nil
 	}
 }
 
+func (session *viewerSession) openerAvailable() bool {
+	opener := openerCommand(runtime.GOOS)
+	return len(opener) > 0 && session.commandAvailable(opener[0])
+}
+
 func (session *viewerSession) commandAvailable(command string) bool {
 	available, ok := session.commands[command]
 	return ok && available
@@ -63,18 +89,104 @@ func getYearAndRace(input string) (string, string, error) {
 	return fullYear, raceNumber, nil
 }
 
-func (session *viewerSession) logError(v ...interface{}) {
-	if session.textWindow != nil {
-		fmt.Fprintln(session.textWindow, fmt.Sprintf("[%s::b]ERROR:[-::-]", colortoHexString(activeTheme.ErrorColor)), fmt.Sprint(v...))
+type logLevel int
+
+// the zero value is the default info level
+const (
+	levelDebug logLevel = iota - 1
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var logLevelNames = map[logLevel]string{
+	levelDebug: "debug",
+	levelInfo:  "info",
+	levelWarn:  "warn",
+	levelError: "error",
+}
+
+func (l logLevel) String() string {
+	return logLevelNames[l]
+}
+
+func parseLogLevel(name string) (logLevel, error) {
+	if name == "" {
+		return levelInfo, nil
 	}
-	log.Println("[ERROR]", fmt.Sprint(v...))
+	for level, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return levelInfo, fmt.Errorf("invalid log level '%s', must be error, warn, info or debug", name)
 }
 
-func (session *viewerSession) logInfo(v ...interface{}) {
-	if session.textWindow != nil {
-		fmt.Fprintln(session.textWindow, fmt.Sprintf("[%s::b]INFO:[-::-]", colortoHexString(activeTheme.InfoColor)), fmt.Sprint(v...))
+// toggleDebugPane shows or hides the pane with every message next to the output
+func (session *viewerSession) toggleDebugPane() {
+	if session.outputFlex == nil || session.debugWindow == nil {
+		return
+	}
+	session.debugShown = !session.debugShown
+	if session.debugShown {
+		session.outputFlex.AddItem(session.debugWindow, 0, 1, false)
+		session.debugWindow.ScrollToEnd()
+	} else {
+		session.outputFlex.RemoveItem(session.debugWindow)
 	}
-	log.Println("[INFO]", fmt.Sprint(v...))
+}
+
+// cycleLogLevel switches to the next log level, from only showing errors to showing everything
+func (session *viewerSession) cycleLogLevel() {
+	session.logLevel--
+	if session.logLevel < levelDebug {
+		session.logLevel = levelError
+	}
+	// always show the new level, even if info messages are hidden now
+	fmt.Fprintln(session.textWindow, fmt.Sprintf("[%s::b]INFO:[-::-]", colortoHexString(session.theme.InfoColor)), "showing messages from level", session.logLevel)
+}
+
+// logAt prints messages at or above the configured level to the output window.
+// All messages except debug messages are always saved to the log file.
+func (session *viewerSession) logAt(level logLevel, v ...interface{}) {
+	var color tcell.Color
+	switch level {
+	case levelError:
+		color = session.theme.ErrorColor
+	case levelWarn:
+		color = session.theme.WarnColor
+	case levelInfo:
+		color = session.theme.InfoColor
+	default:
+		color = session.theme.TerminalTextColor
+	}
+	prefix := strings.ToUpper(level.String())
+	if session.textWindow != nil && level >= session.logLevel {
+		fmt.Fprintln(session.textWindow, fmt.Sprintf("[%s::b]%s:[-::-]", colortoHexString(color), prefix), fmt.Sprint(v...))
+	}
+	// the debug pane gets every message, so it has the whole log when it's shown
+	if session.debugWindow != nil {
+		fmt.Fprintln(session.debugWindow, fmt.Sprintf("[%s::b]%s:[-::-]", colortoHexString(color), prefix), fmt.Sprint(v...))
+	}
+	if level > levelDebug || session.logLevel == levelDebug {
+		log.Println("["+prefix+"]", fmt.Sprint(v...))
+	}
+}
+
+func (session *viewerSession) logError(v ...interface{}) {
+	session.logAt(levelError, v...)
+}
+
+func (session *viewerSession) logWarn(v ...interface{}) {
+	session.logAt(levelWarn, v...)
+}
+
+func (session *viewerSession) logInfo(v ...interface{}) {
+	session.logAt(levelInfo, v...)
+}
+
+func (session *viewerSession) logDebug(v ...interface{}) {
+	session.logAt(levelDebug, v...)
 }
 
 func (session *viewerSession) withBlink(node *tview.TreeNode, fn func(), after func()) func() {
@@ -97,20 +209,20 @@ func (session *viewerSession) blinkNode(node *tview.TreeNode, done chan struct{}
 	originalText := node.GetText()
 	originalColor := node.GetColor()
 	color1 := originalColor
-	color2 := activeTheme.LoadingColor
+	color2 := session.theme.LoadingColor
 	node.SetText("loading...")
 
-	ticker := time.NewTicker(200 * time.Millisecond)
+	ticker := time.NewTicker(session.blinkInterval())
 	for {
 		select {
 		case <-done:
 			node.SetText(originalText)
 			node.SetColor(originalColor)
-			session.app.Draw()
+			session.draw()
 			return
 		case <-ticker.C:
 			node.SetColor(color2)
-			session.app.Draw()
+			session.draw()
 			c := color1
 			color1 = color2
 			color2 = c
@@ -129,21 +241,22 @@ func colortoHexString(color tcell.Color) string {
 	return fmt.Sprintf("#%06x", color.Hex())
 }
 
-func (t theme) apply() {
+// apply sets the colors that are set in the theme, unset colors are left unchanged
+func (t theme) apply(colors *themeColors) {
 	if t.TerminalTextColor != "" {
 		tview.Styles.PrimaryTextColor = hexStringToColor(t.TerminalTextColor)
 	}
 	if t.CategoryNodeColor != "" {
-		activeTheme.CategoryNodeColor = hexStringToColor(t.CategoryNodeColor)
+		colors.CategoryNodeColor = hexStringToColor(t.CategoryNodeColor)
 	}
 	if t.FolderNodeColor != "" {
-		activeTheme.FolderNodeColor = hexStringToColor(t.FolderNodeColor)
+		colors.FolderNodeColor = hexStringToColor(t.FolderNodeColor)
 	}
 	if t.ItemNodeColor != "" {
-		activeTheme.ItemNodeColor = hexStringToColor(t.ItemNodeColor)
+		colors.ItemNodeColor = hexStringToColor(t.ItemNodeColor)
 	}
 	if t.ActionNodeColor != "" {
-		activeTheme.ActionNodeColor = hexStringToColor(t.ActionNodeColor)
+		colors.ActionNodeColor = hexStringToColor(t.ActionNodeColor)
 	}
 	if t.BackgroundColor != "" {
 		tview.Styles.PrimitiveBackgroundColor = hexStringToColor(t.BackgroundColor)
@@ -152,31 +265,43 @@ func (t theme) apply() {
 		tview.Styles.BorderColor = hexStringToColor(t.BorderColor)
 	}
 	if t.NoContentColor != "" {
-		activeTheme.NoContentColor = hexStringToColor(t.NoContentColor)
+		colors.NoContentColor = hexStringToColor(t.NoContentColor)
+	}
+	if t.RateLimitColor != "" {
+		colors.RateLimitColor = hexStringToColor(t.RateLimitColor)
+	}
+	if t.GeoBlockColor != "" {
+		colors.GeoBlockColor = hexStringToColor(t.GeoBlockColor)
+	}
+	if t.VerifiedColor != "" {
+		colors.VerifiedColor = hexStringToColor(t.VerifiedColor)
 	}
 	if t.LoadingColor != "" {
-		activeTheme.LoadingColor = hexStringToColor(t.LoadingColor)
+		colors.LoadingColor = hexStringToColor(t.LoadingColor)
 	}
 	if t.LiveColor != "" {
-		activeTheme.LiveColor = hexStringToColor(t.LiveColor)
+		colors.LiveColor = hexStringToColor(t.LiveColor)
 	}
 	if t.UpdateColor != "" {
-		activeTheme.UpdateColor = hexStringToColor(t.UpdateColor)
+		colors.UpdateColor = hexStringToColor(t.UpdateColor)
 	}
 	if t.TerminalAccentColor != "" {
-		activeTheme.TerminalAccentColor = hexStringToColor(t.TerminalAccentColor)
+		colors.TerminalAccentColor = hexStringToColor(t.TerminalAccentColor)
 	}
 	if t.TerminalTextColor != "" {
-		activeTheme.TerminalTextColor = hexStringToColor(t.TerminalTextColor)
+		colors.TerminalTextColor = hexStringToColor(t.TerminalTextColor)
+	}
+	if t.WarnColor != "" {
+		colors.WarnColor = hexStringToColor(t.WarnColor)
 	}
 	if t.InfoColor != "" {
-		activeTheme.InfoColor = hexStringToColor(t.InfoColor)
+		colors.InfoColor = hexStringToColor(t.InfoColor)
 	}
 	if t.ErrorColor != "" {
-		activeTheme.ErrorColor = hexStringToColor(t.ErrorColor)
+		colors.ErrorColor = hexStringToColor(t.ErrorColor)
 	}
 	if t.MultiCommandColor != "" {
-		activeTheme.MultiCommandColor = hexStringToColor(t.MultiCommandColor)
+		colors.MultiCommandColor = hexStringToColor(t.MultiCommandColor)
 	}
 }
 
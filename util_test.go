@@ -72,6 +72,7 @@ func TestColorToHexString(t *testing.T) {
 func TestHexStringToColor(t *testing.T) {
 	t.Parallel()
 	for _, s := range colorPairs {
+		s := s
 		t.Run(s.name, func(t *testing.T) {
 			t.Parallel()
 			color := hexStringToColor(s.hex)
@@ -235,5 +236,136 @@ func newTestApp(t *testing.T, x, y int) (tcell.SimulationScreen, viewerSession)
 
 	app.SetRoot(flex, true)
 
-	return simScreen, viewerSession{tree: tree, app: app, textWindow: text, commands: make(map[string]bool)}
+	return simScreen, viewerSession{tree: tree, app: app, textWindow: text, commands: make(map[string]bool), theme: defaultTheme()}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	t.Parallel()
+	level, err := parseLogLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, levelWarn, level)
+
+	level, err = parseLogLevel("")
+	assert.NoError(t, err)
+	assert.Equal(t, levelInfo, level)
+
+	_, err = parseLogLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestOpenerFallback(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"xdg-open", "$url"}, openerCommand("linux"))
+	assert.Equal(t, []string{"open", "$url"}, openerCommand("darwin"))
+	assert.Equal(t, "rundll32", openerCommand("windows")[0])
+	assert.Nil(t, openerCommand("plan9"))
+
+	opener := openerCommand(runtime.GOOS)
+	if opener == nil {
+		return
+	}
+	s := viewerSession{commands: map[string]bool{opener[0]: true}}
+	commands := s.playerCommands(Titles{})
+	assert.Len(t, commands, 1)
+	assert.Equal(t, "Open in system default", commands[0].Title)
+
+	s.commands["mpv"] = true
+	assert.Equal(t, "Play with MPV", s.playerCommands(Titles{})[0].Title)
+}
+
+func TestPlayFromBeginning(t *testing.T) {
+	t.Parallel()
+	s := viewerSession{commands: map[string]bool{"mpv": true}}
+	for _, c := range s.playerCommands(Titles{}) {
+		assert.NotEqual(t, "Play from beginning with MPV", c.Title)
+	}
+
+	commands := s.playerCommands(Titles{Live: true})
+	if assert.True(t, len(commands) >= 2) {
+		assert.Equal(t, "Play with MPV", commands[0].Title)
+		assert.Equal(t, "Play from beginning with MPV", commands[1].Title)
+		assert.Contains(t, commands[1].Command, "--demuxer-lavf-o=live_start_index=0")
+		assert.NotContains(t, commands[0].Command, "--demuxer-lavf-o=live_start_index=0")
+	}
+}
+
+func TestAudioDevice(t *testing.T) {
+	t.Parallel()
+	s := viewerSession{commands: map[string]bool{"mpv": true, "vlc": true}}
+	for _, c := range s.playerCommands(Titles{}) {
+		for _, arg := range c.Command {
+			assert.NotContains(t, arg, "audio")
+		}
+	}
+
+	s.cfg.AudioDevice = "pulse/hdmi"
+	s.cfg.VLCAudioOptions = []string{"--aout=alsa", "--alsa-audio-device=hdmi"}
+	commands := s.playerCommands(Titles{})
+	if assert.Len(t, commands, 2) {
+		assert.Contains(t, commands[0].Command, "--audio-device=pulse/hdmi")
+		assert.Equal(t, commandAndArgs{"--aout=alsa", "--alsa-audio-device=hdmi"}, commands[1].Command[len(commands[1].Command)-2:])
+	}
+}
+
+func TestDefaultPlayer(t *testing.T) {
+	t.Parallel()
+	s := viewerSession{commands: map[string]bool{"mpv": true, "vlc": true}}
+	s.cfg.CustomPlaybackOptions = []command{
+		{Title: "Stream to TV", Command: []string{"catt", "cast", "$url"}},
+		{Title: "IINA", Command: []string{"iina", "$url"}},
+	}
+	titles := func() []string {
+		var titles []string
+		for _, c := range s.playerCommands(Titles{}) {
+			titles = append(titles, c.Title)
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Stream to TV", "IINA", "Play with MPV", "Play with VLC"}, titles())
+
+	s.cfg.DefaultPlayer = "iina"
+	assert.Equal(t, []string{"IINA", "Stream to TV", "Play with MPV", "Play with VLC"}, titles())
+	s.cfg.DefaultPlayer = "VLC"
+	assert.Equal(t, []string{"Play with VLC", "Stream to TV", "IINA", "Play with MPV"}, titles())
+	// unknown players keep the order
+	s.cfg.DefaultPlayer = "kodi"
+	assert.Equal(t, []string{"Stream to TV", "IINA", "Play with MPV", "Play with VLC"}, titles())
+	assert.False(t, s.cfg.knownPlayer())
+
+	assert.Empty(t, validateConfig([]byte(`{"default_player": "mpv"}`)))
+	assert.Empty(t, validateConfig([]byte(`{"default_player": "IINA", "custom_playback_options": [{"title": "IINA", "command": ["iina", "$url"]}]}`)))
+	assert.Len(t, validateConfig([]byte(`{"default_player": "kodi"}`)), 1)
+}
+
+func TestToggleDebugPane(t *testing.T) {
+	t.Parallel()
+	simScreen, s := newTestApp(t, 60, 12)
+	s.infoTable = tview.NewTable()
+	s.debugWindow = tview.NewTextView()
+	s.debugWindow.SetBorder(true).SetTitle(" debug ")
+	s.logLevel = levelInfo
+	s.cfg.KeyBindings = map[string]string{toggleDebugPane: "Ctrl+G"}
+	s.app.SetRoot(s.outputLayout(), true)
+	go func() {
+		err := s.app.Run()
+		assert.NoError(t, err)
+	}()
+
+	s.app.QueueUpdateDraw(func() {
+		s.logDebug("hidden debug message")
+		s.toggleDebugPane()
+	})
+	time.Sleep(100 * time.Millisecond)
+	assert.Contains(t, toTextScreen(simScreen), "debug")
+	assert.Contains(t, s.debugWindow.GetText(true), "hidden debug message")
+	assert.NotContains(t, s.textWindow.GetText(true), "hidden debug message")
+
+	// D isn't bound anymore, the rebound key hides the pane
+	s.app.QueueUpdateDraw(func() {
+		assert.NotNil(t, s.treeInputCapture(tcell.NewEventKey(tcell.KeyRune, 'D', tcell.ModNone)))
+		assert.Nil(t, s.treeInputCapture(tcell.NewEventKey(tcell.KeyCtrlG, 0, tcell.ModCtrl)))
+	})
+	time.Sleep(100 * time.Millisecond)
+	assert.NotContains(t, toTextScreen(simScreen), "debug")
+	assert.Contains(t, s.debugWindow.GetText(true), "hidden debug message")
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// a verified download has to be at least this share of the scheduled session length
+const minDurationShare = 0.75
+
+// probeDuration runs ffprobe on the file and returns its duration, it fails if ffprobe reports an error
+func probeDuration(file string) (time.Duration, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", file)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return 0, fmt.Errorf("ffprobe found errors: %s", firstLine(msg))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseProbeDuration(stdout.String())
+}
+
+// parseProbeDuration parses the duration in seconds ffprobe prints
+func parseProbeDuration(output string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("the file has no duration, it is probably incomplete")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// checkDuration fails if the file is a lot shorter than expected, a zero expected duration isn't checked
+func checkDuration(actual, expected time.Duration) error {
+	if expected > 0 && float64(actual) < float64(expected)*minDurationShare {
+		return fmt.Errorf("the file is only %s long, the session was scheduled for %s", formatPosition(actual), formatPosition(expected))
+	}
+	return nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// expectedDuration returns the scheduled length of the session the node belongs to, or 0 if it isn't known
+func (session *viewerSession) expectedDuration(node *tview.TreeNode) time.Duration {
+	for n := node; n != nil; n = session.findParent(n) {
+		ref, ok := n.GetReference().(*NodeMetadata)
+		if !ok {
+			continue
+		}
+		if s, ok := ref.metadata.(sessionStruct); ok {
+			if s.StartTime.IsZero() || !s.EndTime.After(s.StartTime) {
+				return 0
+			}
+			return s.EndTime.Sub(s.StartTime)
+		}
+	}
+	return 0
+}
+
+// verifyDownload checks a finished download with ffprobe and marks the node green if it's fine,
+// or red if it isn't. The reason can be shown with o. Live recordings aren't compared to the session length.
+func (session *viewerSession) verifyDownload(file string, t Titles, node *tview.TreeNode) {
+	if !session.commandAvailable("ffprobe") {
+		session.logWarn("can't verify ", file, ", ffprobe is not installed")
+		return
+	}
+	duration, err := probeDuration(file)
+	if err == nil && !t.Live && node != nil {
+		err = checkDuration(duration, session.expectedDuration(node))
+	}
+	if err != nil {
+		session.logError("download of ", t.String(), " is broken: ", err)
+		if node != nil {
+			session.markFailed(node, err.Error())
+		}
+		return
+	}
+	session.logInfo("verified ", file, " (", formatPosition(duration), ")")
+	if node != nil {
+		session.clearPlayerFailure(node)
+		node.SetColor(session.theme.VerifiedColor)
+		session.draw()
+	}
+}
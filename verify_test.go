@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProbeDuration(t *testing.T) {
+	t.Parallel()
+
+	d, err := parseProbeDuration("7265.120000\n")
+	assert.NoError(t, err)
+	assert.Equal(t, 7265120*time.Millisecond, d)
+
+	_, err = parseProbeDuration("N/A\n")
+	assert.Error(t, err)
+	_, err = parseProbeDuration("0.000000")
+	assert.Error(t, err)
+}
+
+func TestCheckDuration(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkDuration(2*time.Hour, 2*time.Hour))
+	assert.NoError(t, checkDuration(100*time.Minute, 2*time.Hour))
+	assert.Error(t, checkDuration(30*time.Minute, 2*time.Hour))
+	assert.NoError(t, checkDuration(time.Minute, 0))
+}
+
+func TestExpectedDuration(t *testing.T) {
+	t.Parallel()
+	_, s := newTestApp(t, 20, 5)
+
+	start := time.Date(2020, 7, 5, 13, 10, 0, 0, time.UTC)
+	sessionNode := tview.NewTreeNode("Race").SetReference(&NodeMetadata{nodeType: PlayableNode, metadata: sessionStruct{StartTime: start, EndTime: start.Add(2 * time.Hour)}})
+	download := tview.NewTreeNode("Download").SetReference(&NodeMetadata{nodeType: ActionNode})
+	sessionNode.AddChild(download)
+	s.tree.GetRoot().AddChild(sessionNode)
+
+	assert.Equal(t, 2*time.Hour, s.expectedDuration(download))
+	assert.Equal(t, time.Duration(0), s.expectedDuration(tview.NewTreeNode("episode")))
+}